@@ -0,0 +1,249 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Fingerprint returns a stable hex digest identifying the log statement
+// of e: its level, logger name, caller and message, but not its fields.
+// Repeated entries of a statement share the fingerprint, which makes it a
+// deduplication key for alerting.
+func Fingerprint(e Entry) string {
+	b := getBuilder()
+	b.WriteString(e.Level.CapitalString())
+	b.WriteByte(0)
+	b.WriteString(e.LoggerName)
+	b.WriteByte(0)
+	if e.Caller.Defined {
+		b.WriteString(e.Caller.File)
+		b.WriteByte(':')
+		b.AppendInt(int64(e.Caller.Line))
+	}
+	b.WriteByte(0)
+	b.WriteString(e.Message)
+	sum := sha1.Sum(b.Bytes())
+	putBuilder(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// AlertProvider is the incident management service of an AlertCore.
+type AlertProvider int
+
+const (
+	// PagerDuty triggers events through the Events API v2.
+	PagerDuty AlertProvider = iota
+	// Opsgenie creates alerts through the Alert API.
+	Opsgenie
+)
+
+// AlertConfig configures an AlertCore.
+type AlertConfig struct {
+	Provider AlertProvider
+	// Key is the PagerDuty routing key, or the Opsgenie API key.
+	Key string
+	// URL overrides the endpoint of the provider.
+	URL string
+	// Source names the origin of the events, the host name if empty.
+	Source string
+	// ErrorRate also pages for Error entries, when the entries of a log
+	// statement reach this rate per minute. Zero pages for Panic and Fatal
+	// entries only.
+	ErrorRate int
+	// Client sends the requests, a client with a 10 seconds timeout if nil.
+	Client *http.Client
+}
+
+// the default endpoints of the providers.
+const (
+	_pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	_opsgenieURL  = "https://api.opsgenie.com/v2/alerts"
+)
+
+// the number of statements above which the error rates are reset.
+const _alertMaxRates = 4096
+
+type alertRate struct {
+	start time.Time
+	n     int
+}
+
+type alertCore struct {
+	cfg   AlertConfig
+	mu    sync.Mutex
+	rates map[string]*alertRate
+}
+
+// NewAlertCore returns a Core turning Panic and Fatal entries, and
+// optionally high-rate Error entries, into PagerDuty or Opsgenie events,
+// deduplicated by the Fingerprint of the entries. Events are sent before
+// Write returns, so they are not lost when the process exits on Fatal.
+func NewAlertCore(cfg AlertConfig) Core {
+	if cfg.URL == "" {
+		cfg.URL = _pagerDutyURL
+		if cfg.Provider == Opsgenie {
+			cfg.URL = _opsgenieURL
+		}
+	}
+	if cfg.Source == "" {
+		cfg.Source, _ = os.Hostname()
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &alertCore{cfg: cfg, rates: make(map[string]*alertRate)}
+}
+
+func (c *alertCore) Enabled(lvl Level) bool {
+	if c.cfg.ErrorRate > 0 {
+		return lvl >= ErrorLevel
+	}
+	return lvl >= PanicLevel
+}
+
+func (c *alertCore) Write(e Entry) error {
+	fp := Fingerprint(e)
+	if e.Level < PanicLevel && !c.rateReached(fp) {
+		return nil
+	}
+
+	b := getBuilder()
+	defer putBuilder(b)
+	req, err := c.request(b, e, fp)
+	if err != nil {
+		return err
+	}
+	resp, err := c.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("xlog: alert: %s", resp.Status)
+	}
+	return nil
+}
+
+func (c *alertCore) Sync() error { return nil }
+
+// rateReached counts an Error entry of the statement fp, and reports
+// whether the statement just reached the configured rate.
+func (c *alertCore) rateReached(fp string) bool {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := c.rates[fp]
+	if r == nil || now.Sub(r.start) >= time.Minute {
+		if r == nil && len(c.rates) >= _alertMaxRates {
+			c.rates = make(map[string]*alertRate)
+		}
+		r = &alertRate{start: now}
+		c.rates[fp] = r
+	}
+	r.n++
+	return r.n == c.cfg.ErrorRate
+}
+
+func (c *alertCore) request(b *Builder, e Entry, fp string) (*http.Request, error) {
+	switch c.cfg.Provider {
+	case Opsgenie:
+		priority := "P3"
+		if e.Level >= PanicLevel {
+			priority = "P1"
+		}
+		msg := e.Message
+		if len(msg) > 130 { // the limit of Opsgenie, on a rune boundary
+			i := 130
+			for i > 0 && !utf8.RuneStart(msg[i]) {
+				i--
+			}
+			msg = msg[:i]
+		}
+		b.WriteString(`{"message":`)
+		b.AppendQuote(msg)
+		b.WriteString(`,"alias":"`)
+		b.WriteString(fp)
+		b.WriteString(`","description":`)
+		b.AppendQuote(e.Message)
+		b.WriteString(`,"priority":"`)
+		b.WriteString(priority)
+		b.WriteString(`","source":`)
+		b.AppendQuote(c.cfg.Source)
+		b.WriteString(`,"details":{`)
+		n := 0
+		for _, fs := range [2][]Field{e.Ctx, e.Fields} {
+			for _, f := range fs {
+				if n > 0 {
+					b.WriteByte(',')
+				}
+				n++
+				b.AppendQuote(f.Key)
+				b.WriteByte(':')
+				appendStringValue(b, f.Value())
+			}
+		}
+		b.WriteString("}}")
+
+	default:
+		severity := "error"
+		if e.Level >= PanicLevel {
+			severity = "critical"
+		}
+		b.WriteString(`{"routing_key":`)
+		b.AppendQuote(c.cfg.Key)
+		b.WriteString(`,"event_action":"trigger","dedup_key":"`)
+		b.WriteString(fp)
+		b.WriteString(`","payload":{"summary":`)
+		b.AppendQuote(e.Message)
+		b.WriteString(`,"source":`)
+		b.AppendQuote(c.cfg.Source)
+		b.WriteString(`,"severity":"`)
+		b.WriteString(severity)
+		b.WriteString(`","timestamp":"`)
+		b.AppendTime(e.Time, Trfc3339Nano)
+		b.WriteByte('"')
+		if e.LoggerName != "" {
+			b.WriteString(`,"component":`)
+			b.AppendQuote(e.LoggerName)
+		}
+		b.WriteString(`,"custom_details":{`)
+		if hasFields(e) {
+			appendFields(b, e)
+		}
+		b.WriteString("}}}")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.cfg.URL, bytes.NewReader(b.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.Provider == Opsgenie {
+		req.Header.Set("Authorization", "GenieKey "+c.cfg.Key)
+	}
+	return req, nil
+}
+
+// appendStringValue appends v as a json string: strings as they are,
+// other values as their json text.
+func appendStringValue(b *Builder, v interface{}) {
+	if s, ok := v.(string); ok {
+		b.AppendQuote(s)
+		return
+	}
+	jb := getBuilder()
+	defer putBuilder(jb)
+	jb.AppendJSON(v)
+	b.AppendQuote(jb.String())
+}
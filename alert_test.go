@@ -0,0 +1,93 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	e := Entry{Level: ErrorLevel, LoggerName: "db", Message: "failed", Caller: NewEntryCaller(0, "a.go", 1, true)}
+	f := e
+	f.Fields = []Field{F("id", 1)}
+	f.Time = time.Now()
+	if Fingerprint(e) != Fingerprint(f) || len(Fingerprint(e)) != 40 {
+		t.Errorf("Fingerprint() depends on fields or time")
+	}
+	f.Caller.Line = 2
+	if Fingerprint(e) == Fingerprint(f) {
+		t.Errorf("Fingerprint() doesn't depend on the caller")
+	}
+}
+
+func TestAlertCore_opsgenieMessage(t *testing.T) {
+	c := NewAlertCore(AlertConfig{Provider: Opsgenie, Key: "gk"}).(*alertCore)
+	b := getBuilder()
+	defer putBuilder(b)
+	// 3 bytes per rune, the limit of 130 bytes falls in the 44th rune
+	msg := strings.Repeat("日", 50)
+	if _, err := c.request(b, Entry{Level: ErrorLevel, Message: msg}, "fp"); err != nil {
+		t.Fatal(err)
+	}
+	var m struct{ Message string }
+	if err := json.Unmarshal(b.Bytes(), &m); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", b.Bytes(), err)
+	}
+	if want := strings.Repeat("日", 43); m.Message != want {
+		t.Errorf("message = %q, want %q", m.Message, want)
+	}
+}
+
+func TestAlertCore(t *testing.T) {
+	var reqs []map[string]interface{}
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := ioutil.ReadAll(r.Body)
+		var m map[string]interface{}
+		if err := json.Unmarshal(p, &m); err != nil {
+			t.Errorf("json.Unmarshal(%s) error = %v", p, err)
+		}
+		reqs = append(reqs, m)
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	pd := NewAlertCore(AlertConfig{Key: "rk", URL: srv.URL, Source: "h1", ErrorRate: 3})
+	if pd.Enabled(WarnLevel) || !pd.Enabled(ErrorLevel) {
+		t.Errorf("Enabled() with ErrorRate")
+	}
+	for i := 0; i < 4; i++ {
+		pd.Write(Entry{Level: ErrorLevel, Message: "flapping", Fields: []Field{F("i", i)}})
+	}
+	pd.Write(Entry{Level: FatalLevel, LoggerName: "main", Message: "cannot start"})
+
+	og := NewAlertCore(AlertConfig{Provider: Opsgenie, Key: "gk", URL: srv.URL, Source: "h1"})
+	if og.Enabled(ErrorLevel) {
+		t.Errorf("Enabled(ErrorLevel) without ErrorRate")
+	}
+	og.Write(Entry{Level: PanicLevel, Message: "nil map", Fields: []Field{F("n", 1)}})
+
+	if len(reqs) != 3 {
+		t.Fatalf("requests = %v, want 3", reqs)
+	}
+	if reqs[0]["dedup_key"] != Fingerprint(Entry{Level: ErrorLevel, Message: "flapping"}) ||
+		reqs[0]["payload"].(map[string]interface{})["severity"] != "error" ||
+		reqs[0]["payload"].(map[string]interface{})["custom_details"].(map[string]interface{})["i"] != 2.0 {
+		t.Errorf("rate event = %v", reqs[0])
+	}
+	if p := reqs[1]["payload"].(map[string]interface{}); p["severity"] != "critical" || p["component"] != "main" {
+		t.Errorf("fatal event = %v", reqs[1])
+	}
+	if reqs[2]["priority"] != "P1" || reqs[2]["details"].(map[string]interface{})["n"] != "1" || auth[2] != "GenieKey gk" {
+		t.Errorf("opsgenie alert = %v %v", reqs[2], auth[2])
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import "strconv"
+
+// An Audience is the trust level of the readers of a log.
+// Higher audiences are more trusted.
+type Audience int8
+
+const (
+	// PublicAudience logs may be shown to customers. It's the default
+	// audience of entries and fields.
+	PublicAudience Audience = iota
+	// InternalAudience logs are for the staff operating the service.
+	InternalAudience
+	// RestrictedAudience logs are for a few privileged people only.
+	RestrictedAudience
+)
+
+// AudienceKey is the key of the field tagging the audience of an entry.
+const AudienceKey = "audience"
+
+// String returns a lower-case ASCII representation of the audience.
+func (a Audience) String() string {
+	switch a {
+	case PublicAudience:
+		return "public"
+	case InternalAudience:
+		return "internal"
+	case RestrictedAudience:
+		return "restricted"
+	default:
+		return "Audience(" + strconv.Itoa(int(a)) + ")"
+	}
+}
+
+// ForAudience returns a field tagging the entry with the audience a.
+// Cores created by NewAudienceCore drop the entries whose audience
+// exceeds their trust. Use it as a log-site field, or with the Fields
+// option to tag all the entries of a Logger.
+func ForAudience(a Audience) Field {
+	return Field{Key: AudienceKey, Val: a}
+}
+
+// restrictedValue is the value of a field restricted to an audience,
+// it holds the field, whose value is resolved when it's encoded.
+type restrictedValue struct {
+	audience Audience
+	f        Field
+}
+
+// Restrict restricts the field f to the audience a, so that cores created
+// by NewAudienceCore with a lower trust strip it. Other cores encode the
+// field as usual. The value of f is resolved when the field is encoded,
+// so a Lazy field restricted to an audience costs nothing until then.
+func Restrict(a Audience, f Field) Field {
+	return Field{Key: f.Key, Val: restrictedValue{a, f}}
+}
+
+type audienceCore struct {
+	Core
+	trust Audience
+}
+
+// NewAudienceCore returns a Core that feeds core with the detail its
+// audience of trust may see: entries tagged by ForAudience with a higher
+// audience are dropped, and fields restricted by Restrict to a higher
+// audience are stripped. It lets a single call site feed both a
+// customer-visible log and an internal one, e.g.
+//
+//	xlog.NewTee(
+//		xlog.NewAudienceCore(customerCore, xlog.PublicAudience),
+//		xlog.NewAudienceCore(internalCore, xlog.InternalAudience))
+func NewAudienceCore(core Core, trust Audience) Core {
+	return &audienceCore{core, trust}
+}
+
+func (c *audienceCore) Write(e Entry) error {
+	if entryAudience(e) > c.trust {
+		return nil
+	}
+	e.Ctx = c.strip(e.Ctx)
+	e.Fields = c.strip(e.Fields)
+	return c.Core.Write(e)
+}
+
+// strip returns fs without the fields exceeding the trust of c,
+// it doesn't modify fs.
+func (c *audienceCore) strip(fs []Field) []Field {
+	for i := range fs {
+		if r, ok := fs[i].Val.(restrictedValue); ok && r.audience > c.trust {
+			out := make([]Field, i, len(fs))
+			copy(out, fs[:i])
+			for _, f := range fs[i+1:] {
+				if r, ok := f.Val.(restrictedValue); !ok || r.audience <= c.trust {
+					out = append(out, f)
+				}
+			}
+			return out
+		}
+	}
+	return fs
+}
+
+// entryAudience returns the highest audience e is tagged with.
+func entryAudience(e Entry) Audience {
+	a := PublicAudience
+	for _, fs := range [2][]Field{e.Ctx, e.Fields} {
+		for _, f := range fs {
+			if f.Key != AudienceKey {
+				continue
+			}
+			if v, ok := f.Val.(Audience); ok && v > a {
+				a = v
+			}
+		}
+	}
+	return a
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAudienceCore(t *testing.T) {
+	var public, internal, plain bytes.Buffer
+	log := New(NewTee(
+		NewAudienceCore(NewCore(NewJSONEncoder(0), &public, DebugLevel), PublicAudience),
+		NewAudienceCore(NewCore(NewJSONEncoder(0), &internal, DebugLevel), InternalAudience),
+		NewCore(NewJSONEncoder(0), &plain, DebugLevel)))
+
+	log.Error("payment failed",
+		F("order", 42),
+		Restrict(InternalAudience, F("card", "4242")),
+		Restrict(RestrictedAudience, F("cvv", "123")))
+	log.Warn("retrying", ForAudience(InternalAudience))
+
+	tests := []struct {
+		name string
+		buf  *bytes.Buffer
+		want []string
+		not  []string
+	}{
+		{"public", &public, []string{`"order":42}`}, []string{"card", "cvv", "retrying"}},
+		{"internal", &internal, []string{`"order":42,"card":"4242"}`, `"msg":"retrying","audience":"internal"`}, []string{"cvv"}},
+		{"plain", &plain, []string{`"order":42,"card":"4242","cvv":"123"}`}, nil},
+	}
+	for _, tt := range tests {
+		for _, s := range tt.want {
+			if !bytes.Contains(tt.buf.Bytes(), []byte(s)) {
+				t.Errorf("%s = %s, want %s", tt.name, tt.buf.Bytes(), s)
+			}
+		}
+		for _, s := range tt.not {
+			if bytes.Contains(tt.buf.Bytes(), []byte(s)) {
+				t.Errorf("%s = %s, must not contain %s", tt.name, tt.buf.Bytes(), s)
+			}
+		}
+	}
+}
+
+func TestRestrict_lazy(t *testing.T) {
+	calls := 0
+	f := Restrict(InternalAudience, Lazy("dump", func() interface{} {
+		calls++
+		return "state"
+	}))
+	if calls != 0 {
+		t.Fatalf("Restrict() called the Lazy function")
+	}
+
+	var public, internal bytes.Buffer
+	New(NewAudienceCore(NewCore(NewJSONEncoder(0), &public, DebugLevel), PublicAudience)).Info("m", f)
+	if calls != 0 || bytes.Contains(public.Bytes(), []byte("dump")) {
+		t.Errorf("public = %s, calls = %d, want the field stripped unresolved", public.Bytes(), calls)
+	}
+	New(NewAudienceCore(NewCore(NewJSONEncoder(0), &internal, DebugLevel), InternalAudience)).Info("m", f, Restrict(InternalAudience, Int("n", 7)))
+	if calls != 1 || !bytes.Contains(internal.Bytes(), []byte(`"dump":"state","n":7`)) {
+		t.Errorf("internal = %s, calls = %d, want the field resolved once", internal.Bytes(), calls)
+	}
+	if v := Restrict(InternalAudience, Int("n", 7)).Value(); v != 7 {
+		t.Errorf("Value() = %v, want 7", v)
+	}
+}
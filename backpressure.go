@@ -0,0 +1,62 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import "sync/atomic"
+
+// Pressurer is implemented by the cores queuing entries, the WebhookCore
+// and the OTLPCore, so that applications can shed their own verbose
+// logging while the queue of a core fills up, instead of letting the core
+// drop entries:
+//
+//	if p, ok := core.(xlog.Pressurer); ok && p.Pressured() {
+//		// skip the debug dump
+//	}
+//
+// The configs of these cores also take an OnBackpressure callback,
+// notified when the pressure starts and ends.
+type Pressurer interface {
+	// Pressured reports whether the queue is above its high-water mark.
+	Pressured() bool
+}
+
+// backpressure tracks whether a queue is above its high-water mark. The
+// pressure starts when the queue grows above high, and ends when it
+// drains to half of high, so that a queue hovering around the mark
+// doesn't flap.
+type backpressure struct {
+	high   int
+	notify func(pressured bool)
+	on     int32
+}
+
+// newBackpressure returns the backpressure of a queue of size entries,
+// with the high-water mark high, 3/4 of size if zero or negative.
+func newBackpressure(size, high int, notify func(bool)) *backpressure {
+	if high <= 0 {
+		high = size * 3 / 4
+	}
+	return &backpressure{high: high, notify: notify}
+}
+
+// update records the length n of the queue, and notifies the start or
+// the end of the pressure. It must not be called with a lock held, as
+// notify may log.
+func (p *backpressure) update(n int) {
+	switch {
+	case n > p.high:
+		if atomic.CompareAndSwapInt32(&p.on, 0, 1) && p.notify != nil {
+			p.notify(true)
+		}
+	case n <= p.high/2:
+		if atomic.CompareAndSwapInt32(&p.on, 1, 0) && p.notify != nil {
+			p.notify(false)
+		}
+	}
+}
+
+func (p *backpressure) pressured() bool {
+	return atomic.LoadInt32(&p.on) != 0
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBackpressure(t *testing.T) {
+	var got []bool
+	p := newBackpressure(8, 0, func(pressured bool) { got = append(got, pressured) })
+	for _, n := range []int{1, 6, 7, 8, 7, 4, 3, 2, 7} {
+		p.update(n)
+	}
+	want := []bool{true, false, true}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("notified %v, want %v", got, want)
+	}
+	if !p.pressured() {
+		t.Error("pressured() = false, want true")
+	}
+}
+
+// waitPressure waits for a notification from ch.
+func waitPressure(t *testing.T, ch chan bool, want bool) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Fatalf("OnBackpressure(%v), want %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("OnBackpressure(%v) not called", want)
+	}
+}
+
+func TestWebhookCore_backpressure(t *testing.T) {
+	received, release := make(chan struct{}, 8), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	}))
+	defer srv.Close()
+
+	pressure := make(chan bool, 4)
+	core := NewWebhookCore(WebhookConfig{
+		URL:            srv.URL,
+		QueueSize:      4,
+		HighWater:      2,
+		OnBackpressure: func(pressured bool) { pressure <- pressured },
+	})
+	defer core.Close()
+
+	core.Write(Entry{Level: ErrorLevel, Message: "posting"})
+	<-received
+	// the endpoint hangs: the entries pile up in the queue
+	for i := 0; i < 3; i++ {
+		core.Write(Entry{Level: ErrorLevel, Message: "queued"})
+	}
+	if !core.Pressured() {
+		t.Error("Pressured() = false, want true")
+	}
+	waitPressure(t, pressure, true)
+
+	close(release)
+	core.Sync()
+	waitPressure(t, pressure, false)
+	if core.Pressured() {
+		t.Error("Pressured() after the queue drained = true, want false")
+	}
+}
+
+func TestOTLPCore_backpressure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	pressure := make(chan bool, 4)
+	core := NewOTLPCore(OTLPConfig{
+		Endpoint:       srv.URL,
+		MaxPending:     8,
+		Interval:       time.Hour,
+		OnBackpressure: func(pressured bool) { pressure <- pressured },
+	})
+	defer core.Close()
+
+	for i := 0; i < 7; i++ {
+		core.Write(Entry{Message: "pending"})
+	}
+	if !core.Pressured() {
+		t.Error("Pressured() = false, want true")
+	}
+	waitPressure(t, pressure, true)
+
+	core.Sync()
+	waitPressure(t, pressure, false)
+	if core.Pressured() {
+		t.Error("Pressured() after the export = true, want false")
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package xlog
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// the maximum number of iovec of a writev call
+const _iovMax = 1024
+
+// writeBuffers writes bufs to f with as few writev calls as possible.
+func writeBuffers(f *os.File, bufs [][]byte) error {
+	var iovecs [_iovMax]syscall.Iovec
+	fd := f.Fd()
+	for len(bufs) > 0 {
+		n := 0
+		total := 0
+		for ; n < len(bufs) && n < _iovMax; n++ {
+			iovecs[n].Base = &bufs[n][0]
+			iovecs[n].SetLen(len(bufs[n]))
+			total += len(bufs[n])
+		}
+
+		written, _, errno := syscall.Syscall(syscall.SYS_WRITEV, fd, uintptr(unsafe.Pointer(&iovecs[0])), uintptr(n))
+		if errno == syscall.EINTR || errno == syscall.EAGAIN {
+			continue
+		}
+		if errno != 0 {
+			return &os.PathError{Op: "writev", Path: f.Name(), Err: errno}
+		}
+		if int(written) == total {
+			bufs = bufs[n:]
+			continue
+		}
+		if written == 0 {
+			return io.ErrShortWrite
+		}
+
+		// partial write, skip what was written and retry
+		rest := int(written)
+		for rest >= len(bufs[0]) {
+			rest -= len(bufs[0])
+			bufs = bufs[1:]
+		}
+		head := bufs[0][rest:]
+		bufs = append([][]byte{head}, bufs[1:]...)
+	}
+	return nil
+}
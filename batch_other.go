@@ -0,0 +1,20 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package xlog
+
+import "os"
+
+// writeBuffers writes bufs to f one after the other.
+func writeBuffers(f *os.File, bufs [][]byte) error {
+	for _, buf := range bufs {
+		if _, err := f.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBatchWriter(t *testing.T) {
+	f, err := ioutil.TempFile("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := NewBatchWriter(f, 1<<20, 0)
+	var want bytes.Buffer
+	line := bytes.Repeat([]byte("0123456789"), 100)
+	for i := 0; i < 200; i++ { // spans several chunks
+		w.Write(line)
+		want.Write(line)
+	}
+	if data, _ := ioutil.ReadFile(f.Name()); len(data) != 0 {
+		t.Errorf("BatchWriter wrote %d bytes before flush", len(data))
+	}
+	if err = w.Sync(); err != nil {
+		t.Fatalf("BatchWriter.Sync() error = %v", err)
+	}
+	if data, _ := ioutil.ReadFile(f.Name()); !bytes.Equal(data, want.Bytes()) {
+		t.Errorf("BatchWriter wrote %d bytes, want %d", len(data), want.Len())
+	}
+
+	// periodic flush
+	w = NewBatchWriter(f, 1<<20, 5*time.Millisecond)
+	defer w.Close()
+	w.Write([]byte("tick\n"))
+	want.WriteString("tick\n")
+	time.Sleep(50 * time.Millisecond)
+	if data, _ := ioutil.ReadFile(f.Name()); !bytes.Equal(data, want.Bytes()) {
+		t.Errorf("BatchWriter didn't flush periodically")
+	}
+}
+
+func TestBatchWriter_Close(t *testing.T) {
+	f, err := ioutil.TempFile("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := NewBatchWriter(f, 1<<20, time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, err := w.Write([]byte("line\n")); err != nil {
+				if err != errClosed {
+					t.Errorf("Write() error = %v, want errClosed", err)
+				}
+				return
+			}
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if err := w.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	}
+	<-done
+
+	data, _ := ioutil.ReadFile(f.Name())
+	if len(data) == 0 || len(data)%len("line\n") != 0 {
+		t.Errorf("BatchWriter wrote %d bytes, want whole lines", len(data))
+	}
+	if _, err := w.Write([]byte("late\n")); err != errClosed {
+		t.Errorf("Write() after Close error = %v, want errClosed", err)
+	}
+}
@@ -5,11 +5,17 @@
 package xlog
 
 import (
+	"encoding"
 	"encoding/base64"
 	"encoding/json"
 	"math"
+	"math/big"
+	"net"
+	"net/url"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 	"unsafe"
@@ -34,6 +40,13 @@ const (
 	TnineFlag
 	// Tzone the local time zone: Z07:00
 	Tzone
+	// Tunix the seconds elapsed since January 1, 1970 UTC: 1136214245.
+	// The epoch flags override the other flags.
+	Tunix
+	// TunixMilli the milliseconds elapsed since January 1, 1970 UTC: 1136214245000
+	TunixMilli
+	// TunixNano the nanoseconds elapsed since January 1, 1970 UTC: 1136214245000000000
+	TunixNano
 	// Tdatetime the date and time in the local time zone: 2006-01-02 15:04:05"
 	Tdatetime = Tdate | Ttime
 	// TdatetimeMilli the date and time(ms) in the local time zone: 2006-01-02 15:04:05.000"
@@ -53,7 +66,11 @@ const (
 // It implements io.Writer and io.ByteWriter and io.StringWriter.
 type Builder struct {
 	buf        []byte
-	reflectEnc *json.Encoder // for encoding generic values by reflection
+	reflectEnc *json.Encoder  // for encoding generic values by reflection
+	rawHTML    bool           // AppendJSON doesn't escape <, > and &
+	durations  DurationFormat // how AppendJSON encodes durations
+	bytes      BytesFormat    // how AppendJSON encodes byte slices
+	mapOrder   bool           // AppendJSON doesn't sort the keys of maps
 }
 
 // grow copies the buffer to a new, larger buffer so that there are at least n
@@ -94,10 +111,22 @@ func (b *Builder) Cap() int {
 }
 
 // Bytes returns the builder's underlying byte slice.
+// The slice aliases the builder's storage: it is only valid until the next
+// modification of the builder, and must not be retained after the builder is
+// reset or returned to its owner (e.g. after Encoder.Encode returns in a Core).
+// Use CopyBytes to keep the content.
 func (b *Builder) Bytes() []byte {
 	return b.buf
 }
 
+// CopyBytes returns a copy of the accumulated bytes
+// which the caller owns and may retain.
+func (b *Builder) CopyBytes() []byte {
+	p := make([]byte, len(b.buf))
+	copy(p, b.buf)
+	return p
+}
+
 // String returns the accumulated string.
 func (b *Builder) String() string {
 	return *(*string)(unsafe.Pointer(b))
@@ -158,12 +187,57 @@ func (b *Builder) AppendBool(v bool) {
 
 // AppendInt appends the string form of the int64 i.
 func (b *Builder) AppendInt(i int64) {
-	b.buf = strconv.AppendInt(b.buf, i, 10)
+	if i >= 0 {
+		b.AppendUint(uint64(i))
+		return
+	}
+	b.buf = append(b.buf, '-')
+	b.AppendUint(-uint64(i))
 }
 
 // AppendUint appends the string form of the uint64 i.
 func (b *Builder) AppendUint(i uint64) {
-	b.buf = strconv.AppendUint(b.buf, i, 10)
+	if i < 10 { // single digit, very common for counters and flags
+		b.buf = append(b.buf, byte('0'+i))
+		return
+	}
+
+	// digits are written backwards directly into the buffer
+	n := uintLen(i)
+	l := len(b.buf)
+	if cap(b.buf)-l < n {
+		b.grow(n)
+	}
+	b.buf = b.buf[:l+n]
+	buf := b.buf[l:]
+	w := n
+	for i >= 100 {
+		q := i / 100
+		j := (i - q*100) * 2
+		w -= 2
+		buf[w], buf[w+1] = _digits100[j], _digits100[j+1]
+		i = q
+	}
+	if i >= 10 {
+		j := i * 2
+		buf[0], buf[1] = _digits100[j], _digits100[j+1]
+	} else {
+		buf[0] = byte('0' + i)
+	}
+}
+
+// uintLen returns the number of decimal digits of i.
+func uintLen(i uint64) int {
+	n := 1
+	for i >= 10000 {
+		i /= 10000
+		n += 4
+	}
+	for i >= 10 {
+		i /= 10
+		n++
+	}
+	return n
 }
 
 // AppendUintptr appends the string form of the uintptr p.
@@ -298,6 +372,18 @@ func (b *Builder) AppendDuration(d time.Duration) {
 // It has a faster formatting method that you can use if you are demanding
 // performance, but it supports only a few formats
 func (b *Builder) AppendTime(t time.Time, flag int) {
+	if flag&(Tunix|TunixMilli|TunixNano) != 0 {
+		switch {
+		case flag&TunixNano != 0:
+			b.AppendInt(t.UnixNano())
+		case flag&TunixMilli != 0:
+			b.AppendInt(t.UnixNano() / int64(time.Millisecond))
+		default:
+			b.AppendInt(t.Unix())
+		}
+		return
+	}
+
 	// Largest time is 2006-01-02T15:04:05.999999999Z07:00
 	var buf [40]byte
 	w := len(buf)
@@ -383,6 +469,133 @@ func (b *Builder) AppendHTMLQuote(s string) {
 	b.WriteByte('"')
 }
 
+// SetEscapeHTML specifies whether AppendJSON escapes the characters <, >
+// and & in strings, so the output can be embedded in HTML; it does by
+// default. Without escaping, the output is smaller and still valid JSON.
+func (b *Builder) SetEscapeHTML(on bool) {
+	b.rawHTML = !on
+}
+
+// appendJSONString appends s as a json string, escaping the HTML
+// characters as configured by SetEscapeHTML.
+func (b *Builder) appendJSONString(s string) {
+	if b.rawHTML {
+		b.AppendQuote(s)
+	} else {
+		b.AppendHTMLQuote(s)
+	}
+}
+
+// DurationFormat is the encoding of time.Duration values by AppendJSON.
+type DurationFormat int
+
+const (
+	// DurationString encodes durations as strings, e.g. "1.2ms".
+	DurationString DurationFormat = iota
+	// DurationSeconds encodes durations as numbers of seconds, e.g. 0.0012.
+	DurationSeconds
+	// DurationMillis encodes durations as numbers of milliseconds, e.g. 1.2.
+	DurationMillis
+	// DurationNanos encodes durations as integer numbers of nanoseconds,
+	// e.g. 1200000.
+	DurationNanos
+)
+
+// SetDurationFormat specifies how AppendJSON encodes durations,
+// as DurationString does by default.
+func (b *Builder) SetDurationFormat(f DurationFormat) {
+	b.durations = f
+}
+
+// resetFormats restores the default encodings of AppendJSON.
+func (b *Builder) resetFormats() {
+	b.rawHTML = false
+	b.durations = DurationString
+	b.bytes = BytesBase64
+	b.mapOrder = false
+}
+
+func (b *Builder) appendJSONDuration(d time.Duration) {
+	switch b.durations {
+	case DurationSeconds:
+		b.AppendFloat64(d.Seconds())
+	case DurationMillis:
+		b.AppendFloat64(float64(d) / float64(time.Millisecond))
+	case DurationNanos:
+		b.AppendInt(int64(d))
+	default:
+		b.WriteByte('"')
+		b.AppendDuration(d)
+		b.WriteByte('"')
+	}
+}
+
+// BytesFormat is the encoding of []byte values by AppendJSON.
+type BytesFormat int
+
+const (
+	// BytesBase64 encodes byte slices as standard base64 strings,
+	// as encoding/json does.
+	BytesBase64 BytesFormat = iota
+	// BytesBase64URL encodes byte slices as URL-safe base64 strings.
+	BytesBase64URL
+	// BytesHex encodes byte slices as lower-case hexadecimal strings,
+	// the usual notation of hashes and keys.
+	BytesHex
+	// BytesString encodes byte slices holding valid UTF-8 as strings,
+	// and the others as standard base64 strings.
+	BytesString
+)
+
+// SetBytesFormat specifies how AppendJSON encodes byte slices,
+// as BytesBase64 does by default.
+func (b *Builder) SetBytesFormat(f BytesFormat) {
+	b.bytes = f
+}
+
+// formattedBytes is a byte slice with its own encoding.
+type formattedBytes struct {
+	val    []byte
+	format BytesFormat
+}
+
+// FormatBytes returns a field whose byte slice value is encoded in format f,
+// whatever the encoding of the other byte slices:
+//
+//	log.Info("block stored", xlog.FormatBytes("sha256", sum[:], xlog.BytesHex))
+func FormatBytes(key string, val []byte, f BytesFormat) Field {
+	return Field{Key: key, Val: formattedBytes{val, f}}
+}
+
+func (b *Builder) appendJSONBytes(v []byte, f BytesFormat) {
+	switch f {
+	case BytesBase64URL:
+		n := base64.URLEncoding.EncodedLen(len(v))
+		b.Grow(n + 2)
+		b.WriteByte('"')
+		base64.URLEncoding.Encode(b.buf[b.Len():b.Len()+n], v)
+		b.buf = b.buf[:b.Len()+n]
+		b.WriteByte('"')
+	case BytesHex:
+		b.Grow(2*len(v) + 2)
+		b.WriteByte('"')
+		for _, c := range v {
+			b.buf = append(b.buf, _hex[c>>4], _hex[c&0xf])
+		}
+		b.WriteByte('"')
+	case BytesString:
+		if utf8.Valid(v) {
+			b.appendJSONString(string(v))
+			return
+		}
+		fallthrough
+	default:
+		b.WriteByte('"')
+		b.AppendByteSlice(v)
+		b.WriteByte('"')
+	}
+}
+
 // AppendByteSlice appends a base64 string representing []byte v.
 func (b *Builder) AppendByteSlice(v []byte) {
 	encodedLen := base64.StdEncoding.EncodedLen(len(v))
@@ -395,6 +608,13 @@ func (b *Builder) AppendByteSlice(v []byte) {
 // AppendJSON appends an json-style string literal representing v.
 // It implements a json-encoded subset of encoding/json and
 // remains compatible with encoding/json.
+//
+// Values that can't be encoded, such as self-referential values, are
+// replaced by a string marker describing the problem, so the output
+// is always valid JSON.
+//
+// The encoders registered with RegisterTypeEncoder take precedence over
+// the marshaling methods and the reflection.
 func (b *Builder) AppendJSON(iv interface{}) (err error) {
 	if iv == nil {
 		b.WriteString("null")
@@ -403,9 +623,9 @@ func (b *Builder) AppendJSON(iv interface{}) (err error) {
 
 	switch v := iv.(type) {
 	case *string:
-		b.AppendHTMLQuote(*v)
+		b.appendJSONString(*v)
 	case string:
-		b.AppendHTMLQuote(v)
+		b.appendJSONString(v)
 	case []string:
 		b.appendNullOrElse(v == nil, func() {
 			b.WriteByte('[')
@@ -413,7 +633,7 @@ func (b *Builder) AppendJSON(iv interface{}) (err error) {
 				if i > 0 {
 					b.WriteByte(',')
 				}
-				b.AppendHTMLQuote(e)
+				b.appendJSONString(e)
 			}
 			b.WriteByte(']')
 		})
@@ -528,9 +748,11 @@ func (b *Builder) AppendJSON(iv interface{}) (err error) {
 		b.AppendUint(uint64(v))
 	case []uint8:
 		b.appendNullOrElse(v == nil, func() {
-			b.WriteByte('"')
-			b.AppendByteSlice(v)
-			b.WriteByte('"')
+			b.appendJSONBytes(v, b.bytes)
+		})
+	case formattedBytes:
+		b.appendNullOrElse(v.val == nil, func() {
+			b.appendJSONBytes(v.val, v.format)
 		})
 	case *uint16:
 		b.AppendUint(uint64(*v))
@@ -654,13 +876,9 @@ func (b *Builder) AppendJSON(iv interface{}) (err error) {
 			b.WriteByte(']')
 		})
 	case *time.Duration:
-		b.WriteByte('"')
-		b.AppendDuration(*v)
-		b.WriteByte('"')
+		b.appendJSONDuration(*v)
 	case time.Duration:
-		b.WriteByte('"')
-		b.AppendDuration(v)
-		b.WriteByte('"')
+		b.appendJSONDuration(v)
 	case *time.Time:
 		b.WriteByte('"')
 		b.AppendTime(*v, Trfc3339Nano)
@@ -669,28 +887,256 @@ func (b *Builder) AppendJSON(iv interface{}) (err error) {
 		b.WriteByte('"')
 		b.AppendTime(v, Trfc3339Nano)
 		b.WriteByte('"')
+	case net.IP:
+		b.appendNullOrElse(v == nil, func() {
+			b.WriteByte('"')
+			b.WriteString(v.String())
+			b.WriteByte('"')
+		})
+	case *net.IPNet:
+		b.appendNullOrElse(v == nil, func() {
+			b.WriteByte('"')
+			b.WriteString(v.String())
+			b.WriteByte('"')
+		})
+	case *url.URL:
+		b.appendNullOrElse(v == nil, func() {
+			b.appendJSONString(v.String())
+		})
+	case url.URL:
+		b.appendJSONString(v.String())
+	case *time.Location:
+		b.appendNullOrElse(v == nil, func() {
+			b.appendJSONString(v.String())
+		})
+	case *big.Int:
+		b.appendNullOrElse(v == nil, func() {
+			b.buf = v.Append(b.buf, 10)
+		})
+	case *big.Float:
+		b.appendNullOrElse(v == nil, func() {
+			b.WriteByte('"')
+			b.buf = v.Append(b.buf, 'g', -1)
+			b.WriteByte('"')
+		})
+	case map[string]interface{}:
+		if v == nil {
+			b.WriteString("null")
+		} else {
+			err = b.appendJSONMap(v)
+		}
+	case map[string]string:
+		if v == nil {
+			b.WriteString("null")
+		} else {
+			b.appendJSONStringMap(v)
+		}
+	case json.RawMessage:
+		b.appendNullOrElse(len(v) == 0, func() {
+			b.Write(v)
+		})
+	case Frames:
+		v.appendTo(b)
+	case Audience:
+		b.appendJSONString(v.String())
+	case stringerValue:
+		v.appendTo(b)
+	case lazyValue:
+		err = b.AppendJSON(v.value())
+	case restrictedValue:
+		err = b.AppendJSON(v.f.Value())
+	default:
+		err = b.appendOther(v)
+	}
+	return
+}
+
+// appendOther appends the values of the types unknown to AppendJSON:
+// with their registered encoder, as errors, with their marshaling
+// methods, or else by reflection.
+func (b *Builder) appendOther(v interface{}) error {
+	if fn := lookupTypeEncoder(v); fn != nil {
+		fn(b, v)
+		return nil
+	}
+	switch v := v.(type) {
 	case error:
-		b.AppendHTMLQuote(v.Error())
+		b.appendError(v)
+	case json.Marshaler:
+		return b.appendMarshaler(v)
+	case encoding.TextMarshaler:
+		return b.appendTextMarshaler(v)
 	default:
-		len := b.Len()
-		b.prepareReflectEnc()
-		err = b.reflectEnc.Encode(v)
-		if err != nil {
-			b.buf = b.buf[:len]
-			return
+		return b.appendReflect(v)
+	}
+	return nil
+}
+
+// SetSortMapKeys specifies whether AppendJSON writes the keys of the
+// map[string]interface{} and map[string]string values sorted, as
+// encoding/json does; it does by default. Unsorted, the keys are written in
+// the iteration order of the map, which saves the sort of large maps.
+func (b *Builder) SetSortMapKeys(on bool) {
+	b.mapOrder = !on
+}
+
+// _mapKeysOnStack is the number of keys sorted without allocating.
+const _mapKeysOnStack = 16
+
+func (b *Builder) appendJSONMap(m map[string]interface{}) (err error) {
+	b.WriteByte('{')
+	if b.mapOrder {
+		i := 0
+		for k, v := range m {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			i++
+			if verr := b.appendJSONMapEntry(k, v); verr != nil && err == nil {
+				err = verr
+			}
 		}
+		b.WriteByte('}')
+		return
+	}
 
-		// ignore json.Encoder last '\n'
-		b.buf = b.buf[:b.Len()-1]
+	var arr [_mapKeysOnStack]string
+	keys := arr[:0]
+	for k := range m {
+		keys = append(keys, k)
+	}
+	for i, k := range sortStrings(keys) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if verr := b.appendJSONMapEntry(k, m[k]); verr != nil && err == nil {
+			err = verr
+		}
 	}
+	b.WriteByte('}')
 	return
 }
 
+func (b *Builder) appendJSONMapEntry(k string, v interface{}) error {
+	b.appendJSONString(k)
+	b.WriteByte(':')
+	if _, nested := v.(map[string]interface{}); nested {
+		// a map may contain itself, the reflection detects the cycles
+		return b.appendReflect(v)
+	}
+	return b.AppendJSON(v)
+}
+
+func (b *Builder) appendJSONStringMap(m map[string]string) {
+	b.WriteByte('{')
+	if b.mapOrder {
+		i := 0
+		for k, v := range m {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			i++
+			b.appendJSONString(k)
+			b.WriteByte(':')
+			b.appendJSONString(v)
+		}
+		b.WriteByte('}')
+		return
+	}
+
+	var arr [_mapKeysOnStack]string
+	keys := arr[:0]
+	for k := range m {
+		keys = append(keys, k)
+	}
+	for i, k := range sortStrings(keys) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.appendJSONString(k)
+		b.WriteByte(':')
+		b.appendJSONString(m[k])
+	}
+	b.WriteByte('}')
+}
+
+// sortStrings sorts keys, in place if they are few, so that a slice on the
+// stack stays there, or else a sorted copy is returned.
+func sortStrings(keys []string) []string {
+	if len(keys) > _mapKeysOnStack {
+		sorted := make([]string, len(keys))
+		copy(sorted, keys)
+		sort.Strings(sorted)
+		return sorted
+	}
+	// insertion sort
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+	return keys
+}
+
+// multipleErrors is implemented by errors wrapping several errors,
+// such as the ones returned by errors.Join.
+type multipleErrors interface {
+	Unwrap() []error
+}
+
+// appendError appends the message of err. The chain of causes of a field
+// holding an error is written by the field, see ErrorCausesSuffix.
+func (b *Builder) appendError(err error) {
+	b.appendJSONString(err.Error())
+}
+
+// appendErrorChain appends the chain of causes of err outermost first, as
+// an array of objects with the message and the type of each error. An
+// error made up of several errors, such as the ones combined by the Logger
+// or returned by errors.Join, is replaced by the chains of its errors:
+//
+//	[{"msg":"save: disk full","type":"*fmt.wrapError"},{"msg":"disk full","type":"*errors.errorString"}]
+func (b *Builder) appendErrorChain(err error) {
+	b.WriteByte('[')
+	b.appendErrorCauses(err, 0)
+	b.WriteByte(']')
+}
+
+// appendErrorCauses appends the causes of the chain of err, flattening
+// the multiple errors, n is the number of causes already appended.
+func (b *Builder) appendErrorCauses(err error, n int) int {
+	for i := 0; err != nil && i < _maxErrorChain; i++ {
+		switch v := err.(type) {
+		case *multiError:
+			for _, e := range v.errors {
+				n = b.appendErrorCauses(e, n)
+			}
+			return n
+		case multipleErrors:
+			for _, e := range v.Unwrap() {
+				n = b.appendErrorCauses(e, n)
+			}
+			return n
+		}
+		if n > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(`{"msg":`)
+		b.appendJSONString(err.Error())
+		b.WriteString(`,"type":`)
+		b.appendJSONString(typeName(err))
+		b.WriteByte('}')
+		n++
+		err = nextError(err)
+	}
+	return n
+}
+
 func (b *Builder) prepareReflectEnc() {
 	if b.reflectEnc == nil {
 		b.reflectEnc = json.NewEncoder(b)
-		// b.reflectEnc.SetIndent("", b.indent)
 	}
+	b.reflectEnc.SetEscapeHTML(!b.rawHTML)
 }
 
 func (b *Builder) appendNullOrElse(isNil bool, elseOp func()) {
@@ -704,6 +1150,18 @@ func (b *Builder) appendNullOrElse(isNil bool, elseOp func()) {
 // For JSON-escaping
 const _hex = "0123456789abcdef"
 
+// _digits100 holds the two-digit decimal representation of 0..99.
+const _digits100 = "00010203040506070809" +
+	"10111213141516171819" +
+	"20212223242526272829" +
+	"30313233343536373839" +
+	"40414243444546474849" +
+	"50515253545556575859" +
+	"60616263646566676869" +
+	"70717273747576777879" +
+	"80818283848586878889" +
+	"90919293949596979899"
+
 // safeSet holds the value true if the ASCII character with the given array
 // position can be represented inside a JSON string without any further
 // escaping.
@@ -741,9 +1199,59 @@ func init() {
 	htmlCompactSafeSet['&'] = false
 }
 
+// SWAR constants for scanning 8 bytes at a time.
+const (
+	_lsb = 0x0101010101010101
+	_msb = 0x8080808080808080
+)
+
+// cleanPrefix returns the length of the longest prefix of s
+// that can be written without any escaping.
+// Most messages are plain ASCII, so s is scanned in 8-byte chunks
+// before falling back to the per-byte lookup table.
+func cleanPrefix(s string, safeSet *[utf8.RuneSelf]bool) int {
+	html := safeSet == &htmlSafeSet
+	i := 0
+	for ; i+8 <= len(s); i += 8 {
+		v := uint64(s[i]) | uint64(s[i+1])<<8 | uint64(s[i+2])<<16 | uint64(s[i+3])<<24 |
+			uint64(s[i+4])<<32 | uint64(s[i+5])<<40 | uint64(s[i+6])<<48 | uint64(s[i+7])<<56
+		// control characters and non-ASCII bytes
+		mask := (v - _lsb*0x20) | v
+		// quote and backslash
+		mask |= hasZeroByte(v ^ (_lsb * '"'))
+		mask |= hasZeroByte(v ^ (_lsb * '\\'))
+		if html {
+			mask |= hasZeroByte(v ^ (_lsb * '<'))
+			mask |= hasZeroByte(v ^ (_lsb * '>'))
+			mask |= hasZeroByte(v ^ (_lsb * '&'))
+		}
+		if mask&_msb != 0 {
+			break
+		}
+	}
+	for ; i < len(s); i++ {
+		if c := s[i]; c >= utf8.RuneSelf || !(*safeSet)[c] {
+			break
+		}
+	}
+	return i
+}
+
+// hasZeroByte sets the high bit of every zero byte in v
+// (and possibly of bytes that follow one).
+func hasZeroByte(v uint64) uint64 {
+	return (v - _lsb) &^ v & _msb
+}
+
 func (b *Builder) appendEscape(s string, safeSet *[utf8.RuneSelf]bool) {
+	i := cleanPrefix(s, safeSet)
+	if i == len(s) { // fast path, nothing to escape
+		b.WriteString(s)
+		return
+	}
+
 	start := 0
-	for i := 0; i < len(s); {
+	for i < len(s) {
 		if c := s[i]; c < utf8.RuneSelf {
 			if (*safeSet)[c] {
 				i++
@@ -878,18 +1386,32 @@ func fmtNano(buf []byte, nano uint64, wid int, trimZeroSuffix bool) int {
 	return w
 }
 
+// builderSize is the initial capacity of the pooled builders,
+// raised by Preallocate.
+var builderSize int32 = 512
+
 var builderPool = sync.Pool{
 	New: func() interface{} {
-		return &Builder{buf: make([]byte, 0, 512)}
+		return &Builder{buf: make([]byte, 0, atomic.LoadInt32(&builderSize))}
 	},
 }
 
 func getBuilder() *Builder {
 	b := builderPool.Get().(*Builder)
 	b.Reset()
+	b.resetFormats()
 	return b
 }
 
 func putBuilder(b *Builder) {
+	if debugOwnership {
+		// poison the released storage and never reuse it, so that
+		// any slice retained after release shows up as garbage.
+		buf := b.buf[:cap(b.buf)]
+		for i := range buf {
+			buf[i] = 0xEE
+		}
+		return
+	}
 	builderPool.Put(b)
 }
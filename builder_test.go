@@ -5,8 +5,18 @@
 package xlog
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -55,6 +65,21 @@ func TestBuilder_AppendTime(t *testing.T) {
 	}
 }
 
+func TestBuilder_AppendTime_epoch(t *testing.T) {
+	tm := time.Date(1980, 1, 1, 12, 0, 0, 1234, time.UTC)
+	for flag, want := range map[int]string{
+		Tunix:                "315576000",
+		TunixMilli:           "315576000000",
+		TunixNano | Trfc3339: "315576000000001234",
+	} {
+		var builder Builder
+		builder.AppendTime(tm, flag)
+		if got := builder.String(); got != want {
+			t.Errorf("AppendTime(%#x) = %v, want %v", flag, got, want)
+		}
+	}
+}
+
 func TestBuilder_AppendDuration(t *testing.T) {
 	t.Run("builder.AppendDuration", func(t *testing.T) {
 		d := time.Duration(91989993334522)
@@ -68,6 +93,38 @@ func TestBuilder_AppendDuration(t *testing.T) {
 	})
 }
 
+func TestBuilder_AppendInt(t *testing.T) {
+	ints := []int64{0, 1, -1, 9, 10, -10, 99, 100, 101, 999, 1000, 12345, -98765,
+		1<<31 - 1, -1 << 31, 1234567890123, math.MaxInt64, math.MinInt64}
+	for _, i := range ints {
+		var builder Builder
+		builder.AppendInt(i)
+		if got, want := builder.String(), strconv.FormatInt(i, 10); got != want {
+			t.Errorf("Builder.AppendInt(%d) = %v, want %v", i, got, want)
+		}
+	}
+
+	uints := []uint64{0, 5, 10, 55, 100, 4096, 65535, 1<<32 - 1, math.MaxUint64}
+	for _, i := range uints {
+		var builder Builder
+		builder.AppendUint(i)
+		if got, want := builder.String(), strconv.FormatUint(i, 10); got != want {
+			t.Errorf("Builder.AppendUint(%d) = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestBuilder_CopyBytes(t *testing.T) {
+	var builder Builder
+	builder.WriteString("hello")
+	p := builder.CopyBytes()
+	builder.Reset()
+	builder.WriteString("world")
+	if string(p) != "hello" {
+		t.Errorf("Builder.CopyBytes() = %s, want hello", p)
+	}
+}
+
 func TestBuilder_AppendQuote(t *testing.T) {
 	testStrs := []string{
 		`"Fran & Freddie's Diner"`,
@@ -87,6 +144,78 @@ func TestBuilder_AppendQuote(t *testing.T) {
 	}
 }
 
+func TestBuilder_AppendQuote_cleanPrefix(t *testing.T) {
+	const clean = "builder provides a convenient way"
+	for _, special := range []string{"\"", "\\", "\n", "\x01", "<", ">", "&", "中", "\u2028"} {
+		for i := 0; i <= 20; i++ {
+			s := clean[:i] + special + clean[i:]
+			for _, html := range []bool{false, true} {
+				var want bytes.Buffer
+				enc := json.NewEncoder(&want)
+				enc.SetEscapeHTML(html)
+				enc.Encode(s)
+				want.Truncate(want.Len() - 1)
+
+				var builder Builder
+				if html {
+					builder.AppendHTMLQuote(s)
+				} else {
+					builder.AppendQuote(s)
+				}
+				if got := builder.String(); got != want.String() {
+					t.Errorf("quote(%q, html=%v) = %v, want %v", s, html, got, want.String())
+				}
+			}
+		}
+	}
+}
+
+// joinedErrors mimics the errors returned by errors.Join.
+type joinedErrors []error
+
+func (errs joinedErrors) Error() string {
+	var b Builder
+	for i, err := range errs {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (errs joinedErrors) Unwrap() []error { return errs }
+
+func TestField_errorCauses(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"error", errors.New("failed"), `"err":"failed"`},
+		{"multiError", combineErrors(errors.New("a"), combineErrors(errors.New("b"), errors.New("c"))),
+			`"err":"a; b; c","err_causes":[{"msg":"a","type":"*errors.errorString"},{"msg":"b","type":"*errors.errorString"},{"msg":"c","type":"*errors.errorString"}]`},
+		{"Unwrap() []error", joinedErrors{errors.New("a"), joinedErrors{errors.New("b")}},
+			`"err":"a\nb","err_causes":[{"msg":"a","type":"*errors.errorString"},{"msg":"b","type":"*errors.errorString"}]`},
+		{"wrapped error", fmt.Errorf("save: %w", combineErrors(errors.New("a"), fmt.Errorf("b: %w", io.EOF))),
+			`"err":"save: a; b: EOF","err_causes":[{"msg":"save: a; b: EOF","type":"*fmt.wrapError"},{"msg":"a","type":"*errors.errorString"},{"msg":"b: EOF","type":"*fmt.wrapError"},{"msg":"EOF","type":"*errors.errorString"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := F("err", tt.err).String(); got != tt.want {
+				t.Errorf("Field.String() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// the other encoders add the field too
+	var b Builder
+	NewGELFEncoder("h").Encode(&b, Entry{Fields: []Field{F("err", fmt.Errorf("save: %w", io.EOF))}})
+	if !strings.Contains(b.String(), `"_err_causes":"[{\"msg\":\"save: EOF\",`) {
+		t.Errorf("GELF output = %s", b.String())
+	}
+}
+
 func TestBuild_AppendJSON(t *testing.T) {
 	type Embed struct {
 		F float64
@@ -106,6 +235,9 @@ func TestBuild_AppendJSON(t *testing.T) {
 	tm := time.Now()
 
 	embptr := &Embed{9.9}
+	_, ipnet, _ := net.ParseCIDR("10.0.0.0/8")
+	u, _ := url.Parse("https://example.com/a?b=c&d=e")
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
 
 	tests := []struct {
 		label string
@@ -165,6 +297,23 @@ func TestBuild_AppendJSON(t *testing.T) {
 		{"*duration", &dt, `"` + dt.String() + `"`},
 		{"time", tm, `"` + tm.Format(time.RFC3339Nano) + `"`},
 		{"*time", &tm, `"` + tm.Format(time.RFC3339Nano) + `"`},
+		{"net.IP", net.ParseIP("192.168.1.10"), `"192.168.1.10"`},
+		{"net.IP(v6)", net.ParseIP("2001:db8::1"), `"2001:db8::1"`},
+		{"net.IP(nil)", net.IP(nil), "null"},
+		{"*net.IPNet", ipnet, `"10.0.0.0/8"`},
+		{"*url.URL", u, `"https://example.com/a?b=c\u0026d=e"`},
+		{"url.URL", *u, `"https://example.com/a?b=c\u0026d=e"`},
+		{"*time.Location", time.UTC, `"UTC"`},
+		{"*big.Int", bigInt, "123456789012345678901234567890"},
+		{"*big.Float", big.NewFloat(1.5), `"1.5"`},
+		{"map[string]interface{}", map[string]interface{}{"b": 1, "a": []string{"x"}, "c": map[string]string{"<": "&"}}, `{"a":["x"],"b":1,"c":{"\u003c":"\u0026"}}`},
+		{"map[string]interface{}(nil)", map[string]interface{}(nil), "null"},
+		{"map[string]string", map[string]string{"b": "2", "a": "1"}, `{"a":"1","b":"2"}`},
+		{"map[string]string(nil)", map[string]string(nil), "null"},
+		{"json.RawMessage", json.RawMessage(`{"a":1}`), `{"a":1}`},
+		{"error", errors.New("failed"), `"failed"`},
+		{"multiError", combineErrors(errors.New("a"), combineErrors(errors.New("b"), errors.New("c"))), `"a; b; c"`},
+		{"wrapped error", fmt.Errorf("save: %w", io.EOF), `"save: EOF"`},
 		{"struct(embed)", struct {
 			Name string
 			Age  int
@@ -200,6 +349,44 @@ func TestBuild_AppendJSON(t *testing.T) {
 		})
 	}
 }
+func TestBuilder_AppendJSON_maps(t *testing.T) {
+	m := make(map[string]interface{})
+	sm := make(map[string]string)
+	for i := 0; i < 3*_mapKeysOnStack; i++ {
+		k := strconv.Itoa(i * 7919 % 100)
+		m[k] = i
+		sm[k] = k
+	}
+
+	for _, v := range []interface{}{m, sm} {
+		var b Builder
+		b.AppendJSON(v)
+		want, _ := json.Marshal(v)
+		if got := b.String(); got != string(want) {
+			t.Errorf("AppendJSON(%T) = %s, want %s", v, got, want)
+		}
+
+		// unsorted, the output decodes to the same map
+		b.Reset()
+		b.SetSortMapKeys(false)
+		b.AppendJSON(v)
+		var got map[string]interface{}
+		if err := json.Unmarshal(b.Bytes(), &got); err != nil || len(got) != len(m) {
+			t.Errorf("AppendJSON(%T) unsorted = %s, %v", v, b.String(), err)
+		}
+	}
+
+	var b Builder
+	small := map[string]string{"c": "3", "a": "1", "b": "2"}
+	allocs := testing.AllocsPerRun(100, func() {
+		b.Reset()
+		b.AppendJSON(small)
+	})
+	if allocs != 0 {
+		t.Errorf("AppendJSON(map[string]string) allocs = %v, want 0", allocs)
+	}
+}
+
 func BenchmarkStd_AppendTime(b *testing.B) {
 	var sb Builder
 	now := time.Now()
@@ -224,6 +411,30 @@ func BenchmarkBuild_AppendTime(b *testing.B) {
 	}
 }
 
+func BenchmarkStd_AppendInt(b *testing.B) {
+	var sb Builder
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		sb.Reset()
+		sb.buf = strconv.AppendInt(sb.buf, int64(i&0xffff), 10)
+		sb.buf = strconv.AppendInt(sb.buf, 1234567890123, 10)
+	}
+}
+
+func BenchmarkBuilder_AppendInt(b *testing.B) {
+	var sb Builder
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		sb.Reset()
+		sb.AppendInt(int64(i & 0xffff))
+		sb.AppendInt(1234567890123)
+	}
+}
+
 func BenchmarkStd_Quote(b *testing.B) {
 	var sb Builder
 	b.ReportAllocs()
@@ -256,3 +467,15 @@ func BenchmarkBuilder_HTMLQuote(b *testing.B) {
 		sb.AppendHTMLQuote("builder provides a convenient way to build strings.\n")
 	}
 }
+
+func BenchmarkBuilder_HTMLQuoteLong(b *testing.B) {
+	var sb Builder
+	s := "Failed to fetch URL, the remote server closed the connection unexpectedly."
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		sb.Reset()
+		sb.AppendHTMLQuote(s)
+	}
+}
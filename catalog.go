@@ -0,0 +1,152 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"strings"
+	"sync"
+)
+
+// Catalog maps message codes to localized templates for operator-facing
+// output. Log with the canonical code as the message, e.g.
+//
+//	log.Error("disk.full", xlog.F("path", path))
+//
+// and add a template per locale:
+//
+//	c.Add("en", "disk.full", "disk {path} is full")
+//	c.Add("de", "disk.full", "Datenträger {path} ist voll")
+//
+// A {key} placeholder is replaced by the value of the field key.
+// The JSON output keeps the code, so it stays stable across locales.
+//
+// A Catalog is safe for concurrent use.
+type Catalog struct {
+	mu       sync.RWMutex
+	locale   string
+	fallback string
+	msgs     map[string]map[string]string // locale -> code -> template
+}
+
+// NewCatalog creates an empty Catalog for locale. Codes missing in locale
+// are looked up in the fallback locale, then printed as they are.
+func NewCatalog(locale, fallback string) *Catalog {
+	return &Catalog{
+		locale:   locale,
+		fallback: fallback,
+		msgs:     make(map[string]map[string]string),
+	}
+}
+
+// Add adds the template of code in locale.
+func (c *Catalog) Add(locale, code, template string) *Catalog {
+	c.mu.Lock()
+	m := c.msgs[locale]
+	if m == nil {
+		m = make(map[string]string)
+		c.msgs[locale] = m
+	}
+	m[code] = template
+	c.mu.Unlock()
+	return c
+}
+
+// SetLocale switches the locale of the output.
+func (c *Catalog) SetLocale(locale string) {
+	c.mu.Lock()
+	c.locale = locale
+	c.mu.Unlock()
+}
+
+// Locale returns the current locale.
+func (c *Catalog) Locale() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.locale
+}
+
+// Lookup returns the template of code in the current locale,
+// or in the fallback locale.
+func (c *Catalog) Lookup(code string) (template string, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if template, ok = c.msgs[c.locale][code]; !ok {
+		template, ok = c.msgs[c.fallback][code]
+	}
+	return
+}
+
+// appendMessage appends the localized message of e.
+func (c *Catalog) appendMessage(b *Builder, e Entry) {
+	tmpl, ok := c.Lookup(e.Message)
+	if !ok {
+		b.WriteString(e.Message)
+		return
+	}
+
+	appendTemplate(b, tmpl, e, false)
+}
+
+// appendTemplate appends tmpl with each {key} placeholder replaced by the
+// value of the field key of e. If reserved is true, the placeholders
+// {level}, {logger} and {msg} are replaced by the level, the logger name
+// and the message of e. Unknown placeholders are kept as they are.
+func appendTemplate(b *Builder, tmpl string, e Entry, reserved bool) {
+	for {
+		i := strings.IndexByte(tmpl, '{')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(tmpl[i:], '}')
+		if j < 0 {
+			break
+		}
+		b.WriteString(tmpl[:i])
+		key := tmpl[i+1 : i+j]
+		tmpl = tmpl[i+j+1:]
+
+		if reserved {
+			switch key {
+			case "level":
+				b.WriteString(e.Level.CapitalString())
+				continue
+			case "logger":
+				b.WriteString(e.LoggerName)
+				continue
+			case "msg":
+				b.WriteString(e.Message)
+				continue
+			}
+		}
+		if f, ok := lookupField(e, key); ok {
+			if s, isStr := f.stringValue(); isStr {
+				b.WriteString(s)
+			} else {
+				f.appendValue(b)
+			}
+		} else {
+			b.WriteByte('{')
+			b.WriteString(key)
+			b.WriteByte('}')
+		}
+	}
+	b.WriteString(tmpl)
+}
+
+// lookupField returns the last field of e with key;
+// the log-site fields take precedence over the preset ones.
+func lookupField(e Entry, key string) (Field, bool) {
+	for i := len(e.Fields) - 1; i >= 0; i-- {
+		if e.Fields[i].Key == key {
+			return e.Fields[i], true
+		}
+	}
+	for i := len(e.Ctx) - 1; i >= 0; i-- {
+		if e.Ctx[i].Key == key {
+			return e.Ctx[i], true
+		}
+	}
+	return Field{}, false
+}
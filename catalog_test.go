@@ -0,0 +1,48 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	c := NewCatalog("de", "en").
+		Add("en", "disk.full", "disk {path} is full").
+		Add("en", "disk.slow", "disk {path} is slow: {latency} ms, {missing}").
+		Add("de", "disk.full", "Datenträger {path} ist voll")
+
+	var cbuf, jbuf bytes.Buffer
+	log := New(NewTee(
+		NewCore(NewConsoleEncoder(0, WithCatalog(c)), &cbuf, DebugLevel),
+		NewCore(NewJSONEncoder(0), &jbuf, DebugLevel)),
+		Fields(F("path", "/ctx")))
+
+	log.Error("disk.full", F("path", "/data"))
+	log.Warn("disk.slow", F("latency", 12))
+	log.Info("unknown {path}")
+
+	want := `ERROR Datenträger /data ist voll
+ -  {"path":"/ctx","path":"/data"}
+WARN  disk /ctx is slow: 12 ms, {missing}
+ -  {"path":"/ctx","latency":12}
+INFO  unknown {path}
+ -  {"path":"/ctx"}
+`
+	got := regexp.MustCompile("\x1b\\[[0-9]+m").ReplaceAllString(cbuf.String(), "")
+	if got != want {
+		t.Errorf("console = %q, want %q", got, want)
+	}
+	if !bytes.Contains(jbuf.Bytes(), []byte(`"msg":"disk.full"`)) {
+		t.Errorf("json = %s, want canonical code", jbuf.Bytes())
+	}
+
+	c.SetLocale("en")
+	if tmpl, _ := c.Lookup("disk.full"); tmpl != "disk {path} is full" || c.Locale() != "en" {
+		t.Errorf("Lookup() = %q", tmpl)
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+)
+
+// CBOR major types (RFC 8949).
+const (
+	cborUint   = 0 << 5
+	cborNegInt = 1 << 5
+	cborBytes  = 2 << 5
+	cborText   = 3 << 5
+	cborArray  = 4 << 5
+	cborMap    = 5 << 5
+	cborTag    = 6 << 5
+	cborSimple = 7 << 5
+
+	cborFalse   = cborSimple | 20
+	cborTrue    = cborSimple | 21
+	cborNull    = cborSimple | 22
+	cborFloat64 = cborSimple | 27
+
+	cborTagDateTime = 0 // RFC 3339 date/time string
+	cborTagEpoch    = 1 // epoch-based date/time
+)
+
+type cborEncoder int
+
+// NewCBOREncoder returns an encoder writing each entry as a CBOR map
+// (RFC 8949), so the output is a CBOR sequence of compact, self-describing
+// binary records. The map has the keys of the JSON encoder; time.Time
+// values are encoded as tag 1 epoch times, or tag 0 RFC 3339 strings if
+// they have a fraction of a second, byte slices as byte strings, and
+// values of other types as their JSON encoding does.
+//
+// Only the Llongfile, Lshortfile and Lschema flags apply.
+func NewCBOREncoder(flags int) Encoder { return cborEncoder(flags) }
+
+func (enc cborEncoder) Encode(b *Builder, e Entry) error {
+	e = nestEntry(e)
+	flags := int(enc)
+	caller := flags&(Llongfile|Lshortfile) != 0 && e.Caller.Defined
+
+	n := 3 + len(e.Ctx) + len(e.Fields) // level, time, msg
+	if flags&Lschema != 0 {
+		n++
+	}
+	if !e.IngestTime.IsZero() {
+		n++
+	}
+	if e.LoggerName != "" {
+		n++
+	}
+	if caller {
+		n++
+	}
+	if len(e.Stack) > 0 {
+		n++
+	}
+	b.appendCBORHead(cborMap, uint64(n))
+
+	if flags&Lschema != 0 {
+		b.appendCBORText("schema")
+		b.appendCBORInt(SchemaVersion)
+	}
+	b.appendCBORText("level")
+	b.appendCBORText(e.Level.CapitalString())
+	b.appendCBORText("time")
+	b.appendCBORTime(e.Time)
+	if !e.IngestTime.IsZero() {
+		b.appendCBORText("ingest_time")
+		b.appendCBORTime(e.IngestTime)
+	}
+	if e.LoggerName != "" {
+		b.appendCBORText("logger")
+		b.appendCBORText(e.LoggerName)
+	}
+	if caller {
+		b.appendCBORText("caller")
+		c := getBuilder()
+		c.WriteString(callerFile(e.Caller.File, flags))
+		c.WriteByte(':')
+		c.AppendInt(int64(e.Caller.Line))
+		b.appendCBORText(c.String())
+		putBuilder(c)
+	}
+	b.appendCBORText("msg")
+	b.appendCBORText(e.Message)
+	if len(e.Stack) > 0 {
+		b.appendCBORText("stacktrace")
+		b.appendCBORFrames(e.Stack)
+	}
+
+	var err error
+	for _, fs := range [2][]Field{e.Ctx, e.Fields} {
+		for _, f := range fs {
+			b.appendCBORText(f.Key)
+			if ferr := b.appendCBOR(f.Value()); ferr != nil && err == nil {
+				err = ferr
+			}
+		}
+	}
+	return err
+}
+
+// appendCBORFrames appends fs as an array of maps, with the keys of their
+// JSON encoding.
+func (b *Builder) appendCBORFrames(fs Frames) {
+	b.appendCBORHead(cborArray, uint64(len(fs)))
+	for _, f := range fs {
+		b.appendCBORHead(cborMap, 3)
+		b.appendCBORText("func")
+		b.appendCBORText(f.Function)
+		b.appendCBORText("file")
+		b.appendCBORText(f.File)
+		b.appendCBORText("line")
+		b.appendCBORInt(int64(f.Line))
+	}
+}
+
+// appendCBORHead appends the head of a data item of major type major
+// with the argument n.
+func (b *Builder) appendCBORHead(major byte, n uint64) {
+	switch {
+	case n < 24:
+		b.buf = append(b.buf, major|byte(n))
+	case n <= math.MaxUint8:
+		b.buf = append(b.buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		b.buf = append(b.buf, major|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		b.buf = append(b.buf, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	default:
+		b.buf = append(b.buf, major|27, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32),
+			byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+func (b *Builder) appendCBORInt(i int64) {
+	if i >= 0 {
+		b.appendCBORHead(cborUint, uint64(i))
+	} else {
+		b.appendCBORHead(cborNegInt, uint64(-(i + 1)))
+	}
+}
+
+func (b *Builder) appendCBORFloat(f float64) {
+	bits := math.Float64bits(f)
+	b.buf = append(b.buf, cborFloat64, byte(bits>>56), byte(bits>>48), byte(bits>>40), byte(bits>>32),
+		byte(bits>>24), byte(bits>>16), byte(bits>>8), byte(bits))
+}
+
+func (b *Builder) appendCBORText(s string) {
+	b.appendCBORHead(cborText, uint64(len(s)))
+	b.buf = append(b.buf, s...)
+}
+
+// appendCBORTime appends t as tag 1, integer seconds since the epoch,
+// or, if t has a fraction of a second, as tag 0, its RFC 3339 string in
+// UTC with the nanoseconds, which floating-point seconds can't hold.
+func (b *Builder) appendCBORTime(t time.Time) {
+	if t.Nanosecond() == 0 {
+		b.appendCBORHead(cborTag, cborTagEpoch)
+		b.appendCBORInt(t.Unix())
+		return
+	}
+	b.appendCBORHead(cborTag, cborTagDateTime)
+	var buf [len(time.RFC3339Nano)]byte
+	p := t.UTC().AppendFormat(buf[:0], time.RFC3339Nano)
+	b.appendCBORHead(cborText, uint64(len(p)))
+	b.buf = append(b.buf, p...)
+}
+
+// appendCBOR appends the CBOR encoding of v.
+func (b *Builder) appendCBOR(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		b.WriteByte(cborNull)
+	case string:
+		b.appendCBORText(v)
+	case []byte:
+		if v == nil {
+			b.WriteByte(cborNull)
+			return nil
+		}
+		b.appendCBORHead(cborBytes, uint64(len(v)))
+		b.buf = append(b.buf, v...)
+	case bool:
+		if v {
+			b.WriteByte(cborTrue)
+		} else {
+			b.WriteByte(cborFalse)
+		}
+	case int:
+		b.appendCBORInt(int64(v))
+	case int8:
+		b.appendCBORInt(int64(v))
+	case int16:
+		b.appendCBORInt(int64(v))
+	case int32:
+		b.appendCBORInt(int64(v))
+	case int64:
+		b.appendCBORInt(v)
+	case uint:
+		b.appendCBORHead(cborUint, uint64(v))
+	case uint8:
+		b.appendCBORHead(cborUint, uint64(v))
+	case uint16:
+		b.appendCBORHead(cborUint, uint64(v))
+	case uint32:
+		b.appendCBORHead(cborUint, uint64(v))
+	case uint64:
+		b.appendCBORHead(cborUint, v)
+	case float32:
+		b.appendCBORFloat(float64(v))
+	case float64:
+		b.appendCBORFloat(v)
+	case time.Time:
+		b.appendCBORTime(v)
+	case *time.Time:
+		if v == nil {
+			b.WriteByte(cborNull)
+			return nil
+		}
+		b.appendCBORTime(*v)
+	case Field:
+		b.appendCBORHead(cborMap, 1)
+		b.appendCBORText(v.Key)
+		return b.appendCBOR(v.Value())
+	case O:
+		b.appendCBORHead(cborMap, uint64(len(v)))
+		var err error
+		for _, f := range v {
+			b.appendCBORText(f.Key)
+			if ferr := b.appendCBOR(f.Value()); ferr != nil && err == nil {
+				err = ferr
+			}
+		}
+		return err
+	case []O:
+		b.appendCBORHead(cborArray, uint64(len(v)))
+		for _, o := range v {
+			if err := b.appendCBOR(o); err != nil {
+				return err
+			}
+		}
+	default:
+		return b.appendCBORViaJSON(v)
+	}
+	return nil
+}
+
+// appendCBORViaJSON encodes v as its JSON encoding does,
+// by converting the JSON document to CBOR.
+func (b *Builder) appendCBORViaJSON(v interface{}) error {
+	jb := getBuilder()
+	defer putBuilder(jb)
+	err := jb.AppendJSON(v)
+
+	dec := json.NewDecoder(bytes.NewReader(jb.Bytes()))
+	dec.UseNumber()
+	var doc interface{}
+	if derr := dec.Decode(&doc); derr != nil {
+		b.appendCBORText(jb.String())
+		return derr
+	}
+	b.appendCBORDoc(doc)
+	return err
+}
+
+// appendCBORDoc appends a value decoded by encoding/json with UseNumber.
+func (b *Builder) appendCBORDoc(doc interface{}) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.appendCBORHead(cborMap, uint64(len(v)))
+		for _, k := range keys {
+			b.appendCBORText(k)
+			b.appendCBORDoc(v[k])
+		}
+	case []interface{}:
+		b.appendCBORHead(cborArray, uint64(len(v)))
+		for _, e := range v {
+			b.appendCBORDoc(e)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			b.appendCBORInt(i)
+		} else if f, err := v.Float64(); err == nil {
+			b.appendCBORFloat(f)
+		} else {
+			b.appendCBORText(v.String())
+		}
+	default:
+		b.appendCBOR(v) // string, bool, nil
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type cborTagged struct {
+	Tag uint64
+	Val interface{}
+}
+
+// decodeCBOR is a minimal CBOR decoder for the items written by the encoder.
+func decodeCBOR(t *testing.T, p []byte) (interface{}, []byte) {
+	t.Helper()
+	major, info := p[0]&0xe0, p[0]&0x1f
+	p = p[1:]
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info == 24:
+		n, p = uint64(p[0]), p[1:]
+	case info == 25:
+		n, p = uint64(binary.BigEndian.Uint16(p)), p[2:]
+	case info == 26:
+		n, p = uint64(binary.BigEndian.Uint32(p)), p[4:]
+	case info == 27:
+		n, p = binary.BigEndian.Uint64(p), p[8:]
+	}
+
+	switch major {
+	case cborUint:
+		return int64(n), p
+	case cborNegInt:
+		return -1 - int64(n), p
+	case cborBytes:
+		return p[:n], p[n:]
+	case cborText:
+		return string(p[:n]), p[n:]
+	case cborArray:
+		a := make([]interface{}, n)
+		for i := range a {
+			a[i], p = decodeCBOR(t, p)
+		}
+		return a, p
+	case cborMap:
+		m := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			var k, v interface{}
+			k, p = decodeCBOR(t, p)
+			v, p = decodeCBOR(t, p)
+			m[k.(string)] = v
+		}
+		return m, p
+	case cborTag:
+		var v interface{}
+		v, p = decodeCBOR(t, p)
+		return cborTagged{n, v}, p
+	default:
+		switch info {
+		case 20:
+			return false, p
+		case 21:
+			return true, p
+		case 22:
+			return nil, p
+		case 27:
+			return math.Float64frombits(n), p
+		}
+	}
+	t.Fatalf("unexpected CBOR initial byte %#x", major|info)
+	return nil, nil
+}
+
+func TestCBOREncoder(t *testing.T) {
+	at := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+	e := Entry{
+		Level:      ErrorLevel,
+		Time:       at.Add(500 * time.Millisecond),
+		LoggerName: "svc",
+		Caller:     NewEntryCaller(0, "/src/a.go", 7, true),
+		Message:    "failed",
+		Stack:      Frames{{"main.run", "/src/main.go", 12}},
+		Ctx:        []Field{F("id", -300)},
+		Fields: []Field{
+			F("data", []byte{1, 2}), F("at", at), F("ok", true), F("ratio", 0.5),
+			F("n", uint64(70000)), F("obj", O{F("k", nil)}), F("list", []int{1, 2}),
+			F("m", map[string]int{"x": 1}),
+		},
+	}
+
+	var b Builder
+	if err := NewCBOREncoder(Lshortfile|Lschema).Encode(&b, e); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, rest := decodeCBOR(t, b.Bytes())
+	if len(rest) != 0 {
+		t.Errorf("trailing bytes %x", rest)
+	}
+
+	want := map[string]interface{}{
+		"schema": int64(1),
+		"level":  "ERROR",
+		"time":   cborTagged{0, "2019-04-01T12:00:00.5Z"},
+		"logger": "svc",
+		"caller": "a.go:7",
+		"msg":    "failed",
+		"id":     int64(-300),
+		"data":   []byte{1, 2},
+		"at":     cborTagged{1, at.Unix()},
+		"ok":     true,
+		"ratio":  0.5,
+		"n":      int64(70000),
+		"obj":    map[string]interface{}{"k": nil},
+		"list":   []interface{}{int64(1), int64(2)},
+		"m":      map[string]interface{}{"x": int64(1)},
+		"stacktrace": []interface{}{
+			map[string]interface{}{"func": "main.run", "file": "/src/main.go", "line": int64(12)},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Encode() = %#v\nwant %#v", got, want)
+	}
+}
+
+func TestAppendCBORTime(t *testing.T) {
+	for _, at := range []time.Time{
+		time.Unix(1554120000, 0),
+		time.Unix(1791115200, 123456789), // float64 seconds would lose the nanoseconds
+		time.Date(2019, 4, 1, 20, 0, 0, 1, time.FixedZone("CST", 8*3600)),
+	} {
+		var b Builder
+		b.appendCBORTime(at)
+		v, _ := decodeCBOR(t, b.Bytes())
+		var got time.Time
+		switch v := v.(cborTagged); v.Tag {
+		case 0:
+			got, _ = time.Parse(time.RFC3339Nano, v.Val.(string))
+		case 1:
+			got = time.Unix(v.Val.(int64), 0)
+		}
+		if !got.Equal(at) {
+			t.Errorf("appendCBORTime(%v) decoded as %v", at, got)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"sort"
+	"time"
+)
+
+// A Clock provides the time of log entries.
+type Clock interface {
+	Now() time.Time
+}
+
+// FixedClock is a Clock always returning the same time.
+type FixedClock time.Time
+
+// Now returns the fixed time.
+func (c FixedClock) Now() time.Time { return time.Time(c) }
+
+// WithClock configures the Logger to stamp entries with the time of c
+// instead of the system clock.
+func WithClock(c Clock) Option {
+	return optionFunc(func(log *Logger) {
+		log.clock = c
+	})
+}
+
+// SortFields configures the Logger to sort the preset fields and the
+// log-site fields of each entry by key, so their order doesn't depend on
+// the order the fields were added in.
+func SortFields() Option {
+	return optionFunc(func(log *Logger) {
+		log.sortFields = true
+	})
+}
+
+// DeterministicTime is the time of the entries of a Deterministic Logger.
+var DeterministicTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Deterministic bundles the options making identical code paths produce
+// byte-identical output across runs, for snapshot-based tests: entries are
+// stamped with DeterministicTime, callers are not annotated since line
+// numbers move as code is edited, and fields are sorted by key.
+func Deterministic() Option {
+	return optionFunc(func(log *Logger) {
+		log.clock = FixedClock(DeterministicTime)
+		log.addCaller = false
+		log.callerDepth = 0
+		log.sortFields = true
+	})
+}
+
+// sortedFields returns a copy of fs stably sorted by key.
+func sortedFields(fs []Field) []Field {
+	if len(fs) < 2 {
+		return fs
+	}
+	sorted := make([]Field, len(fs))
+	copy(sorted, fs)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+	return sorted
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDeterministic(t *testing.T) {
+	run := func() string {
+		var buf bytes.Buffer
+		log := New(NewCore(NewJSONEncoder(Lshortfile), &buf, DebugLevel),
+			AddCaller(), Fields(F("z", 1), F("a", 2)), Deterministic())
+		log.Info("first", F("b", true), F("a", "x"))
+		return buf.String()
+	}
+
+	want := `{"level":"INFO","time":"2000-01-01T00:00:00Z","msg":"first","a":2,"z":1,"a":"x","b":true}
+`
+	if got := run(); got != want {
+		t.Errorf("Out = %s, want %s", got, want)
+	}
+	if run() != run() {
+		t.Errorf("output differs across runs")
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	var buf bytes.Buffer
+	at := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+	New(NewCore(NewJSONEncoder(0), &buf, DebugLevel), WithClock(FixedClock(at))).Info("m")
+	if !bytes.Contains(buf.Bytes(), []byte(`"time":"2019-04-01T12:00:00Z"`)) {
+		t.Errorf("Out = %s", buf.Bytes())
+	}
+}
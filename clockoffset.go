@@ -0,0 +1,158 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// ClockOffsetKey is the key of the field added by a ClockOffsetCore.
+const ClockOffsetKey = "clock_offset_ms"
+
+// ClockOffsetConfig configures a ClockOffsetCore.
+type ClockOffsetConfig struct {
+	// Sample measures the offset of the reference clock from the local
+	// clock. If nil, the offset is measured by SNTP against Server.
+	Sample func() (time.Duration, error)
+	// Server is the "host:port" of the NTP server, "pool.ntp.org:123"
+	// if empty. The port defaults to 123.
+	Server string
+	// Interval between two samples taken by Run, 10 minutes if zero.
+	Interval time.Duration
+}
+
+// ClockOffsetCore is a Core stamping each entry with the last sampled
+// offset of the local clock from a reference clock, as the field
+// "clock_offset_ms", in milliseconds, so that the aggregation of the logs
+// of several hosts can correct their skew when ordering the entries: the
+// reference time of an entry is its time plus the offset. No field is
+// added until an offset is sampled.
+type ClockOffsetCore struct {
+	Core
+	cfg ClockOffsetConfig
+
+	offset int64 // time.Duration
+	known  int32
+}
+
+// NewClockOffsetCore creates a ClockOffsetCore writing to core. The offset
+// is sampled by calling Sample or Run.
+func NewClockOffsetCore(core Core, cfg ClockOffsetConfig) *ClockOffsetCore {
+	if cfg.Server == "" {
+		cfg.Server = "pool.ntp.org:123"
+	} else if _, _, err := net.SplitHostPort(cfg.Server); err != nil {
+		cfg.Server = net.JoinHostPort(cfg.Server, "123")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Minute
+	}
+	if cfg.Sample == nil {
+		server := cfg.Server
+		cfg.Sample = func() (time.Duration, error) { return SNTPOffset(server, 5*time.Second) }
+	}
+	return &ClockOffsetCore{Core: core, cfg: cfg}
+}
+
+// Offset returns the last sampled offset, and whether one was sampled.
+func (c *ClockOffsetCore) Offset() (time.Duration, bool) {
+	return time.Duration(atomic.LoadInt64(&c.offset)), atomic.LoadInt32(&c.known) != 0
+}
+
+// Sample samples the offset once. On error, the last offset is kept.
+func (c *ClockOffsetCore) Sample() error {
+	offset, err := c.cfg.Sample()
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&c.offset, int64(offset))
+	atomic.StoreInt32(&c.known, 1)
+	return nil
+}
+
+// Run samples the offset at once, then every Interval until ctx is done.
+// It's usually run in its own goroutine.
+func (c *ClockOffsetCore) Run(ctx context.Context) {
+	c.Sample()
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Sample()
+		}
+	}
+}
+
+// Write writes e with the clock offset to the underlying core.
+func (c *ClockOffsetCore) Write(e Entry) error {
+	if offset, ok := c.Offset(); ok {
+		ms := float64(offset) / float64(time.Millisecond)
+		e.Fields = append(e.Fields[:len(e.Fields):len(e.Fields)], Float64(ClockOffsetKey, ms))
+	}
+	return c.Core.Write(e)
+}
+
+// the seconds from the NTP epoch, 1900, to the Unix epoch
+const _ntpEpochOffset = 2208988800
+
+var errSNTPResponse = errors.New("xlog: invalid sntp response")
+
+// SNTPOffset queries the NTP server at "host:port" with SNTP (RFC 4330),
+// and returns the offset of its clock from the local clock, corrected for
+// the network delay.
+func SNTPOffset(server string, timeout time.Duration) (time.Duration, error) {
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	req := make([]byte, 48)
+	req[0] = 0x23 // LI 0, version 4, mode 3 (client)
+	t1 := time.Now()
+	putNTPTime(req[40:], t1) // transmit timestamp, echoed as the originate one
+	if _, err = conn.Write(req); err != nil {
+		return 0, err
+	}
+
+	resp := make([]byte, 48)
+	for {
+		n, err := conn.Read(resp)
+		if err != nil {
+			return 0, err
+		}
+		t4 := time.Now()
+		// skip the responses to other requests
+		if n < 48 || binary.BigEndian.Uint64(resp[24:]) != binary.BigEndian.Uint64(req[40:]) {
+			continue
+		}
+		if mode := resp[0] & 7; mode != 4 || resp[1] == 0 { // server mode, stratum 0 is kiss-o'-death
+			return 0, errSNTPResponse
+		}
+		t2, t3 := ntpTime(resp[32:]), ntpTime(resp[40:])
+		return (t2.Sub(t1) + t3.Sub(t4)) / 2, nil
+	}
+}
+
+// ntpTime decodes an NTP timestamp.
+func ntpTime(p []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(p)) - _ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(p[4:]))
+	return time.Unix(secs, frac*1e9>>32)
+}
+
+// putNTPTime encodes t as an NTP timestamp in p.
+func putNTPTime(p []byte, t time.Time) {
+	binary.BigEndian.PutUint32(p, uint32(t.Unix()+_ntpEpochOffset))
+	binary.BigEndian.PutUint32(p[4:], uint32(int64(t.Nanosecond())<<32/1e9))
+}
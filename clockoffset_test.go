@@ -0,0 +1,78 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClockOffsetCore(t *testing.T) {
+	var buf bytes.Buffer
+	offset := 1500 * time.Microsecond
+	c := NewClockOffsetCore(NewCore(NewJSONEncoder(0), &buf, DebugLevel), ClockOffsetConfig{
+		Sample: func() (time.Duration, error) { return offset, nil },
+	})
+	log := New(c)
+
+	log.Info("unknown")
+	if err := c.Sample(); err != nil {
+		t.Fatal(err)
+	}
+	log.Info("known", F("n", 1))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || strings.Contains(lines[0], ClockOffsetKey) {
+		t.Fatalf("Out = %s, want no offset before the first sample", buf.String())
+	}
+	if !strings.HasSuffix(lines[1], `"n":1,"clock_offset_ms":1.5}`) {
+		t.Errorf("entry = %s, want the offset in milliseconds", lines[1])
+	}
+}
+
+func TestSNTPOffset(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	// a server whose clock is 2 seconds ahead
+	skew := 2 * time.Second
+	go func() {
+		req := make([]byte, 48)
+		n, addr, err := conn.ReadFrom(req)
+		if err != nil || n < 48 {
+			return
+		}
+		resp := make([]byte, 48)
+		resp[0], resp[1] = 0x24, 2 // version 4, server mode, stratum 2
+		copy(resp[24:32], req[40:48])
+		now := time.Now().Add(skew)
+		putNTPTime(resp[32:], now)
+		putNTPTime(resp[40:], now)
+		conn.WriteTo(resp, addr)
+	}()
+
+	offset, err := SNTPOffset(conn.LocalAddr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := offset - skew; d < -100*time.Millisecond || d > 100*time.Millisecond {
+		t.Errorf("SNTPOffset() = %v, want about %v", offset, skew)
+	}
+}
+
+func TestNTPTime(t *testing.T) {
+	want := time.Date(2019, 4, 1, 10, 0, 0, 250000000, time.UTC)
+	p := make([]byte, 8)
+	putNTPTime(p, want)
+	if got := ntpTime(p); !got.Equal(want) {
+		t.Errorf("ntpTime(putNTPTime(%v)) = %v", want, got.UTC())
+	}
+}
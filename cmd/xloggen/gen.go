@@ -0,0 +1,209 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Schema describes the events of a typed logger.
+type Schema struct {
+	Package string   `json:"package"`
+	Type    string   `json:"type"`    // the name of the logger type, Logger if empty
+	Imports []string `json:"imports"` // the packages of the field types, beyond the known ones
+	Events  []Event  `json:"events"`
+}
+
+// Event describes a log method.
+type Event struct {
+	Name    string       `json:"name"`    // the method name, e.g. UserLoggedIn
+	Level   string       `json:"level"`   // debug, info, warn or error, info if empty
+	Message string       `json:"message"` // the snake case of the name if empty
+	Doc     string       `json:"doc"`
+	Fields  []EventField `json:"fields"`
+}
+
+// EventField describes a typed field of an event.
+type EventField struct {
+	Name string `json:"name"` // the parameter name, e.g. userID
+	Key  string `json:"key"`  // the field key, the snake case of the name if empty
+	Type string `json:"type"` // a Go type, e.g. string or net.IP
+}
+
+// knownImports maps the package names of common field types to their path.
+var knownImports = map[string]string{
+	"big":  "math/big",
+	"json": "encoding/json",
+	"net":  "net",
+	"time": "time",
+	"url":  "net/url",
+	"xlog": "github.com/cnotch/xlog",
+}
+
+var levels = map[string]string{"debug": "Debug", "info": "Info", "warn": "Warn", "error": "Error"}
+
+// Generate returns the Go source of the typed logger of s.
+func Generate(s *Schema) ([]byte, error) {
+	if err := s.normalize(); err != nil {
+		return nil, err
+	}
+
+	imports := map[string]bool{"github.com/cnotch/xlog": true}
+	for _, path := range s.Imports {
+		imports[path] = true
+	}
+	for _, ev := range s.Events {
+		for _, f := range ev.Fields {
+			if i := strings.IndexByte(f.Type, '.'); i >= 0 {
+				pkg := strings.TrimLeft(f.Type[:i], "*[]")
+				if path, ok := knownImports[pkg]; ok {
+					imports[path] = true
+				}
+			}
+		}
+	}
+	// the standard packages, then the others
+	var std, others []string
+	for path := range imports {
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			others = append(others, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+
+	var buf bytes.Buffer
+	err := sourceTemplate.Execute(&buf, struct {
+		*Schema
+		Std, Others []string
+	}{s, std, others})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("xloggen: invalid type in schema: %v", err)
+	}
+	return src, nil
+}
+
+// normalize fills in the defaults of s and checks it, in particular that
+// a key has the same type in all events.
+func (s *Schema) normalize() error {
+	if !token.IsIdentifier(s.Package) {
+		return fmt.Errorf("xloggen: invalid package name %q", s.Package)
+	}
+	if s.Type == "" {
+		s.Type = "Logger"
+	}
+	if !token.IsExported(s.Type) {
+		return fmt.Errorf("xloggen: invalid type name %q", s.Type)
+	}
+
+	names := make(map[string]bool)
+	keyTypes := make(map[string]string)
+	for i := range s.Events {
+		ev := &s.Events[i]
+		if !token.IsIdentifier(ev.Name) || !token.IsExported(ev.Name) {
+			return fmt.Errorf("xloggen: invalid event name %q", ev.Name)
+		}
+		if names[ev.Name] {
+			return fmt.Errorf("xloggen: duplicate event %q", ev.Name)
+		}
+		names[ev.Name] = true
+
+		if ev.Level == "" {
+			ev.Level = "info"
+		}
+		if levels[ev.Level] == "" {
+			return fmt.Errorf("xloggen: event %s: invalid level %q", ev.Name, ev.Level)
+		}
+		ev.Level = levels[ev.Level]
+		if ev.Message == "" {
+			ev.Message = strings.Replace(snakeCase(ev.Name), "_", " ", -1)
+		}
+
+		params := make(map[string]bool)
+		for j := range ev.Fields {
+			f := &ev.Fields[j]
+			if !token.IsIdentifier(f.Name) || f.Name == "l" {
+				return fmt.Errorf("xloggen: event %s: invalid field name %q", ev.Name, f.Name)
+			}
+			if params[f.Name] {
+				return fmt.Errorf("xloggen: event %s: duplicate field %q", ev.Name, f.Name)
+			}
+			params[f.Name] = true
+			if f.Type == "" {
+				return fmt.Errorf("xloggen: event %s: field %s has no type", ev.Name, f.Name)
+			}
+			if f.Key == "" {
+				f.Key = snakeCase(f.Name)
+			}
+			if t, ok := keyTypes[f.Key]; ok && t != f.Type {
+				return fmt.Errorf("xloggen: event %s: key %q is %s, but %s in another event", ev.Name, f.Key, f.Type, t)
+			}
+			keyTypes[f.Key] = f.Type
+		}
+	}
+	return nil
+}
+
+// snakeCase converts a Go identifier to snake case, e.g. userID to user_id.
+func snakeCase(s string) string {
+	rs := []rune(s)
+	var b strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			// a new word starts at an upper case letter following a lower case
+			// one, or preceding one in an acronym, e.g. HTTPServer
+			if i > 0 && (unicode.IsLower(rs[i-1]) || i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(rs[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var sourceTemplate = template.Must(template.New("source").Parse(`// Code generated by xloggen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Std}}
+	{{printf "%q" .}}
+{{- end}}
+{{if .Std}}
+{{end -}}
+{{- range .Others}}
+	{{printf "%q" .}}
+{{- end}}
+)
+
+// {{.Type}} logs the events of its schema, with consistent keys and types.
+type {{.Type}} struct {
+	log *xlog.Logger
+}
+
+// New{{.Type}} returns a {{.Type}} logging to log.
+func New{{.Type}}(log *xlog.Logger) {{.Type}} {
+	return {{.Type}}{log.With(xlog.AddCallerSkip(1))}
+}
+{{range .Events}}
+{{if .Doc}}// {{.Name}} {{.Doc}}{{else}}// {{.Name}} logs {{printf "%q" .Message}} at {{.Level}}Level.{{end}}
+func (l {{$.Type}}) {{.Name}}({{range $i, $f := .Fields}}{{if $i}}, {{end}}{{$f.Name}} {{$f.Type}}{{end}}) {
+	l.log.{{.Level}}({{printf "%q" .Message}}{{range .Fields}}, xlog.F({{printf "%q" .Key}}, {{.Name}}){{end}})
+}
+{{end}}`))
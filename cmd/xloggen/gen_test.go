@@ -0,0 +1,72 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	s := &Schema{
+		Package: "audit",
+		Events: []Event{
+			{Name: "UserLoggedIn", Fields: []EventField{{Name: "userID", Type: "string"}, {Name: "ip", Type: "net.IP"}}},
+			{Name: "SessionExpired", Level: "warn", Message: "session expired", Fields: []EventField{
+				{Name: "uid", Key: "user_id", Type: "string"}, {Name: "idle", Type: "time.Duration"}}},
+		},
+	}
+	src, err := Generate(s)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		"package audit",
+		"\t\"net\"\n\t\"time\"\n\n\t\"github.com/cnotch/xlog\"\n",
+		"func NewLogger(log *xlog.Logger) Logger {",
+		"// UserLoggedIn logs \"user logged in\" at InfoLevel.\n",
+		"func (l Logger) UserLoggedIn(userID string, ip net.IP) {\n\tl.log.Info(\"user logged in\", xlog.F(\"user_id\", userID), xlog.F(\"ip\", ip))\n}",
+		"l.log.Warn(\"session expired\", xlog.F(\"user_id\", uid), xlog.F(\"idle\", idle))",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("Generate() = %s\nwant it to contain %q", src, want)
+		}
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Schema
+		want string
+	}{
+		{"package", Schema{Package: "a-b"}, "invalid package name"},
+		{"level", Schema{Package: "p", Events: []Event{{Name: "A", Level: "trace"}}}, "invalid level"},
+		{"duplicate", Schema{Package: "p", Events: []Event{{Name: "A"}, {Name: "A"}}}, "duplicate event"},
+		{"key type", Schema{Package: "p", Events: []Event{
+			{Name: "A", Fields: []EventField{{Name: "userID", Type: "string"}}},
+			{Name: "B", Fields: []EventField{{Name: "userID", Type: "int"}}},
+		}}, `key "user_id" is int, but string`},
+		{"type", Schema{Package: "p", Events: []Event{{Name: "A", Fields: []EventField{{Name: "x", Type: "[["}}}}}, "invalid type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Generate(&tt.s)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Generate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"userID": "user_id", "UserLoggedIn": "user_logged_in", "HTTPServer": "http_server", "ip": "ip",
+	} {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
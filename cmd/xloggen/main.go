@@ -0,0 +1,74 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Command xloggen generates a strongly-typed logger from a JSON schema of
+// events, so that all the teams of an organization log an event with the
+// same keys and types:
+//
+//	{
+//		"package": "audit",
+//		"events": [
+//			{"name": "UserLoggedIn", "fields": [
+//				{"name": "userID", "type": "string"},
+//				{"name": "ip", "type": "net.IP"}
+//			]}
+//		]
+//	}
+//
+// generates
+//
+//	func (l Logger) UserLoggedIn(userID string, ip net.IP) {
+//		l.log.Info("user logged in", xlog.F("user_id", userID), xlog.F("ip", ip))
+//	}
+//
+// Usage, typically in a go:generate directive:
+//
+//	xloggen -o audit_log.go audit.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	out := flag.String("o", "", "the output file, the standard output if empty")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: xloggen [-o file.go] schema.json\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out string) error {
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	var s Schema
+	if err = json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("xloggen: %s: %v", in, err)
+	}
+	src, err := Generate(&s)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
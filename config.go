@@ -0,0 +1,164 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigMask replaces the values of the masked keys in DumpConfig.
+const ConfigMask = "******"
+
+// the nesting depth at which DumpConfig stops, to break reference cycles.
+const _maxConfigDepth = 32
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	durationType      = reflect.TypeOf(time.Duration(0))
+)
+
+// DumpConfig logs cfg, typically the effective configuration of the
+// program at startup, at InfoLevel as the field "config".
+//
+// Structs become nested objects of their exported fields, keyed like
+// encoding/json does, as do maps with string keys. The values of the keys
+// equal to one of maskKeys, ignoring case, are replaced by ConfigMask
+// at any depth:
+//
+//	xlog.DumpConfig(log, cfg, "password", "token")
+func DumpConfig(l *Logger, cfg interface{}, maskKeys ...string) {
+	if !l.core.Enabled(InfoLevel) {
+		return
+	}
+	d := configDumper{mask: maskKeys}
+	l.log(2, InfoLevel, "effective config", nil, []Field{F("config", d.value(reflect.ValueOf(cfg), 0))})
+}
+
+type configDumper struct {
+	mask []string
+}
+
+func (d *configDumper) masked(key string) bool {
+	for _, k := range d.mask {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns v as an O, a []interface{} or a value encoded as it is.
+func (d *configDumper) value(v reflect.Value, depth int) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > _maxConfigDepth {
+		return "!xlog: exceeds max depth " + strconv.Itoa(_maxConfigDepth)
+	}
+
+	t := v.Type()
+	if t == durationType {
+		return v.Interface().(time.Duration).String()
+	}
+	if t.Implements(marshalerType) || t.Implements(textMarshalerType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil
+		}
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return d.value(v.Elem(), depth)
+	case reflect.Struct:
+		return d.appendStruct(nil, v, depth)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			return nil
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		o := make(O, 0, len(keys))
+		for _, k := range keys {
+			o = append(o, d.field(k.String(), v.MapIndex(k), depth))
+		}
+		return o
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 || v.Kind() == reflect.Slice && v.IsNil() {
+			break
+		}
+		a := make([]interface{}, v.Len())
+		for i := range a {
+			a[i] = d.value(v.Index(i), depth+1)
+		}
+		return a
+	}
+	return v.Interface()
+}
+
+// appendStruct appends the exported fields of the struct v to o,
+// the fields of embedded structs being promoted.
+func (d *configDumper) appendStruct(o O, v reflect.Value, depth int) O {
+	if o == nil {
+		o = O{}
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue // unexported
+		}
+		name := sf.Name
+		if tag := sf.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if j := strings.IndexByte(tag, ','); j >= 0 {
+				tag = tag[:j]
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && sf.Tag.Get("json") == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				o = d.appendStruct(o, fv, depth)
+				continue
+			}
+			if sf.PkgPath != "" {
+				continue
+			}
+		}
+		o = append(o, d.field(name, fv, depth))
+	}
+	return o
+}
+
+func (d *configDumper) field(key string, v reflect.Value, depth int) Field {
+	if d.masked(key) {
+		return F(key, ConfigMask)
+	}
+	return F(key, d.value(v, depth+1))
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testDBConfig struct {
+	DSN      string `json:"dsn"`
+	Password string `json:"password"`
+	Pool     int    `json:"pool,omitempty"`
+}
+
+type testBase struct {
+	Name string
+}
+
+type testConfig struct {
+	testBase
+	Addr    string
+	Timeout time.Duration
+	DB      *testDBConfig
+	Tags    []string
+	Tokens  map[string]string
+	Ignored string `json:"-"`
+	secret  string
+}
+
+func TestDumpConfig(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, InfoLevel))
+	DumpConfig(log, &testConfig{
+		testBase: testBase{Name: "api"},
+		Addr:     ":80",
+		Timeout:  1500 * time.Millisecond,
+		DB:       &testDBConfig{DSN: "db:5432", Password: "s3cr3t"},
+		Tags:     []string{"a"},
+		Tokens:   map[string]string{"b": "2", "TOKEN": "t0k3n"},
+		Ignored:  "x",
+		secret:   "y",
+	}, "password", "token")
+
+	want := `"msg":"effective config","config":{"Name":"api","Addr":":80","Timeout":"1.5s",` +
+		`"DB":{"dsn":"db:5432","password":"******","pool":0},"Tags":["a"],"Tokens":{"TOKEN":"******","b":"2"}}}`
+	if got := buf.String(); !strings.HasSuffix(got, want+"\n") {
+		t.Errorf("DumpConfig() = %s, want suffix %s", got, want)
+	}
+
+	buf.Reset()
+	DumpConfig(log, nil)
+	if got := buf.String(); !strings.HasSuffix(got, `"config":null}`+"\n") {
+		t.Errorf("DumpConfig(nil) = %s", got)
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"context"
+	"time"
+)
+
+// CtxInfo returns the field "ctx" describing the state of ctx, for the logs
+// of timeouts: the deadline and the time remaining until it, negative if
+// it has passed, whether ctx was canceled, and its error once it's done.
+//
+//	log.Error("query failed", xlog.F("error", err), xlog.CtxInfo(ctx))
+//	// "ctx":{"deadline":"2019-04-01T10:00:00Z","remaining":"-12ms","canceled":false,"err":"context deadline exceeded"}
+func CtxInfo(ctx context.Context) Field {
+	o := make(O, 0, 4)
+	if deadline, ok := ctx.Deadline(); ok {
+		o = append(o, F("deadline", deadline), F("remaining", time.Until(deadline)))
+	}
+	err := ctx.Err()
+	o = append(o, F("canceled", err == context.Canceled))
+	if err != nil {
+		o = append(o, F("err", err))
+	}
+	return Field{Key: "ctx", Val: o}
+}
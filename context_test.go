@@ -0,0 +1,42 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCtxInfo(t *testing.T) {
+	decode := func(f Field) map[string]interface{} {
+		t.Helper()
+		var m map[string]map[string]interface{}
+		p, _ := O{f}.MarshalJSON()
+		if err := json.Unmarshal(p, &m); err != nil {
+			t.Fatalf("json.Unmarshal(%s) error = %v", p, err)
+		}
+		return m["ctx"]
+	}
+
+	if got := decode(CtxInfo(context.Background())); len(got) != 1 || got["canceled"] != false {
+		t.Errorf("CtxInfo(Background) = %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := decode(CtxInfo(ctx)); got["canceled"] != true || got["err"] != "context canceled" {
+		t.Errorf("CtxInfo(canceled) = %v", got)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	got := decode(CtxInfo(ctx))
+	remaining, _ := time.ParseDuration(got["remaining"].(string))
+	if got["canceled"] != false || got["err"] != "context deadline exceeded" || remaining > -time.Second || got["deadline"] == nil {
+		t.Errorf("CtxInfo(expired) = %v", got)
+	}
+}
@@ -6,10 +6,29 @@ package xlog
 
 import (
 	"io"
+	"time"
 )
 
 // Core is a minimal, fast logger interface.
 // It's designed for library authors to wrap in a more user-friendly API.
+//
+// Concurrency contract: a Logger calls Enabled, Write and Sync of its Core
+// from any number of goroutines at once, without synchronization, so a
+// Core must be safe for concurrent use. In addition:
+//
+//   - Write must not modify e.Fields, e.Ctx or e.Callers, which are shared
+//     with the log site and other Loggers, nor retain them after it
+//     returns; a Core that writes asynchronously must copy them first.
+//   - The output of an entry must not interleave with the output of others.
+//     ioCore writes each entry with a single Write call, so it relies on
+//     its io.Writer being safe for concurrent use (see Lock).
+//   - Entries written by a goroutine must be output in the order of the
+//     calls to Write. There is no order among goroutines.
+//   - After Sync returns, every entry whose Write returned before Sync was
+//     called must have reached its destination.
+//
+// The package github.com/cnotch/xlog/coretest checks a Core against
+// this contract.
 type Core interface {
 	LevelEnabler
 	// Write serializes the Entry supplied at the log site and
@@ -35,16 +54,54 @@ type ioCore struct {
 	w            io.Writer // destination for output
 	LevelEnabler           // available log levels
 	sync         func() error
+	slowEncode   time.Duration // encodes slower than it are reported
+	syncAll      bool          // sync after every entry, not only errors
+}
+
+// A CoreOption configures a Core created by NewCore.
+type CoreOption interface {
+	apply(*ioCore)
+}
+
+// coreOptionFunc wraps a func so it satisfies the CoreOption interface.
+type coreOptionFunc func(*ioCore)
+
+func (f coreOptionFunc) apply(c *ioCore) {
+	f(c)
+}
+
+// SlowEncodeThreshold configures the Core to report entries whose encoding
+// takes longer than d, which is usually caused by the reflection of a huge
+// object graph. The entry is still written, and the lifecycle entry
+// "slow encode" names its logger and its most expensive field key (see
+// ReplaceInternalCore).
+func SlowEncodeThreshold(d time.Duration) CoreOption {
+	return coreOptionFunc(func(c *ioCore) {
+		c.slowEncode = d
+	})
+}
+
+// SyncEveryWrite configures the Core to call Sync after every entry,
+// instead of after ErrorLevel and above entries only. Writing to a
+// *FileWriter or an *os.File, it commits each entry to the disk before
+// Write returns, for trails that must not lose a single entry.
+func SyncEveryWrite() CoreOption {
+	return coreOptionFunc(func(c *ioCore) {
+		c.syncAll = true
+	})
 }
 
 // NewCore creates a Core that writes logs to a io.Writer.
-func NewCore(enc Encoder, w io.Writer, enab LevelEnabler) Core {
+func NewCore(enc Encoder, w io.Writer, enab LevelEnabler, opts ...CoreOption) Core {
 	c := &ioCore{
 		enc:          enc,
 		LevelEnabler: enab,
 		w:            w,
 	}
 	c.sync = getSyncFunc(w)
+	for _, opt := range opts {
+		opt.apply(c)
+	}
 	return c
 }
 
@@ -52,13 +109,46 @@ func (c *ioCore) Write(e Entry) (err error) {
 	b := getBuilder()
 	defer putBuilder(b)
 
+	var start time.Time
+	var elapsed time.Duration
+	if c.slowEncode > 0 {
+		start = time.Now()
+	}
 	if err = c.enc.Encode(b, e); err == nil {
+		if c.slowEncode > 0 {
+			elapsed = time.Since(start)
+		}
 		_, err = c.w.Write(b.Bytes())
 	}
 
-	if err == nil && e.Level >= ErrorLevel {
+	if err == nil && (c.syncAll || e.Level >= ErrorLevel) {
 		err = c.Sync()
 	}
+
+	if elapsed > c.slowEncode {
+		lifecycle(WarnLevel, "slow encode", String("name", e.LoggerName),
+			String("key", slowestKey(e)), Duration("elapsed", elapsed))
+	}
+	return
+}
+
+// slowestKey encodes each field of e on its own and returns the key
+// of the one that took the longest. It only runs on the slow path.
+func slowestKey(e Entry) (key string) {
+	b := getBuilder()
+	defer putBuilder(b)
+
+	var max time.Duration
+	for _, fs := range [2][]Field{e.Ctx, e.Fields} {
+		for _, f := range fs {
+			b.Reset()
+			start := time.Now()
+			f.appendTo(b)
+			if d := time.Since(start); d >= max {
+				max, key = d, f.Key
+			}
+		}
+	}
 	return
 }
 
@@ -95,16 +185,21 @@ func NewTee(cores ...Core) Core {
 			allCores = append(allCores, c)
 		}
 	}
+	return newMultiCore(allCores)
+}
 
+// newMultiCore returns a multiCore of cores, enabling the levels
+// enabled by any of them.
+func newMultiCore(cores []Core) *multiCore {
 	var levelsEnabled [_maxLevel + 2]bool
-	for _, c := range allCores {
+	for _, c := range cores {
 		for lvl := _minLevel; lvl < _maxLevel+1; lvl++ {
 			if c.Enabled(lvl) {
 				levelsEnabled[lvl+1] = true
 			}
 		}
 	}
-	return &multiCore{allCores, levelsEnabled}
+	return &multiCore{cores, levelsEnabled}
 }
 
 func (mc *multiCore) Enabled(lvl Level) bool {
@@ -133,3 +228,78 @@ func (mc *multiCore) Sync() (err error) {
 	}
 	return
 }
+
+type dualCore struct {
+	console      consoleEncoder
+	json         jsonEncoder
+	cw, jw       io.Writer
+	LevelEnabler // available log levels
+	csync, jsync func() error
+}
+
+// NewDualCore creates a Core that writes each entry to cw in console format
+// and to jw in JSON format.
+//
+// Unlike NewTee of two ioCores, the fields of an entry are serialized only once
+// and the result is shared by both outputs.
+func NewDualCore(consoleFlags int, cw io.Writer, jsonFlags int, jw io.Writer, enab LevelEnabler) Core {
+	return &dualCore{
+		console:      consoleEncoder{flags: consoleFlags},
+		json:         jsonEncoder{flags: jsonFlags},
+		cw:           cw,
+		jw:           jw,
+		LevelEnabler: enab,
+		csync:        getSyncFunc(cw),
+		jsync:        getSyncFunc(jw),
+	}
+}
+
+func (c *dualCore) Write(e Entry) (err error) {
+	fb := getBuilder()
+	defer putBuilder(fb)
+	if hasFields(e) {
+		appendFields(fb, e)
+	}
+
+	b := getBuilder()
+	defer putBuilder(b)
+
+	// console
+	c.console.appendHead(b, e)
+	if fb.Len() > 0 {
+		b.WriteString(" -  {")
+		b.Write(fb.Bytes())
+		b.WriteString("}\n")
+	}
+	appendStackText(b, e)
+	if _, werr := c.cw.Write(b.Bytes()); werr != nil {
+		err = combineErrors(err, werr)
+	}
+
+	// json
+	b.Reset()
+	c.json.appendHead(b, e)
+	if fb.Len() > 0 {
+		b.WriteByte(',')
+		b.Write(fb.Bytes())
+	}
+	b.WriteString("}\n")
+	if _, werr := c.jw.Write(b.Bytes()); werr != nil {
+		err = combineErrors(err, werr)
+	}
+
+	if err == nil && e.Level >= ErrorLevel {
+		err = c.Sync()
+	}
+	return
+}
+
+func (c *dualCore) Sync() (err error) {
+	if c.csync != nil {
+		err = combineErrors(err, c.csync())
+	}
+	if c.jsync != nil {
+		err = combineErrors(err, c.jsync())
+	}
+	return
+}
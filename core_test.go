@@ -67,3 +67,67 @@ func TestCore_Write_json(t *testing.T) {
 		}
 	}
 }
+
+func TestDualCore_Write(t *testing.T) {
+	e := Entry{
+		Level:      InfoLevel,
+		Time:       time.Date(2019, 1, 18, 12, 0, 35, 9876, time.UTC),
+		Caller:     EntryCaller{true, 0, "github.com/cnotch/xlog/core_test.go", 30},
+		Message:    "info message",
+		Fields:     []Field{F("int", 100), F("str", "ok")},
+		LoggerName: "dual",
+		Ctx:        []Field{F("instance", 9000)},
+		Stack:      Frames{{"main.run", "/src/main.go", 12}},
+	}
+
+	var wantC, wantJ bytes.Buffer
+	NewCore(NewConsoleEncoder(LstdFlags|Lshortfile), &wantC, DebugLevel).Write(e)
+	NewCore(NewJSONEncoder(Lshortfile), &wantJ, DebugLevel).Write(e)
+
+	var gotC, gotJ bytes.Buffer
+	core := NewDualCore(LstdFlags|Lshortfile, &gotC, Lshortfile, &gotJ, DebugLevel)
+	if err := core.Write(e); err != nil {
+		t.Fatalf("dualCore.Write() error = %v", err)
+	}
+	if gotC.String() != wantC.String() {
+		t.Errorf("dualCore console Out = \n%v, want = \n%v", gotC.String(), wantC.String())
+	}
+	if gotJ.String() != wantJ.String() {
+		t.Errorf("dualCore json Out = \n%v, want = \n%v", gotJ.String(), wantJ.String())
+	}
+}
+
+type slowValue struct{}
+
+func (slowValue) MarshalJSON() ([]byte, error) {
+	time.Sleep(5 * time.Millisecond)
+	return []byte(`"slow"`), nil
+}
+
+func TestCore_Write_slowEncode(t *testing.T) {
+	var buf, ibuf bytes.Buffer
+	defer ReplaceInternalCore(NewCore(NewJSONEncoder(0), &ibuf, DebugLevel))()
+	core := NewCore(NewJSONEncoder(0), &buf, DebugLevel, SlowEncodeThreshold(time.Millisecond))
+
+	core.Write(Entry{Message: "fast", LoggerName: "svc", Fields: []Field{F("int", 1)}})
+	if ibuf.Len() != 0 {
+		t.Errorf("unexpected slow encode report: %s", ibuf.String())
+	}
+
+	err := core.Write(Entry{Message: "slow", LoggerName: "svc", Fields: []Field{F("int", 1), F("graph", slowValue{})}})
+	if err != nil {
+		t.Errorf("ioCore.Write() error = %v, want nil as the entry was written", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"msg":"slow"`)) {
+		t.Errorf("output = %s, want the slow entry", buf.String())
+	}
+	if !bytes.Contains(ibuf.Bytes(), []byte(`"msg":"slow encode","name":"svc","key":"graph","elapsed":`)) {
+		t.Errorf("internal entries = %s, want slow encode with logger svc and key graph", ibuf.String())
+	}
+}
+
+func TestNew_errorOutput(t *testing.T) {
+	if log := New(nil); log.errorOutput != nil {
+		t.Errorf("default error output = %v, want internal errors discarded", log.errorOutput)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package coretest checks custom xlog Cores against the concurrency
+// contract documented on xlog.Core.
+package coretest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cnotch/xlog"
+)
+
+// RaceSuite hammers a Core from many goroutines, then verifies its output:
+// every entry is written exactly once, the message and the fields of an
+// entry are not interleaved with other entries, the entries of each
+// goroutine keep their order, and the fields passed to Write are neither
+// modified nor retained. Run it with the race detector for full effect:
+//
+//	func TestMyCore(t *testing.T) {
+//		coretest.RaceSuite{}.Run(t, func(w io.Writer) xlog.Core {
+//			return NewMyCore(w)
+//		})
+//	}
+type RaceSuite struct {
+	Goroutines int // the number of writing goroutines, 8 if zero
+	Entries    int // the number of entries per goroutine, 1000 if zero
+}
+
+// Run runs the suite against the Core returned by newCore. The Core must
+// write each entry, at InfoLevel at least, to w with any encoder printing
+// the message and the field values verbatim, such as the console or JSON
+// encoders. w is safe for concurrent use.
+func (s RaceSuite) Run(t testing.TB, newCore func(w io.Writer) xlog.Core) {
+	t.Helper()
+	if s.Goroutines <= 0 {
+		s.Goroutines = 8
+	}
+	if s.Entries <= 0 {
+		s.Entries = 1000
+	}
+
+	var out syncBuffer
+	core := newCore(&out)
+	if !core.Enabled(xlog.InfoLevel) {
+		t.Fatalf("coretest: the core doesn't enable InfoLevel")
+	}
+
+	ctx := []xlog.Field{xlog.F("suite", "race")}
+	var wg sync.WaitGroup
+	errs := make(chan error, s.Goroutines)
+	for g := 0; g < s.Goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			fields := make([]xlog.Field, 1)
+			for n := 0; n < s.Entries; n++ {
+				fields[0] = xlog.F("seq", token("f", g, n))
+				e := xlog.Entry{
+					Level:   xlog.InfoLevel,
+					Time:    time.Now(),
+					Message: token("m", g, n),
+					Ctx:     ctx,
+					Fields:  fields,
+				}
+				if err := core.Write(e); err != nil {
+					errs <- fmt.Errorf("Write() error = %v", err)
+					return
+				}
+				if fields[0].Value() != token("f", g, n) || len(ctx) != 1 || ctx[0].Value() != "race" {
+					errs <- fmt.Errorf("Write() modified the fields of the entry")
+					return
+				}
+				// a core retaining the fields writes the poison later
+				fields[0] = xlog.F("seq", "poison")
+				if n%100 == 0 {
+					core.Enabled(xlog.DebugLevel)
+					if err := core.Sync(); err != nil {
+						errs <- fmt.Errorf("Sync() error = %v", err)
+						return
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("coretest: %v", err)
+	}
+	if err := core.Sync(); err != nil {
+		t.Errorf("coretest: Sync() error = %v", err)
+	}
+
+	s.verify(t, out.Bytes())
+}
+
+func (s RaceSuite) verify(t testing.TB, out []byte) {
+	t.Helper()
+	if bytes.Contains(out, []byte("poison")) {
+		t.Errorf("coretest: the core retained the fields of an entry after Write returned")
+	}
+
+	for g := 0; g < s.Goroutines; g++ {
+		last := -1
+		for n := 0; n < s.Entries; n++ {
+			m, f := token("m", g, n), token("f", g, n)
+			i := bytes.Index(out, []byte(m))
+			if i < 0 {
+				t.Errorf("coretest: entry %s is missing", m)
+				return
+			}
+			if bytes.Count(out, []byte(m)) != 1 {
+				t.Errorf("coretest: entry %s is written more than once", m)
+				return
+			}
+			j := bytes.Index(out, []byte(f))
+			if j < i || bytes.Contains(out[i+len(m):j], []byte(_tokenPrefix)) {
+				t.Errorf("coretest: entry %s is interleaved with other entries", m)
+				return
+			}
+			if i < last {
+				t.Errorf("coretest: entry %s is written out of order", m)
+				return
+			}
+			last = i
+		}
+	}
+}
+
+const _tokenPrefix = "xlograce-"
+
+// token returns the unique text of the message or the field of entry n
+// of goroutine g.
+func token(kind string, g, n int) string {
+	return fmt.Sprintf("%s%s-%d-%d.", _tokenPrefix, kind, g, n)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package coretest
+
+import (
+	"io"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	"github.com/cnotch/xlog"
+)
+
+func TestRaceSuite(t *testing.T) {
+	cores := map[string]func(w io.Writer) xlog.Core{
+		"json": func(w io.Writer) xlog.Core {
+			return xlog.NewCore(xlog.NewJSONEncoder(0), w, xlog.InfoLevel)
+		},
+		"console": func(w io.Writer) xlog.Core {
+			return xlog.NewCore(xlog.NewConsoleEncoder(xlog.LstdFlags), w, xlog.InfoLevel)
+		},
+		"tee": func(w io.Writer) xlog.Core {
+			return xlog.NewTee(
+				xlog.NewCore(xlog.NewJSONEncoder(0), w, xlog.InfoLevel),
+				xlog.NewCore(xlog.NewJSONEncoder(0), ioutil.Discard, xlog.ErrorLevel))
+		},
+	}
+	for name, newCore := range cores {
+		t.Run(name, func(t *testing.T) {
+			RaceSuite{Goroutines: 4, Entries: 200}.Run(t, newCore)
+		})
+	}
+}
+
+// splitCore writes the message and the fields of an entry with separate
+// writes, which breaks the contract.
+type splitCore struct {
+	xlog.Core
+	w io.Writer
+}
+
+func (c splitCore) Write(e xlog.Entry) error {
+	io.WriteString(c.w, e.Message+"\n")
+	runtime.Gosched()
+	io.WriteString(c.w, e.Fields[0].Val.(string)+"\n")
+	return nil
+}
+
+func TestRaceSuite_DetectsInterleaving(t *testing.T) {
+	var rec recorder
+	RaceSuite{Goroutines: 8, Entries: 500}.Run(&rec, func(w io.Writer) xlog.Core {
+		return splitCore{xlog.NewCore(xlog.NewJSONEncoder(0), w, xlog.InfoLevel), w}
+	})
+	if !rec.failed {
+		t.Errorf("RaceSuite didn't detect interleaved entries")
+	}
+}
+
+// recorder records failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper()                                   {}
+func (r *recorder) Errorf(format string, args ...interface{}) { r.failed = true }
+func (r *recorder) Fatalf(format string, args ...interface{}) { r.failed = true }
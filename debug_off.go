@@ -0,0 +1,11 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !xlogdebug
+// +build !xlogdebug
+
+package xlog
+
+// debugOwnership is enabled with the xlogdebug build tag.
+const debugOwnership = false
@@ -0,0 +1,13 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build xlogdebug
+// +build xlogdebug
+
+package xlog
+
+// debugOwnership makes released Builders poisoned instead of recycled,
+// so cores and encoders that keep Builder.Bytes() past its lifetime
+// are caught in tests. Build with -tags xlogdebug to enable it.
+const debugOwnership = true
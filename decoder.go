@@ -0,0 +1,178 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the on-the-wire entry schema written by
+// the JSON encoder (with Lschema) and the binary encoders.
+//
+// The version is incremented when the meaning of a reserved key changes.
+// Adding keys doesn't change it: decoders keep unknown keys as fields.
+const SchemaVersion = 1
+
+var errNotObject = errors.New("xlog: entry is not a json object")
+
+// A Decoder reads entries written by the JSON encoder from an input stream.
+//
+// Entries from older and newer versions of xlog are accepted: reserved keys
+// are mapped back onto Entry, and any other key becomes a field whose value
+// is the raw json.RawMessage. Preset and log-site fields are not
+// distinguished, they are all decoded into Entry.Fields.
+type Decoder struct {
+	dec     *json.Decoder
+	version int
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// Version returns the schema version of the last decoded entry,
+// 0 if the entry didn't carry one.
+func (d *Decoder) Version() int {
+	return d.version
+}
+
+// Decode reads the next entry from its input and stores it in e.
+// It returns io.EOF at the end of the input.
+func (d *Decoder) Decode(e *Entry) (err error) {
+	*e = Entry{}
+	d.version = 0
+
+	tok, err := d.dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return errNotObject
+	}
+
+	for d.dec.More() {
+		tok, err = d.dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+
+		var raw json.RawMessage
+		if err = d.dec.Decode(&raw); err != nil {
+			return err
+		}
+		if err = d.decodeValue(e, key, raw); err != nil {
+			return fmt.Errorf("xlog: decode %q: %v", key, err)
+		}
+	}
+
+	// the closing '}'
+	_, err = d.dec.Token()
+	return err
+}
+
+func (d *Decoder) decodeValue(e *Entry, key string, raw json.RawMessage) (err error) {
+	switch key {
+	case "schema":
+		err = json.Unmarshal(raw, &d.version)
+	case "level":
+		var s string
+		if err = json.Unmarshal(raw, &s); err == nil {
+			err = e.Level.UnmarshalText([]byte(s))
+		}
+	case "time":
+		e.Time, err = decodeTime(raw)
+	case "ingest_time":
+		e.IngestTime, err = decodeTime(raw)
+	case "logger":
+		if len(raw) > 0 && raw[0] == '[' { // written with LoggerNameArray
+			var segments []string
+			if err = json.Unmarshal(raw, &segments); err == nil {
+				e.LoggerName = strings.Join(segments, ".")
+			}
+		} else {
+			err = json.Unmarshal(raw, &e.LoggerName)
+		}
+	case "msg":
+		err = json.Unmarshal(raw, &e.Message)
+	case "caller":
+		var s string
+		if err = json.Unmarshal(raw, &s); err == nil {
+			e.Caller = parseCaller(s)
+		}
+	case "stacktrace":
+		var frames []struct {
+			Func string `json:"func"`
+			File string `json:"file"`
+			Line int    `json:"line"`
+		}
+		if err = json.Unmarshal(raw, &frames); err == nil {
+			e.Stack = make(Frames, len(frames))
+			for i, f := range frames {
+				e.Stack[i] = Frame{Function: f.Func, File: f.File, Line: f.Line}
+			}
+		}
+	case "callers":
+		var ss []string
+		if err = json.Unmarshal(raw, &ss); err == nil {
+			for _, s := range ss {
+				e.Callers = append(e.Callers, parseCaller(s))
+			}
+		}
+	default:
+		e.Fields = append(e.Fields, Field{Key: key, Val: raw})
+	}
+	return
+}
+
+// decodeTime decodes a time written by the JSON encoder.
+func decodeTime(raw json.RawMessage) (time.Time, error) {
+	if len(raw) > 0 && raw[0] != '"' { // written with an epoch flag
+		var n int64
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return time.Time{}, err
+		}
+		return epochTime(n), nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// epochTime returns the time of an epoch timestamp, whose unit,
+// seconds, milliseconds or nanoseconds, is inferred from its magnitude.
+func epochTime(n int64) time.Time {
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs < 1e11: // before year 5138 in seconds
+		return time.Unix(n, 0)
+	case abs < 1e15: // in milliseconds
+		return time.Unix(0, n*int64(time.Millisecond))
+	}
+	return time.Unix(0, n)
+}
+
+// parseCaller parses "file:line" into an EntryCaller.
+func parseCaller(s string) EntryCaller {
+	c := EntryCaller{Defined: true, File: s}
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		if line, err := strconv.Atoi(s[i+1:]); err == nil {
+			c.File, c.Line = s[:i], line
+		}
+	}
+	return c
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecoder_Decode(t *testing.T) {
+	entries := []Entry{
+		{
+			Level:      WarnLevel,
+			Time:       time.Date(2019, 1, 18, 12, 0, 35, 9876, time.UTC),
+			Caller:     EntryCaller{true, 0, "github.com/cnotch/xlog/core_test.go", 30},
+			Message:    "first",
+			LoggerName: "svc.http",
+			Ctx:        []Field{F("instance", 9000)},
+			Fields:     []Field{F("obj", O{F("b", 1), F("a", "x")})},
+		},
+		{
+			Level:   InfoLevel,
+			Time:    time.Date(2019, 1, 18, 12, 0, 36, 0, time.UTC),
+			Message: "second",
+		},
+	}
+
+	var buf bytes.Buffer
+	core := NewCore(NewJSONEncoder(Llongfile|Lschema), &buf, DebugLevel)
+	for _, e := range entries {
+		core.Write(e)
+	}
+	want := buf.String()
+
+	// re-encoding the decoded entries gives the same output
+	var out bytes.Buffer
+	core = NewCore(NewJSONEncoder(Llongfile|Lschema), &out, DebugLevel)
+	dec := NewDecoder(&buf)
+	for {
+		var e Entry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decoder.Decode() error = %v", err)
+		}
+		if dec.Version() != SchemaVersion {
+			t.Errorf("Decoder.Version() = %d, want %d", dec.Version(), SchemaVersion)
+		}
+		core.Write(e)
+	}
+	if out.String() != want {
+		t.Errorf("decoded Out = \n%v, want = \n%v", out.String(), want)
+	}
+}
+
+func TestDecoder_Decode_compatibility(t *testing.T) {
+	// an entry without schema and one of a future version with unknown keys
+	in := `{"level":"INFO","time":"2019-01-18T12:00:35Z","msg":"old","n":1}
+{"schema":9,"level":"ERROR","time":"2019-01-18T12:00:35Z","msg":"new","trace":{"id":"x"}}`
+
+	dec := NewDecoder(strings.NewReader(in))
+	var e Entry
+	if err := dec.Decode(&e); err != nil || dec.Version() != 0 || e.Message != "old" || len(e.Fields) != 1 {
+		t.Errorf("Decode() = %+v, version %d, err %v", e, dec.Version(), err)
+	}
+	if err := dec.Decode(&e); err != nil || dec.Version() != 9 || e.Level != ErrorLevel || e.Fields[0].Key != "trace" {
+		t.Errorf("Decode() = %+v, version %d, err %v", e, dec.Version(), err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DiskFullConfig configures a DiskFullCore.
+type DiskFullConfig struct {
+	// Keep selects the entries still written while the disk is full,
+	// ErrorLevel and above if nil.
+	Keep LevelEnabler
+	// ProbeInterval is the delay between two attempts to write a dropped
+	// entry, to detect that space cleared, 10 seconds if zero.
+	ProbeInterval time.Duration
+	// WarnInterval is the minimum delay between two meta-warnings,
+	// a minute if zero.
+	WarnInterval time.Duration
+	// Clock provides the time of the meta-warnings, the system clock if nil.
+	Clock Clock
+}
+
+// DiskFullCore is a Core degrading gracefully when the disk of its file
+// sink is full: once a write fails with ENOSPC, the entries not selected by
+// Keep are dropped without error, so that a full disk doesn't turn every
+// log call into an error path, while the errors are still attempted.
+//
+// While degraded, a warning entry counting the dropped entries is written,
+// space permitting, at most every WarnInterval; and a dropped entry is
+// written anyway every ProbeInterval. As soon as a write succeeds, an info
+// entry reports the outage and all the entries are written again.
+type DiskFullCore struct {
+	Core
+	cfg DiskFullConfig
+
+	mu        sync.Mutex
+	full      bool
+	since     time.Time // when the disk got full
+	dropped   uint64    // since the disk got full
+	total     uint64
+	nextProbe time.Time
+	nextWarn  time.Time
+	drops     dropCounter
+}
+
+// NewDiskFullCore creates a DiskFullCore writing to core.
+func NewDiskFullCore(core Core, cfg DiskFullConfig) *DiskFullCore {
+	if cfg.Keep == nil {
+		cfg.Keep = ErrorLevel
+	}
+	if cfg.ProbeInterval <= 0 {
+		cfg.ProbeInterval = 10 * time.Second
+	}
+	if cfg.WarnInterval <= 0 {
+		cfg.WarnInterval = time.Minute
+	}
+	return &DiskFullCore{Core: core, cfg: cfg}
+}
+
+// IsDiskFull reports whether err is caused by a full disk (ENOSPC).
+func IsDiskFull(err error) bool {
+	return err != nil && errors.Is(err, syscall.ENOSPC)
+}
+
+// Full reports whether the core is degraded by a full disk.
+func (c *DiskFullCore) Full() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.full
+}
+
+// Dropped returns the number of entries dropped because of a full disk.
+func (c *DiskFullCore) Dropped() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// Sync syncs the underlying core, and returns a *DroppedError if entries
+// were dropped since the previous Sync.
+func (c *DiskFullCore) Sync() error {
+	err := c.Core.Sync()
+	return combineErrors(err, c.drops.since("diskfull", c.Dropped()))
+}
+
+func (c *DiskFullCore) now() time.Time {
+	if c.cfg.Clock != nil {
+		return c.cfg.Clock.Now()
+	}
+	return time.Now()
+}
+
+// Write writes e to the underlying core, unless the disk is full and e
+// isn't kept.
+func (c *DiskFullCore) Write(e Entry) error {
+	keep := c.cfg.Keep.Enabled(e.Level)
+	now := c.now()
+
+	c.mu.Lock()
+	if c.full && !keep {
+		if now.Before(c.nextProbe) {
+			c.drop(now)
+			c.mu.Unlock()
+			return nil
+		}
+		c.nextProbe = now.Add(c.cfg.ProbeInterval)
+	}
+	c.mu.Unlock()
+
+	err := c.Core.Write(e)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case IsDiskFull(err):
+		if !c.full {
+			c.full = true
+			c.since = now
+			c.dropped = 0
+			c.nextProbe = now.Add(c.cfg.ProbeInterval)
+			c.nextWarn = now
+		}
+		if !keep {
+			c.drop(now)
+			return nil
+		}
+		c.warn(now)
+	case err == nil && c.full:
+		c.full = false
+		c.Core.Write(Entry{
+			Level:      InfoLevel,
+			Time:       now,
+			LoggerName: "xlog",
+			Message:    "disk space available, resuming",
+			Fields:     []Field{F("dropped", c.dropped), F("outage", now.Sub(c.since))},
+		})
+	}
+	return err
+}
+
+// drop counts a dropped entry, c.mu is held.
+func (c *DiskFullCore) drop(now time.Time) {
+	c.dropped++
+	c.total++
+	c.warn(now)
+}
+
+// warn writes the meta-warning if it's due, c.mu is held.
+func (c *DiskFullCore) warn(now time.Time) {
+	if now.Before(c.nextWarn) {
+		return
+	}
+	c.nextWarn = now.Add(c.cfg.WarnInterval)
+	c.Core.Write(Entry{
+		Level:      WarnLevel,
+		Time:       now,
+		LoggerName: "xlog",
+		Message:    "disk full, dropping entries",
+		Fields:     []Field{F("dropped", c.dropped), F("since", c.since)},
+	})
+}
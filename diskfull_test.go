@@ -0,0 +1,90 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fullDiskCore records the messages it writes, unless full.
+type fullDiskCore struct {
+	full bool
+	msgs []string
+}
+
+func (c *fullDiskCore) Enabled(lvl Level) bool { return true }
+func (c *fullDiskCore) Sync() error            { return nil }
+func (c *fullDiskCore) Write(e Entry) error {
+	if c.full {
+		return &os.PathError{Op: "write", Path: "app.log", Err: syscall.ENOSPC}
+	}
+	c.msgs = append(c.msgs, e.Message)
+	return nil
+}
+
+func TestDiskFullCore(t *testing.T) {
+	clock := &manualClock{time.Unix(1000, 0)}
+	disk := &fullDiskCore{}
+	c := NewDiskFullCore(disk, DiskFullConfig{Clock: clock})
+	log := New(c)
+
+	log.Info("before")
+	disk.full = true
+	log.Info("lost") // detects the full disk
+	for i := 0; i < 3; i++ {
+		log.Debug("dropped")
+	}
+	if err := c.Write(Entry{Level: ErrorLevel, Message: "error"}); !IsDiskFull(err) {
+		t.Errorf("Write(error) = %v, want the disk full error", err)
+	}
+	if !c.Full() || c.Dropped() != 4 {
+		t.Errorf("Full(), Dropped() = %v, %d, want true, 4", c.Full(), c.Dropped())
+	}
+
+	// space clears, but the dropped entries wait for the probe
+	disk.full = false
+	log.Info("dropped")
+	if len(disk.msgs) != 1 {
+		t.Errorf("messages = %q, want the entries dropped until the probe", disk.msgs)
+	}
+	clock.t = clock.t.Add(10 * time.Second)
+	log.Info("probe")
+	if c.Full() {
+		t.Errorf("Full() = true after a successful probe")
+	}
+	log.Debug("after")
+
+	want := []string{"before", "probe", "disk space available, resuming", "after"}
+	if len(disk.msgs) != len(want) {
+		t.Fatalf("messages = %q, want %q", disk.msgs, want)
+	}
+	for i := range want {
+		if disk.msgs[i] != want[i] {
+			t.Errorf("messages = %q, want %q", disk.msgs, want)
+			break
+		}
+	}
+}
+
+func TestDiskFullCore_warn(t *testing.T) {
+	clock := &manualClock{time.Unix(1000, 0)}
+	disk := &fullDiskCore{full: true}
+	c := NewDiskFullCore(disk, DiskFullConfig{Clock: clock, WarnInterval: time.Minute, ProbeInterval: time.Hour})
+	log := New(c)
+	log.Info("a")
+
+	// the warning is written once space allows it, at most every minute
+	disk.full = false
+	log.Info("b")
+	clock.t = clock.t.Add(time.Minute)
+	log.Info("c")
+	log.Info("d")
+	if len(disk.msgs) != 1 || disk.msgs[0] != "disk full, dropping entries" {
+		t.Errorf("messages = %q, want a single warning", disk.msgs)
+	}
+}
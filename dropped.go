@@ -0,0 +1,77 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+)
+
+// DroppedError is returned by the Sync method of the cores dropping
+// entries, with the number of entries dropped since the previous Sync, so
+// that operators see the data lost at the flush points:
+//
+//   - WebhookCore: over the rate limit or the queue size;
+//   - OTLPCore: over MaxPending;
+//   - QuotaCore: over the quota of their key;
+//   - DiskFullCore: while the disk is full;
+//   - TargetCore: the untargeted entries sampled out.
+//
+// The Sync of a Tee combines it with the errors of the other cores, use
+// DroppedCount to add up the entries dropped by all of them.
+type DroppedError struct {
+	Core  string // the kind of the core, e.g. "webhook"
+	Count uint64 // the entries dropped since the previous Sync
+}
+
+func (e *DroppedError) Error() string {
+	return "xlog: " + e.Core + " core dropped " + strconv.FormatUint(e.Count, 10) +
+		" entries since the last sync"
+}
+
+// DroppedCount returns the number of entries counted by the DroppedErrors
+// of err, which may combine the errors of several cores.
+func DroppedCount(err error) uint64 {
+	var n uint64
+	for i := 0; err != nil && i < _maxErrorChain; i++ {
+		switch v := err.(type) {
+		case *DroppedError:
+			return n + v.Count
+		case *multiError:
+			for _, e := range v.errors {
+				n += DroppedCount(e)
+			}
+			return n
+		case multipleErrors:
+			for _, e := range v.Unwrap() {
+				n += DroppedCount(e)
+			}
+			return n
+		}
+		err = errors.Unwrap(err)
+	}
+	return n
+}
+
+// dropCounter computes the entries dropped since the previous Sync from
+// the total count of a core.
+type dropCounter struct {
+	synced uint64 // the total at the previous Sync
+}
+
+// since returns the DroppedError of the core for the total count, or nil
+// if it dropped nothing since the previous call.
+func (c *dropCounter) since(core string, total uint64) error {
+	for {
+		prev := atomic.LoadUint64(&c.synced)
+		if total <= prev {
+			return nil
+		}
+		if atomic.CompareAndSwapUint64(&c.synced, prev, total) {
+			return &DroppedError{Core: core, Count: total - prev}
+		}
+	}
+}
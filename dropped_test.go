@@ -0,0 +1,103 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDroppedCount(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want uint64
+	}{
+		{"nil", nil, 0},
+		{"other", errors.New("EOF"), 0},
+		{"dropped", &DroppedError{Core: "otlp", Count: 3}, 3},
+		{"wrapped", fmt.Errorf("sync: %w", &DroppedError{Core: "otlp", Count: 3}), 3},
+		{"combined", combineErrors(combineErrors(&DroppedError{Core: "otlp", Count: 3}, errors.New("EOF")),
+			&DroppedError{Core: "quota", Count: 4}), 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DroppedCount(tt.err); got != tt.want {
+				t.Errorf("DroppedCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// checkDropped checks that core.Sync reports want entries dropped.
+func checkDropped(t *testing.T, core Core, want uint64) {
+	t.Helper()
+	err := core.Sync()
+	if got := DroppedCount(err); got != want {
+		t.Errorf("Sync() = %v, want %d entries dropped", err, want)
+	}
+}
+
+func TestSync_dropped(t *testing.T) {
+	t.Run("Quota", func(t *testing.T) {
+		core := NewQuotaCore(NewCore(NewJSONEncoder(0), ioutil.Discard, DebugLevel),
+			Quota{EntriesPerSec: 2, Clock: &manualClock{time.Unix(1000, 0)}})
+		for i := 0; i < 5; i++ {
+			core.Write(Entry{Message: "noisy"})
+		}
+		checkDropped(t, core, 3)
+		checkDropped(t, core, 0)
+		core.Write(Entry{Message: "noisy"})
+		checkDropped(t, core, 1)
+	})
+
+	t.Run("DiskFull", func(t *testing.T) {
+		disk := &fullDiskCore{full: true}
+		core := NewDiskFullCore(disk, DiskFullConfig{Clock: &manualClock{time.Unix(1000, 0)}})
+		for i := 0; i < 3; i++ {
+			core.Write(Entry{Level: InfoLevel, Message: "dropped"})
+		}
+		checkDropped(t, core, 3)
+		checkDropped(t, core, 0)
+	})
+
+	t.Run("Target", func(t *testing.T) {
+		core := NewTargetCore(NewCore(NewJSONEncoder(0), ioutil.Discard, DebugLevel), "user_id", 4)
+		for i := 0; i < 8; i++ {
+			core.Write(Entry{Level: InfoLevel, Message: "other"})
+		}
+		checkDropped(t, core, 6)
+		checkDropped(t, core, 0)
+	})
+
+	t.Run("Webhook", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+		core := NewWebhookCore(WebhookConfig{URL: srv.URL, RateLimit: 1})
+		defer core.Close()
+		for i := 0; i < 3; i++ {
+			core.Write(Entry{Level: ErrorLevel, Message: "m"})
+		}
+		checkDropped(t, core, 2)
+		checkDropped(t, core, 0)
+	})
+
+	t.Run("OTLP", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+		core := NewOTLPCore(OTLPConfig{Endpoint: srv.URL, MaxPending: 2, Interval: time.Hour})
+		defer core.Close()
+		for i := 0; i < 5; i++ {
+			core.Write(Entry{Message: "m"})
+		}
+		checkDropped(t, NewTee(core), 3)
+		checkDropped(t, core, 0)
+	})
+}
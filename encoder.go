@@ -4,6 +4,14 @@
 
 package xlog
 
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
 // These flags define which text to prefix to each log entry generated by the Logger.
 // Bits are or'ed together to control what's printed.
 // There is no control over the order they appear (the order listed
@@ -21,6 +29,10 @@ const (
 	Llongfile                     // full file name and line number: /a/b/c/d.go:23
 	Lshortfile                    // final file name element and line number: d.go:23. overrides Llongfile
 	LUTC                          // if Ldate or Ltime is set, use UTC rather than the local time zone
+	Lschema                       // JSON only, the entry schema version: "schema":1
+	Lunix                         // the time as seconds since the epoch: 1548980603. overrides the other time flags
+	LunixMilli                    // the time as milliseconds since the epoch: 1548980603123
+	LunixNano                     // the time as nanoseconds since the epoch: 1548980603123123123
 	LstdFlags     = Ldate | Ltime // initial values for the standard logger
 )
 
@@ -30,28 +42,455 @@ type Encoder interface {
 	Encode(b *Builder, e Entry) error
 }
 
+// An EncoderOption configures an Encoder.
+type EncoderOption interface {
+	apply(*encoderOptions)
+}
+
+// encoderOptionFunc wraps a func so it satisfies the EncoderOption interface.
+type encoderOptionFunc func(*encoderOptions)
+
+func (f encoderOptionFunc) apply(opts *encoderOptions) {
+	f(opts)
+}
+
+// encoderOptions holds the settings shared by the encoders.
+type encoderOptions struct {
+	catalog   *Catalog
+	msgProcs  []MessageProcessor
+	sortKeys  bool
+	sortAll   bool
+	dedup     DedupMode
+	keyOrder  map[string]int // key -> position
+	nameArray bool
+	indent    string
+	keys      *EncoderKeys // nil for DefaultEncoderKeys
+	rawHTML   bool
+	durations DurationFormat
+	bytes     BytesFormat
+	mapOrder  bool
+	loc       *time.Location
+	labels    *[_maxLevel - _minLevel + 1]string
+}
+
+// EncoderKeys are the keys of the reserved values of the JSON output.
+type EncoderKeys struct {
+	Level      string
+	Time       string
+	IngestTime string
+	Logger     string
+	Caller     string
+	Callers    string
+	Message    string
+	Stacktrace string
+}
+
+// DefaultEncoderKeys are the keys used unless RenameKeys is given.
+var DefaultEncoderKeys = EncoderKeys{
+	Level:      "level",
+	Time:       "time",
+	IngestTime: "ingest_time",
+	Logger:     "logger",
+	Caller:     "caller",
+	Callers:    "callers",
+	Message:    "msg",
+	Stacktrace: "stacktrace",
+}
+
+// RenameKeys makes the JSON encoder write the reserved values under the
+// given keys, for ingestion systems mandating their own names, e.g.
+//
+//	xlog.RenameKeys(xlog.EncoderKeys{Level: "severity", Time: "@timestamp", Message: "message"})
+//
+// An empty key keeps its default.
+func RenameKeys(keys EncoderKeys) EncoderOption {
+	if keys.Level == "" {
+		keys.Level = DefaultEncoderKeys.Level
+	}
+	if keys.Time == "" {
+		keys.Time = DefaultEncoderKeys.Time
+	}
+	if keys.IngestTime == "" {
+		keys.IngestTime = DefaultEncoderKeys.IngestTime
+	}
+	if keys.Logger == "" {
+		keys.Logger = DefaultEncoderKeys.Logger
+	}
+	if keys.Caller == "" {
+		keys.Caller = DefaultEncoderKeys.Caller
+	}
+	if keys.Callers == "" {
+		keys.Callers = DefaultEncoderKeys.Callers
+	}
+	if keys.Message == "" {
+		keys.Message = DefaultEncoderKeys.Message
+	}
+	if keys.Stacktrace == "" {
+		keys.Stacktrace = DefaultEncoderKeys.Stacktrace
+	}
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.keys = &keys
+	})
+}
+
+func (opts *encoderOptions) reservedKeys() *EncoderKeys {
+	if opts.keys != nil {
+		return opts.keys
+	}
+	return &DefaultEncoderKeys
+}
+
+// WithCatalog localizes the messages of the console output with c.
+// The message of an entry is used as the code to look up in c.
+// It has no effect on the JSON encoder, which keeps the canonical code.
+func WithCatalog(c *Catalog) EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.catalog = c
+	})
+}
+
+// LoggerNameArray makes the JSON encoder write the logger name as an array
+// of its segments, "logger":["http","client"], instead of a dotted string,
+// for backends filtering on hierarchical facets.
+func LoggerNameArray() EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.nameArray = true
+	})
+}
+
+// Indent makes the JSON encoder write each entry as indented, multi-line
+// JSON, including the nested values, each level being indented with indent.
+// It's meant for development, the output being much larger.
+func Indent(indent string) EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.indent = indent
+	})
+}
+
+// NoHTMLEscape makes the JSON encoder write the characters <, > and & of
+// strings as they are, instead of as \u003c, \u003e and \u0026. The output
+// is still valid JSON, only not safe to embed in HTML.
+func NoHTMLEscape() EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.rawHTML = true
+	})
+}
+
+// UnsortedMaps makes the JSON encoder write the keys of the
+// map[string]interface{} and map[string]string values in the iteration
+// order of the maps, saving their sort.
+func UnsortedMaps() EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.mapOrder = true
+	})
+}
+
+// EncodeDurations makes the JSON encoder write time.Duration values in
+// format f, e.g. DurationMillis for backends to query duration_ms > 500
+// without parsing strings.
+func EncodeDurations(f DurationFormat) EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.durations = f
+	})
+}
+
+// EncodeBytes makes the JSON encoder write []byte values in format f.
+// See FormatBytes to choose the format of a single field.
+func EncodeBytes(f BytesFormat) EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.bytes = f
+	})
+}
+
+// InLocation makes the encoder render the times of the entries in loc,
+// e.g. a fixed zone for operations dashboards whatever the zone of the
+// host. It takes precedence over the LUTC flag.
+func InLocation(loc *time.Location) EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.loc = loc
+	})
+}
+
+// entryTime returns the time of e in the configured location.
+func (opts *encoderOptions) entryTime(e Entry, flags int) time.Time {
+	return opts.localTime(e.Time, flags)
+}
+
+// localTime returns t in the location configured by InLocation or LUTC.
+func (opts *encoderOptions) localTime(t time.Time, flags int) time.Time {
+	switch {
+	case opts.loc != nil:
+		return t.In(opts.loc)
+	case flags&LUTC != 0:
+		return t.UTC()
+	}
+	return t
+}
+
+// LevelLabels makes the encoder write the levels of labels as the given
+// strings, e.g. "WRN" or "W", to match the log contract of an existing
+// service. The other levels keep their default label. The console encoder
+// writes the custom labels without colors.
+//
+// The Decoder doesn't recognize custom labels.
+func LevelLabels(labels map[Level]string) EncoderOption {
+	var a [_maxLevel - _minLevel + 1]string
+	for lvl, label := range labels {
+		if lvl >= _minLevel && lvl <= _maxLevel {
+			a[lvl-_minLevel] = label
+		}
+	}
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.labels = &a
+	})
+}
+
+// levelLabel returns the custom label of lvl, if any.
+func (opts *encoderOptions) levelLabel(lvl Level) (string, bool) {
+	if opts.labels == nil || lvl < _minLevel || lvl > _maxLevel {
+		return "", false
+	}
+	label := opts.labels[lvl-_minLevel]
+	return label, label != ""
+}
+
+// SortKeys makes the encoder write the preset fields sorted by key, then
+// the log-site fields sorted by key, after the reserved keys. It gives
+// entries a stable key order for downstream diff and compaction tools.
+func SortKeys() EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.sortKeys = true
+	})
+}
+
+// SortAllKeys makes the encoder write all the fields sorted by key after
+// the reserved keys, the preset and the log-site fields mixed, so that the
+// output doesn't depend on the order of With calls. Fields with the same
+// key keep their order, the preset one first.
+func SortAllKeys() EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.sortKeys = true
+		opts.sortAll = true
+	})
+}
+
+// DedupMode selects the field kept of the fields sharing a key.
+type DedupMode int
+
+const (
+	// DedupOff writes all the fields, even if their keys are duplicated.
+	DedupOff DedupMode = iota
+	// DedupLastWins keeps the last field of a key: the log-site field
+	// rather than the preset one, the later With rather than the earlier.
+	DedupLastWins
+	// DedupFirstWins keeps the first field of a key.
+	DedupFirstWins
+)
+
+// DedupKeys makes the encoder write a single field per key, as many JSON
+// parsers reject duplicate keys. Entries without duplicates, the common
+// case, are checked without allocation.
+func DedupKeys(mode DedupMode) EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.dedup = mode
+	})
+}
+
+// the number of fields above which duplicates are looked up in a map.
+const _dedupLinearMax = 32
+
+// dedupFields returns e with a single field per key, as configured.
+// The fields of e are copied, not modified.
+func (opts *encoderOptions) dedupFields(e Entry) Entry {
+	n := len(e.Ctx) + len(e.Fields)
+	field := func(i int) *Field {
+		if i < len(e.Ctx) {
+			return &e.Ctx[i]
+		}
+		return &e.Fields[i-len(e.Ctx)]
+	}
+
+	// dup[i] is true if field i is dropped; nil if there is no duplicate.
+	var dup []bool
+	if n <= _dedupLinearMax {
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				if field(i).Key != field(j).Key {
+					continue
+				}
+				if dup == nil {
+					dup = make([]bool, n)
+				}
+				if opts.dedup == DedupFirstWins {
+					dup[j] = true
+				} else {
+					dup[i] = true
+				}
+			}
+		}
+	} else {
+		last := make(map[string]int, n)
+		for i := 0; i < n; i++ {
+			k := field(i).Key
+			if j, ok := last[k]; ok {
+				if dup == nil {
+					dup = make([]bool, n)
+				}
+				if opts.dedup == DedupFirstWins {
+					dup[i] = true
+					continue
+				}
+				dup[j] = true
+			}
+			last[k] = i
+		}
+	}
+	if dup == nil {
+		return e
+	}
+
+	fs := make([]Field, 0, n)
+	for i := 0; i < n; i++ {
+		if !dup[i] {
+			fs = append(fs, *field(i))
+		}
+	}
+	e.Ctx, e.Fields = nil, fs
+	return e
+}
+
+// KeyOrder makes the encoder write the fields with the given keys first,
+// in the given order, after the reserved keys. The other fields follow,
+// the preset ones first, sorted by key if SortKeys is also given.
+func KeyOrder(keys ...string) EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.keyOrder = make(map[string]int, len(keys))
+		for i, k := range keys {
+			if _, ok := opts.keyOrder[k]; !ok {
+				opts.keyOrder[k] = i
+			}
+		}
+	})
+}
+
+// orderFields returns e with its fields ordered as configured.
+// The fields of e are copied, not modified.
+func (opts *encoderOptions) orderFields(e Entry) Entry {
+	if opts.dedup == DedupOff && opts.keyOrder == nil && !opts.sortKeys {
+		return e
+	}
+	e = nestEntry(e) // namespaces are ordered as a whole
+	if opts.dedup != DedupOff {
+		e = opts.dedupFields(e)
+	}
+	if opts.keyOrder != nil {
+		fs := make([]Field, 0, len(e.Ctx)+len(e.Fields))
+		fs = append(fs, e.Ctx...)
+		fs = append(fs, e.Fields...)
+		ctxLen := len(e.Ctx)
+		rank := func(i int) (int, int) {
+			if pos, ok := opts.keyOrder[fs[i].Key]; ok {
+				return 0, pos
+			}
+			if i < ctxLen || opts.sortAll {
+				return 1, 0
+			}
+			return 2, 0
+		}
+		idx := make([]int, len(fs))
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.SliceStable(idx, func(a, b int) bool {
+			ga, pa := rank(idx[a])
+			gb, pb := rank(idx[b])
+			if ga != gb {
+				return ga < gb
+			}
+			if ga == 0 {
+				return pa < pb
+			}
+			return opts.sortKeys && fs[idx[a]].Key < fs[idx[b]].Key
+		})
+		ordered := make([]Field, len(fs))
+		for i, j := range idx {
+			ordered[i] = fs[j]
+		}
+		e.Ctx, e.Fields = nil, ordered
+		return e
+	}
+	if opts.sortAll && len(e.Ctx) > 0 {
+		fs := make([]Field, 0, len(e.Ctx)+len(e.Fields))
+		fs = append(fs, e.Ctx...)
+		fs = append(fs, e.Fields...)
+		sort.SliceStable(fs, func(i, j int) bool { return fs[i].Key < fs[j].Key })
+		e.Ctx, e.Fields = nil, fs
+	} else if opts.sortKeys {
+		e.Ctx = sortedFields(e.Ctx)
+		e.Fields = sortedFields(e.Fields)
+	}
+	return e
+}
+
 // NewConsoleEncoder returns an encoder whose output is designed for human -
 // rather than machine - consumption.
-func NewConsoleEncoder(flags int) Encoder { return consoleEncoder(flags) }
+func NewConsoleEncoder(flags int, opts ...EncoderOption) Encoder {
+	enc := consoleEncoder{flags: flags}
+	for _, opt := range opts {
+		opt.apply(&enc.encoderOptions)
+	}
+	return enc
+}
 
 // NewJSONEncoder returns a fast, low-allocation JSON encoder.
 // The encoder appropriately escapes all field keys and values.
-func NewJSONEncoder(flags int) Encoder { return jsonEncoder(flags) }
+func NewJSONEncoder(flags int, opts ...EncoderOption) Encoder {
+	enc := jsonEncoder{flags: flags}
+	for _, opt := range opts {
+		opt.apply(&enc.encoderOptions)
+	}
+	return enc
+}
 
-type consoleEncoder int
+type consoleEncoder struct {
+	flags int
+	encoderOptions
+}
 
 func (enc consoleEncoder) Encode(b *Builder, e Entry) error {
-	flags := int(enc)
+	e = enc.orderFields(e)
+	enc.appendHead(b, e)
+	if hasFields(e) {
+		b.WriteString(" -  {")
+		appendFields(b, e)
+		b.WriteString("}\n")
+	}
+	appendStackText(b, e)
+	return nil
+}
+
+// appendStackText appends the stack of e as text lines, if any,
+// after the entry written by the console encoder.
+func appendStackText(b *Builder, e Entry) {
+	if len(e.Stack) > 0 {
+		e.Stack.appendText(b)
+		b.WriteByte('\n')
+	}
+}
+
+// appendHead appends everything before the fields: level, time, name, caller and message.
+func (enc consoleEncoder) appendHead(b *Builder, e Entry) {
+	flags := enc.flags
 	// Level
-	b.WriteString(e.Level.consoleString())
+	if label, ok := enc.levelLabel(e.Level); ok {
+		b.WriteString(label)
+	} else {
+		b.WriteString(e.Level.consoleString())
+	}
 	// Time
 	if tflag := timeFlags(flags); tflag != 0 {
-		t := e.Time
-		if flags&LUTC != 0 {
-			t = t.UTC()
-		}
 		b.WriteByte(' ')
-		b.AppendTime(t, tflag)
+		b.AppendTime(enc.entryTime(e, flags), tflag)
 		b.WriteByte(' ')
 	} else {
 		b.WriteByte(' ')
@@ -79,70 +518,224 @@ func (enc consoleEncoder) Encode(b *Builder, e Entry) error {
 	if i > 0 {
 		b.WriteString(": ")
 	}
-	b.WriteString(e.Message)
+	enc.appendMessage(b, e)
 	b.WriteByte('\n')
 
-	// Fields
-	if len(e.Ctx) > 0 || len(e.Fields) > 0 {
-		i = 0
-		b.WriteString(" -  ")
-		b.WriteByte('{')
-		if len(e.Ctx) > 0 {
-			O(e.Ctx).appendTo(b)
-			i += len(e.Ctx)
-		}
-		if len(e.Fields) > 0 {
-			if i > 0 {
-				b.WriteByte(',')
-			}
-			O(e.Fields).appendTo(b)
+	// Callers
+	if flags&(Llongfile|Lshortfile) != 0 && len(e.Callers) > 1 {
+		for _, c := range e.Callers[1:] {
+			b.WriteString(" -  at ")
+			b.WriteString(callerFile(c.File, flags))
+			b.WriteByte(':')
+			b.AppendInt(int64(c.Line))
+			b.WriteByte('\n')
 		}
-		b.WriteString("}\n")
 	}
-	return nil
 }
 
-type jsonEncoder int
+// appendMessage appends the message of e, localized and post-processed.
+func (enc consoleEncoder) appendMessage(b *Builder, e Entry) {
+	if len(enc.msgProcs) == 0 {
+		if enc.catalog != nil {
+			enc.catalog.appendMessage(b, e)
+		} else {
+			b.WriteString(e.Message)
+		}
+		return
+	}
+
+	msg := e.Message
+	if enc.catalog != nil {
+		mb := getBuilder()
+		enc.catalog.appendMessage(mb, e)
+		msg = string(mb.Bytes()) // the processors may retain it
+		putBuilder(mb)
+	}
+	for _, proc := range enc.msgProcs {
+		msg = proc(e, msg)
+	}
+	b.WriteString(msg)
+}
+
+type jsonEncoder struct {
+	flags int
+	encoderOptions
+}
 
 func (enc jsonEncoder) Encode(b *Builder, e Entry) error {
-	flags := int(enc)
-	b.WriteByte('{')
+	e = enc.orderFields(e)
+	if enc.rawHTML || enc.durations != DurationString || enc.bytes != BytesBase64 || enc.mapOrder {
+		enc.configure(b)
+		defer b.resetFormats()
+	}
+	if enc.indent != "" {
+		return enc.encodeIndent(b, e)
+	}
+	enc.appendHead(b, e)
+	if hasFields(e) {
+		b.WriteByte(',')
+		appendFields(b, e)
+	}
+	b.WriteString("}\n")
+	return nil
+}
 
-	b.WriteString(`"level":"`)
-	b.WriteString(e.Level.CapitalString())
-	b.WriteByte('"')
+// encodeIndent encodes e compactly, then appends it indented to b.
+func (enc jsonEncoder) encodeIndent(b *Builder, e Entry) error {
+	cb := getBuilder()
+	defer putBuilder(cb)
+	enc.configure(cb)
+	enc.appendHead(cb, e)
+	if hasFields(e) {
+		cb.WriteByte(',')
+		appendFields(cb, e)
+	}
+	cb.WriteByte('}')
 
-	b.WriteString(`,"time":`)
+	dst := bytes.NewBuffer(b.buf)
+	if err := json.Indent(dst, cb.Bytes(), "", enc.indent); err != nil {
+		b.Write(cb.Bytes()) // keep the entry, compact
+	} else {
+		b.buf = dst.Bytes()
+	}
+	b.WriteByte('\n')
+	return nil
+}
+
+// configure sets the encoding of the values appended to b.
+func (enc jsonEncoder) configure(b *Builder) {
+	b.SetEscapeHTML(!enc.rawHTML)
+	b.SetDurationFormat(enc.durations)
+	b.SetBytesFormat(enc.bytes)
+	b.SetSortMapKeys(!enc.mapOrder)
+}
+
+// appendTime appends t as configured by the flags and the options.
+func (enc jsonEncoder) appendTime(b *Builder, t time.Time) {
+	if tflag := timeFlags(enc.flags) & (Tunix | TunixMilli | TunixNano); tflag != 0 {
+		b.AppendTime(t, tflag)
+		return
+	}
 	b.WriteByte('"')
-	b.AppendTime(e.Time, Trfc3339Nano)
+	b.AppendTime(enc.localTime(t, 0), Trfc3339Nano)
 	b.WriteByte('"')
+}
+
+// appendHead appends the opening brace and the reserved keys up to the message.
+func (enc jsonEncoder) appendHead(b *Builder, e Entry) {
+	flags := enc.flags
+	b.WriteByte('{')
+
+	if flags&Lschema != 0 {
+		b.WriteString(`"schema":`)
+		b.AppendInt(SchemaVersion)
+		b.WriteByte(',')
+	}
+
+	keys := enc.reservedKeys()
+	b.AppendQuote(keys.Level)
+	b.WriteByte(':')
+	if label, ok := enc.levelLabel(e.Level); ok {
+		b.appendJSONString(label)
+	} else {
+		b.WriteByte('"')
+		b.WriteString(e.Level.CapitalString())
+		b.WriteByte('"')
+	}
+
+	b.WriteByte(',')
+	b.AppendQuote(keys.Time)
+	b.WriteByte(':')
+	enc.appendTime(b, e.Time)
+	if !e.IngestTime.IsZero() {
+		b.WriteByte(',')
+		b.AppendQuote(keys.IngestTime)
+		b.WriteByte(':')
+		enc.appendTime(b, e.IngestTime)
+	}
 
 	if e.LoggerName != "" {
-		b.WriteString(`,"logger":`)
-		b.AppendHTMLQuote(e.LoggerName)
+		b.WriteByte(',')
+		b.AppendQuote(keys.Logger)
+		b.WriteByte(':')
+		if enc.nameArray {
+			appendNameArray(b, e.LoggerName)
+		} else {
+			b.appendJSONString(e.LoggerName)
+		}
 	}
 
 	if flags&(Llongfile|Lshortfile) != 0 && e.Caller.Defined {
-		b.WriteString(`,"caller":"`)
+		b.WriteByte(',')
+		b.AppendQuote(keys.Caller)
+		b.WriteString(`:"`)
 		b.WriteString(callerFile(e.Caller.File, flags))
 		b.WriteByte(':')
 		b.AppendInt(int64(e.Caller.Line))
 		b.WriteByte('"')
+
+		if len(e.Callers) > 1 {
+			b.WriteByte(',')
+			b.AppendQuote(keys.Callers)
+			b.WriteString(`:[`)
+			for i, c := range e.Callers {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteByte('"')
+				b.WriteString(callerFile(c.File, flags))
+				b.WriteByte(':')
+				b.AppendInt(int64(c.Line))
+				b.WriteByte('"')
+			}
+			b.WriteByte(']')
+		}
 	}
 
-	b.WriteString(`,"msg":`)
-	b.AppendHTMLQuote(e.Message)
+	b.WriteByte(',')
+	b.AppendQuote(keys.Message)
+	b.WriteByte(':')
+	b.appendJSONString(e.Message)
 
-	if len(e.Ctx) > 0 {
+	if len(e.Stack) > 0 {
 		b.WriteByte(',')
-		O(e.Ctx).appendTo(b)
+		b.AppendQuote(keys.Stacktrace)
+		b.WriteByte(':')
+		e.Stack.appendTo(b)
 	}
-	if len(e.Fields) > 0 {
+}
+
+// appendNameArray appends the segments of the logger name as a json array.
+func appendNameArray(b *Builder, name string) {
+	b.WriteByte('[')
+	for {
+		i := strings.IndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		b.appendJSONString(name[:i])
 		b.WriteByte(',')
+		name = name[i+1:]
+	}
+	b.appendJSONString(name)
+	b.WriteByte(']')
+}
+
+// hasFields reports whether e has fields to write, Skip fields aside.
+func hasFields(e Entry) bool {
+	return O(e.Ctx).hasFields() || O(e.Fields).hasFields()
+}
+
+// appendFields appends the preset fields and the log-site fields
+// as a comma separated list of json key/value pairs.
+func appendFields(b *Builder, e Entry) {
+	wrote := O(e.Ctx).appendTo(b)
+	if O(e.Fields).hasFields() {
+		if wrote {
+			b.WriteByte(',')
+		}
 		O(e.Fields).appendTo(b)
 	}
-	b.WriteString("}\n")
-	return nil
 }
 
 func timeFlags(flags int) int {
@@ -156,6 +749,14 @@ func timeFlags(flags int) int {
 	if flags&Lmicroseconds != 0 {
 		tflag |= Tmicroseconds
 	}
+	switch {
+	case flags&LunixNano != 0:
+		tflag = TunixNano
+	case flags&LunixMilli != 0:
+		tflag = TunixMilli
+	case flags&Lunix != 0:
+		tflag = Tunix
+	}
 	return tflag
 }
 
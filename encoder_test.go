@@ -0,0 +1,349 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONEncoder_KeyOrder(t *testing.T) {
+	e := Entry{
+		Level:   InfoLevel,
+		Time:    time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC),
+		Message: "m",
+		Ctx:     []Field{F("svc", 1), F("host", 2)},
+		Fields:  []Field{F("z", 3), F("id", 4), F("a", 5)},
+	}
+	head := `{"level":"INFO","time":"2019-04-01T00:00:00Z","msg":"m",`
+	tests := []struct {
+		name string
+		opts []EncoderOption
+		want string
+	}{
+		{"default", nil, `"svc":1,"host":2,"z":3,"id":4,"a":5}`},
+		{"sorted", []EncoderOption{SortKeys()}, `"host":2,"svc":1,"a":5,"id":4,"z":3}`},
+		{"order", []EncoderOption{KeyOrder("id", "host")}, `"id":4,"host":2,"svc":1,"z":3,"a":5}`},
+		{"order sorted", []EncoderOption{KeyOrder("id"), SortKeys()}, `"id":4,"host":2,"svc":1,"a":5,"z":3}`},
+	}
+	for _, tt := range tests {
+		var b Builder
+		NewJSONEncoder(0, tt.opts...).Encode(&b, e)
+		if want := head + tt.want + "\n"; b.String() != want {
+			t.Errorf("%s: Encode() = %s, want %s", tt.name, b.String(), want)
+		}
+	}
+	if e.Fields[0].Key != "z" || e.Ctx[0].Key != "svc" {
+		t.Errorf("Encode() modified the fields of the entry")
+	}
+}
+
+func TestJSONEncoder_LoggerNameArray(t *testing.T) {
+	e := Entry{Level: InfoLevel, LoggerName: "http.client", Message: "m"}
+	var b Builder
+	NewJSONEncoder(0, LoggerNameArray()).Encode(&b, e)
+	if !strings.Contains(b.String(), `"logger":["http","client"],"msg"`) {
+		t.Errorf("Encode() = %s", b.String())
+	}
+
+	var d Entry
+	if err := NewDecoder(strings.NewReader(b.String())).Decode(&d); err != nil || d.LoggerName != "http.client" {
+		t.Errorf("Decode() = %q, %v, want http.client", d.LoggerName, err)
+	}
+}
+
+func TestJSONEncoder_Indent(t *testing.T) {
+	e := Entry{
+		Level:   InfoLevel,
+		Time:    time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC),
+		Message: "m",
+		Fields:  []Field{F("req", struct{ Path []string }{[]string{"a"}})},
+	}
+	b := getBuilder()
+	defer putBuilder(b)
+	b.WriteString("prev\n")
+	NewJSONEncoder(0, Indent("  ")).Encode(b, e)
+
+	want := `prev
+{
+  "level": "INFO",
+  "time": "2019-04-01T00:00:00Z",
+  "msg": "m",
+  "req": {
+    "Path": [
+      "a"
+    ]
+  }
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("Encode() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONEncoder_RenameKeys(t *testing.T) {
+	e := Entry{
+		Level:      WarnLevel,
+		Time:       time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC),
+		LoggerName: "svc",
+		Message:    "m",
+		Caller:     NewEntryCaller(0, "/a/b.go", 3, true),
+		Callers:    []EntryCaller{NewEntryCaller(0, "/a/b.go", 3, true), NewEntryCaller(0, "/a/c.go", 4, true)},
+	}
+	b := getBuilder()
+	defer putBuilder(b)
+	enc := NewJSONEncoder(Lshortfile, RenameKeys(EncoderKeys{Level: "severity", Time: "@timestamp", Message: "message"}))
+	enc.Encode(b, e)
+
+	want := `{"severity":"WARN","@timestamp":"2019-04-01T00:00:00Z","logger":"svc",` +
+		`"caller":"b.go:3","callers":["b.go:3","c.go:4"],"message":"m"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("Encode() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONEncoder_NoHTMLEscape(t *testing.T) {
+	e := Entry{
+		Level:   InfoLevel,
+		Time:    time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC),
+		Message: "<b>",
+		Fields:  []Field{F("q", "a&b"), F("o", O{F("s", []string{">"})}), F("r", struct{ S string }{"<"})},
+	}
+	b := getBuilder()
+	defer putBuilder(b)
+	NewJSONEncoder(0, NoHTMLEscape()).Encode(b, e)
+	want := `{"level":"INFO","time":"2019-04-01T00:00:00Z","msg":"<b>","q":"a&b","o":{"s":[">"]},"r":{"S":"<"}}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("Encode() = %s, want %s", got, want)
+	}
+
+	// the builder escapes again afterwards
+	b.Reset()
+	NewJSONEncoder(0).Encode(b, e)
+	if got := b.String(); strings.ContainsAny(got, "<>&") {
+		t.Errorf("Encode() = %s, want escaped HTML", got)
+	}
+}
+
+func TestJSONEncoder_UnsortedMaps(t *testing.T) {
+	e := Entry{
+		Level:   InfoLevel,
+		Time:    time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC),
+		Message: "m",
+		Fields:  []Field{F("m", map[string]string{"k": "v"})},
+	}
+	b := getBuilder()
+	defer putBuilder(b)
+	NewJSONEncoder(0, UnsortedMaps()).Encode(b, e)
+	want := `{"level":"INFO","time":"2019-04-01T00:00:00Z","msg":"m","m":{"k":"v"}}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("Encode() = %s, want %s", got, want)
+	}
+	if b.mapOrder {
+		t.Errorf("the builder keeps the unsorted maps after Encode")
+	}
+}
+
+func TestJSONEncoder_EncodeDurations(t *testing.T) {
+	d := 1200 * time.Microsecond
+	e := Entry{Fields: []Field{F("d", d), F("p", &d)}}
+	tests := []struct {
+		f    DurationFormat
+		want string
+	}{
+		{DurationString, `"d":"1.2ms","p":"1.2ms"}`},
+		{DurationSeconds, `"d":0.0012,"p":0.0012}`},
+		{DurationMillis, `"d":1.2,"p":1.2}`},
+		{DurationNanos, `"d":1200000,"p":1200000}`},
+	}
+	for _, tt := range tests {
+		b := getBuilder()
+		NewJSONEncoder(0, EncodeDurations(tt.f)).Encode(b, e)
+		if got := b.String(); !strings.HasSuffix(got, tt.want+"\n") {
+			t.Errorf("Encode(%v) = %s, want suffix %s", tt.f, got, tt.want)
+		}
+		putBuilder(b)
+	}
+}
+
+func TestJSONEncoder_EncodeBytes(t *testing.T) {
+	e := Entry{Fields: []Field{F("b", []byte("a?>")), F("bin", []byte{0xff, 0xfe}), FormatBytes("h", []byte{0xab}, BytesHex)}}
+	tests := []struct {
+		f    BytesFormat
+		want string
+	}{
+		{BytesBase64, `"b":"YT8+","bin":"//4=","h":"ab"}`},
+		{BytesBase64URL, `"b":"YT8-","bin":"__4=","h":"ab"}`},
+		{BytesHex, `"b":"613f3e","bin":"fffe","h":"ab"}`},
+		{BytesString, `"b":"a?\u003e","bin":"//4=","h":"ab"}`},
+	}
+	for _, tt := range tests {
+		b := getBuilder()
+		NewJSONEncoder(0, EncodeBytes(tt.f)).Encode(b, e)
+		if got := b.String(); !strings.HasSuffix(got, tt.want+"\n") {
+			t.Errorf("Encode(%v) = %s, want suffix %s", tt.f, got, tt.want)
+		}
+		putBuilder(b)
+	}
+}
+
+func TestJSONEncoder_epochTime(t *testing.T) {
+	ts := time.Date(2019, 2, 1, 0, 23, 23, 123456789, time.UTC)
+	tests := []struct {
+		flags int
+		want  string
+		back  time.Time
+	}{
+		{Lunix, `"time":1548980603,`, ts.Truncate(time.Second)},
+		{LunixMilli, `"time":1548980603123,`, ts.Truncate(time.Millisecond)},
+		{LunixNano | Lunix, `"time":1548980603123456789,`, ts},
+	}
+	for _, tt := range tests {
+		b := getBuilder()
+		NewJSONEncoder(tt.flags).Encode(b, Entry{Time: ts})
+		got := string(b.Bytes())
+		putBuilder(b)
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("Encode(%#x) = %s, want %s", tt.flags, got, tt.want)
+		}
+
+		var e Entry
+		if err := NewDecoder(strings.NewReader(got)).Decode(&e); err != nil || !e.Time.Equal(tt.back) {
+			t.Errorf("Decode(%s) time = %v, %v, want %v", got, e.Time, err, tt.back)
+		}
+	}
+}
+
+func TestEncoder_InLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	e := Entry{Time: time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC), Message: "m"}
+	b := getBuilder()
+	defer putBuilder(b)
+
+	NewJSONEncoder(0, InLocation(loc)).Encode(b, e)
+	if got := b.String(); !strings.Contains(got, `"time":"2019-04-01T08:00:00+08:00"`) {
+		t.Errorf("JSON Encode() = %s", got)
+	}
+	b.Reset()
+	NewConsoleEncoder(LstdFlags|LUTC, InLocation(loc)).Encode(b, e)
+	if got := b.String(); !strings.Contains(got, " 2019-04-01 08:00:00 m") {
+		t.Errorf("console Encode() = %q", got)
+	}
+
+	enc := NewJSONEncoder(0, InLocation(loc))
+	allocs := testing.AllocsPerRun(100, func() {
+		b.Reset()
+		enc.Encode(b, e)
+	})
+	if allocs > 0 {
+		t.Errorf("Encode() allocs = %v, want 0", allocs)
+	}
+}
+
+func TestEncoder_LevelLabels(t *testing.T) {
+	opt := LevelLabels(map[Level]string{WarnLevel: "WRN", DebugLevel: "dbg", Level(42): "x"})
+	b := getBuilder()
+	defer putBuilder(b)
+
+	NewJSONEncoder(0, opt).Encode(b, Entry{Level: WarnLevel})
+	NewJSONEncoder(0, opt).Encode(b, Entry{Level: ErrorLevel})
+	NewConsoleEncoder(0, opt).Encode(b, Entry{Level: DebugLevel, Message: "m"})
+	got := b.String()
+	for _, want := range []string{`{"level":"WRN",`, `{"level":"ERROR",`, "dbg m\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Encode() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestEncoder_SortAllKeys(t *testing.T) {
+	e := Entry{
+		Ctx:    []Field{F("svc", 1), F("b", 2)},
+		Fields: []Field{F("z", 3), F("b", 4), F("a", 5)},
+	}
+	b := getBuilder()
+	defer putBuilder(b)
+	NewJSONEncoder(0, SortAllKeys()).Encode(b, e)
+	if got, want := b.String(), `"msg":"","a":5,"b":2,"b":4,"svc":1,"z":3}`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("Encode() = %s, want suffix %s", got, want)
+	}
+
+	b.Reset()
+	NewJSONEncoder(0, SortAllKeys(), KeyOrder("z")).Encode(b, e)
+	if got, want := b.String(), `"msg":"","z":3,"a":5,"b":2,"b":4,"svc":1}`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("Encode() with KeyOrder = %s, want suffix %s", got, want)
+	}
+	if e.Ctx[0].Key != "svc" || e.Fields[0].Key != "z" {
+		t.Errorf("Encode() modified the entry: %v %v", e.Ctx, e.Fields)
+	}
+}
+
+func TestEncoder_DedupKeys(t *testing.T) {
+	many := make([]Field, 0, 40)
+	for i := 0; i < 38; i++ {
+		many = append(many, F("k"+strconv.Itoa(i), i))
+	}
+	tests := []struct {
+		name string
+		mode DedupMode
+		e    Entry
+		want string
+	}{
+		{"off", DedupOff, Entry{Ctx: []Field{F("a", 1)}, Fields: []Field{F("a", 2)}}, `"a":1,"a":2}`},
+		{"last", DedupLastWins, Entry{Ctx: []Field{F("a", 1), F("b", 2)}, Fields: []Field{F("a", 3), F("a", 4)}}, `"b":2,"a":4}`},
+		{"first", DedupFirstWins, Entry{Ctx: []Field{F("a", 1), F("b", 2)}, Fields: []Field{F("a", 3), F("a", 4)}}, `"a":1,"b":2}`},
+		{"map last", DedupLastWins, Entry{Ctx: []Field{F("Z", -1)}, Fields: append(many[:38:38], F("Z", -2))}, `"k37":37,"Z":-2}`},
+		{"map first", DedupFirstWins, Entry{Ctx: []Field{F("Z", -1)}, Fields: append(many[:38:38], F("Z", -2))}, `"k37":37}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := getBuilder()
+			defer putBuilder(b)
+			NewJSONEncoder(0, DedupKeys(tt.mode)).Encode(b, tt.e)
+			got := b.String()
+			if !strings.HasSuffix(got, tt.want+"\n") || strings.Count(got, `"Z":`) > 1 {
+				t.Errorf("Encode() = %s, want suffix %s", got, tt.want)
+			}
+		})
+	}
+
+	e := Entry{Ctx: []Field{F("a", 1)}, Fields: []Field{F("b", 2)}}
+	enc := NewJSONEncoder(0, DedupKeys(DedupLastWins))
+	b := getBuilder()
+	defer putBuilder(b)
+	if allocs := testing.AllocsPerRun(100, func() { b.Reset(); enc.Encode(b, e) }); allocs > 0 {
+		t.Errorf("Encode() without duplicates allocs = %v, want 0", allocs)
+	}
+}
+
+func TestEncoder_Skip(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx, fs   []Field
+		json, con string
+	}{
+		{"skip only", []Field{Skip()}, []Field{Skip(), Err(nil)}, `"msg":"m"}`, "m\n"},
+		{"ctx only", []Field{F("a", 1)}, []Field{Skip()}, `"msg":"m","a":1}`, "m\n -  {\"a\":1}\n"},
+		{"fields only", []Field{Skip()}, []Field{Skip(), F("b", 2)}, `"msg":"m","b":2}`, "m\n -  {\"b\":2}\n"},
+		{"both", []Field{F("a", 1), Skip()}, []Field{Skip(), F("b", 2)}, `"msg":"m","a":1,"b":2}`, "m\n -  {\"a\":1,\"b\":2}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Entry{Level: InfoLevel, Message: "m", Ctx: tt.ctx, Fields: tt.fs}
+			var b Builder
+			NewJSONEncoder(0).Encode(&b, e)
+			if got := strings.TrimSpace(b.String()); !strings.HasSuffix(got, tt.json) {
+				t.Errorf("JSON = %s, want suffix %s", got, tt.json)
+			}
+			b.Reset()
+			NewConsoleEncoder(0).Encode(&b, e)
+			if got := b.String(); !strings.HasSuffix(got, tt.con) {
+				t.Errorf("console = %q, want suffix %q", got, tt.con)
+			}
+		})
+	}
+}
@@ -23,6 +23,15 @@ type Entry struct {
 	Fields     []Field
 	LoggerName string
 	Ctx        []Field
+	// Callers holds the caller frames outward from Caller, when the
+	// logger is configured with AddCallers. Callers[0] equals Caller.
+	Callers []EntryCaller
+	// IngestTime is the time a backfilled entry was logged by LogAt,
+	// Time being the time of the event. It's zero for the other entries.
+	IngestTime time.Time
+	// Stack holds the stack outward from Caller, when the level of the
+	// entry is enabled by the AddStacktrace option of the logger.
+	Stack Frames
 }
 
 // EntryCaller represents the caller of a logging function.
@@ -63,18 +72,89 @@ func NewEntryCaller(pc uintptr, file string, line int, ok bool) EntryCaller {
 	}
 }
 
+// newEntryCallers returns at most n callers, skip is the same as
+// the argument of runtime.Caller. Frames of functions in the packages
+// matching skipPkgs are left out.
+func newEntryCallers(skip, n int, skipPkgs []string) []EntryCaller {
+	max := n
+	if len(skipPkgs) > 0 {
+		max += 32 // room for the frames of wrappers
+	}
+
+	var buf [32]uintptr
+	pcs := buf[:]
+	if max < len(pcs) {
+		pcs = pcs[:max]
+	} else {
+		pcs = make([]uintptr, max)
+	}
+	// +1 for runtime.Callers, +1 for newEntryCallers
+	pcs = pcs[:runtime.Callers(skip+2, pcs)]
+
+	callers := make([]EntryCaller, 0, n)
+	frames := runtime.CallersFrames(pcs)
+	for len(callers) < n {
+		frame, more := frames.Next()
+		if frame.PC != 0 && !hasPackagePrefix(frame.Function, skipPkgs) {
+			callers = append(callers, NewEntryCaller(frame.PC, frame.File, frame.Line, true))
+		}
+		if !more {
+			break
+		}
+	}
+
+	if len(callers) == 0 {
+		callers = append(callers, NewEntryCaller(0, "", 0, false))
+	}
+	return callers
+}
+
+// hasPackagePrefix reports whether the package of the function
+// named fn starts with one of prefixes.
+func hasPackagePrefix(fn string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return false
+	}
+
+	// github.com/cnotch/xlog.(*Logger).Info => github.com/cnotch/xlog
+	pkg := fn
+	i := strings.LastIndexByte(fn, '/')
+	if i < 0 {
+		i = 0
+	}
+	if j := strings.IndexByte(fn[i:], '.'); j >= 0 {
+		pkg = fn[:i+j]
+	}
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(pkg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // O represents an object consisting of fields.
 type O []Field
 
 // Field represents a custom fielda of log entry.
+//
+// Val holds the value of every field. The fields created by the typed
+// constructors of scalars, such as String or Int, also hold it unboxed,
+// which the encoders write without a type switch, so to change the value
+// of a field, build a new one rather than assigning Val.
 type Field struct {
 	Key string
 	Val interface{}
+
+	typ fieldType // the type of the unboxed value, fieldAny if only Val holds the value
+	num uint64    // the unboxed integer, bool, duration or float bits
+	str string    // the unboxed string
 }
 
 // F .
 func F(key string, val interface{}) Field {
-	return Field{key, val}
+	return Field{Key: key, Val: val}
 }
 
 // String .
@@ -84,11 +164,15 @@ func (f Field) String() string {
 	return b.String()
 }
 
-// MarshalJSON implements the Marshaler interface.
+// MarshalJSON implements the Marshaler interface, it returns the member
+// "key":value of f, to be written in an object. Use O{f} for an object.
+// The returned slice is owned by the caller.
 func (f Field) MarshalJSON() ([]byte, error) {
-	var b Builder
-	f.appendTo(&b)
-	return b.Bytes(), nil
+	b := getBuilder()
+	f.appendTo(b)
+	p := b.CopyBytes()
+	putBuilder(b)
+	return p, nil
 }
 
 func (f Field) appendTo(b *Builder) {
@@ -96,6 +180,28 @@ func (f Field) appendTo(b *Builder) {
 	b.AppendQuote(f.Key)
 	// KV join
 	b.WriteByte(':')
+	f.appendValue(b)
+
+	if err, ok := causesOf(f); ok {
+		b.WriteByte(',')
+		b.AppendQuote(f.Key + ErrorCausesSuffix)
+		b.WriteByte(':')
+		b.appendErrorChain(err)
+	}
+	if f.typ == fieldError {
+		if d, ok := errorDetail(f.Val.(error)); ok {
+			b.WriteByte(',')
+			d.appendTo(b)
+		}
+	}
+}
+
+// appendValue appends the value of f.
+func (f Field) appendValue(b *Builder) {
+	if f.typ != fieldAny {
+		f.appendScalar(b)
+		return
+	}
 	switch v := f.Val.(type) {
 	case Field:
 		b.WriteByte('{')
@@ -105,6 +211,12 @@ func (f Field) appendTo(b *Builder) {
 		b.WriteByte('{')
 		v.appendTo(b)
 		b.WriteByte('}')
+	case fieldGroup: // not expanded by a Logger
+		b.WriteByte('{')
+		O(v).appendTo(b)
+		b.WriteByte('}')
+	case restrictedValue:
+		v.f.appendValue(b)
 	case []O:
 		b.WriteByte('[')
 		for i, fs := range v {
@@ -123,19 +235,52 @@ func (f Field) appendTo(b *Builder) {
 }
 
 // MarshalJSON implements the Marshaler interface.
+// The returned slice is owned by the caller.
 func (o O) MarshalJSON() ([]byte, error) {
-	var b Builder
-	b.WriteByte('{')
-	o.appendTo(&b)
+	b := getBuilder()
 	b.WriteByte('{')
-	return b.Bytes(), nil
+	o.appendTo(b)
+	b.WriteByte('}')
+	p := b.CopyBytes()
+	putBuilder(b)
+	return p, nil
 }
 
-func (o O) appendTo(b *Builder) {
-	for i, f := range o {
-		if i > 0 {
+// appendTo appends the fields of o, and reports whether it appended any.
+func (o O) appendTo(b *Builder) bool {
+	open := 0 // the namespaces opened
+	first := true
+	wrote := false
+	for _, f := range o {
+		if f.isSkip() {
+			continue
+		}
+		wrote = true
+		if !first {
 			b.WriteByte(',')
 		}
+		if _, ok := f.Val.(namespace); ok {
+			b.AppendQuote(f.Key)
+			b.WriteString(":{")
+			open++
+			first = true
+			continue
+		}
+		first = false
 		f.appendTo(b)
 	}
+	for ; open > 0; open-- {
+		b.WriteByte('}')
+	}
+	return wrote
+}
+
+// hasFields reports whether o has fields to append, Skip fields aside.
+func (o O) hasFields() bool {
+	for _, f := range o {
+		if !f.isSkip() {
+			return true
+		}
+	}
+	return false
 }
@@ -161,3 +161,29 @@ func TestField_String(t *testing.T) {
 		})
 	}
 }
+
+func TestField_MarshalJSON(t *testing.T) {
+	if got, _ := F("name", "chj").MarshalJSON(); string(got) != `"name":"chj"` {
+		t.Errorf("Field.MarshalJSON() = %s, want %s", got, `"name":"chj"`)
+	}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"Object", O{F("name", "chj"), F("age", 45)}, `{"name":"chj","age":45}`},
+		{"Nested", map[string]interface{}{"o": O{F("age", 45)}}, `{"o":{"age":45}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.v)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("json.Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
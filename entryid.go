@@ -0,0 +1,68 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync/atomic"
+)
+
+// The keys of the entry correlation fields.
+const (
+	EntryIDKey  = "entry_id"
+	ParentIDKey = "parent_id"
+)
+
+var (
+	_entryIDPrefix uint32 // random per process
+	_entryIDSeq    uint32
+)
+
+func init() {
+	var p [4]byte
+	rand.Read(p[:])
+	_entryIDPrefix = binary.BigEndian.Uint32(p[:])
+}
+
+// NewEntryID returns a new 16 hex digits entry ID: a random prefix per
+// process followed by a sequence number, so IDs are cheap, unique within
+// a process and very likely unique across processes.
+func NewEntryID() string {
+	seq := atomic.AddUint32(&_entryIDSeq, 1)
+	var buf [16]byte
+	const hex = "0123456789abcdef"
+	v := uint64(_entryIDPrefix)<<32 | uint64(seq)
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = hex[v&0xf]
+		v >>= 4
+	}
+	return string(buf[:])
+}
+
+// AddEntryIDs configures the Logger to give each entry a unique entry_id
+// field. Scopes then link related entries into a tree reconstructable by
+// analysis tools without full tracing: the begin entry of a Scope carries
+// the scope's ID, and the entries logged through Scope.Logger, including
+// nested scopes and the end entry, carry it as their parent_id.
+// An entry_id field given at the log site is kept.
+func AddEntryIDs() Option {
+	return optionFunc(func(log *Logger) {
+		log.entryIDs = true
+	})
+}
+
+// withEntryID returns fields with an entry_id field appended,
+// unless it has one. fields is not modified.
+func withEntryID(fields []Field) []Field {
+	for i := range fields {
+		if fields[i].Key == EntryIDKey {
+			return fields
+		}
+	}
+	fs := make([]Field, len(fields), len(fields)+1)
+	copy(fs, fields)
+	return append(fs, Field{Key: EntryIDKey, Val: NewEntryID()})
+}
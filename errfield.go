@@ -0,0 +1,227 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// The keys of the fields created by Err.
+const (
+	ErrorKey        = "error"
+	ErrorStackKey   = "errorStack"
+	ErrorVerboseKey = "errorVerbose"
+)
+
+// ErrorCausesSuffix is appended to the key of a field holding an error
+// that wraps other errors, such as the ones of fmt.Errorf with %w, to name
+// the field added beside it with the chain of causes, e.g. "error_causes":
+//
+//	"error":"save: disk full","error_causes":[{"msg":"save: disk full","type":"*fmt.wrapError"},{"msg":"disk full","type":"*errors.errorString"}]
+//
+// The field itself keeps the message of the error.
+const ErrorCausesSuffix = "_causes"
+
+// errorCauses is the value of the field added beside a field holding an
+// error that wraps others, encoded as the chain of causes of the error.
+type errorCauses struct {
+	err error
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (c errorCauses) MarshalJSON() ([]byte, error) {
+	b := getBuilder()
+	b.appendErrorChain(c.err)
+	p := b.CopyBytes()
+	putBuilder(b)
+	return p, nil
+}
+
+// causesOf returns the error held by f if it wraps other errors.
+func causesOf(f Field) (error, bool) {
+	err, ok := f.Val.(error)
+	if !ok || !wrapsErrors(err) {
+		return nil, false
+	}
+	return err, true
+}
+
+// expandErrors returns fs with the fields written beside the fields
+// holding an error added after them: the chain of causes of an error that
+// wraps others, and the detail of a field created by Err. It's for the
+// encoders which don't write the fields with Field.appendTo. fs is
+// returned as it is if it has no such field.
+func expandErrors(fs []Field) []Field {
+	n := -1
+	for i, f := range fs {
+		if _, ok := f.Val.(error); ok {
+			n = i
+			break
+		}
+	}
+	if n < 0 {
+		return fs
+	}
+
+	expanded := make([]Field, n, len(fs)+2)
+	copy(expanded, fs[:n])
+	for _, f := range fs[n:] {
+		expanded = append(expanded, f)
+		if err, ok := causesOf(f); ok {
+			expanded = append(expanded, Field{Key: f.Key + ErrorCausesSuffix, Val: errorCauses{err}})
+		}
+		if f.typ == fieldError {
+			if d, ok := errorDetail(f.Val.(error)); ok {
+				expanded = append(expanded, d)
+			}
+		}
+	}
+	return expanded
+}
+
+// the maximum number of errors of a chain walked to find a stack,
+// in case of a cycle.
+const _maxErrorChain = 100
+
+// Err returns the field "error" holding err, or Skip if err is nil. If err,
+// or an error it wraps, carries the stack where it was created, as the
+// errors of github.com/pkg/errors do, the field "errorStack" is added with
+// the stack of the innermost one, where the error originated, as Frames:
+//
+//	log.Error("save failed", xlog.Err(err))
+//	// "error":"save: disk full","error_causes":[{"msg":"save: disk full","type":"*fmt.wrapError"},...],"errorStack":[{"func":"main.save","file":"/src/main.go","line":12},...]
+//
+// Otherwise, if err implements fmt.Formatter and its %+v rendering tells
+// more than its message, as the errors combined by the Logger do, the field
+// "errorVerbose" is added with that rendering.
+//
+// The stack and the rendering are looked up by the encoders, so they cost
+// nothing for the entries of a disabled level.
+func Err(err error) Field {
+	if err == nil {
+		return Skip()
+	}
+	return Field{Key: ErrorKey, Val: err, typ: fieldError}
+}
+
+// errorDetail returns the field the encoders write beside the field of err
+// created by Err, if any.
+func errorDetail(err error) (Field, bool) {
+	if frames := errorStack(err); frames != nil {
+		return Field{Key: ErrorStackKey, Val: frames}, true
+	}
+	if verbose, ok := errorVerbose(err); ok {
+		return String(ErrorVerboseKey, verbose), true
+	}
+	return Field{}, false
+}
+
+// errorVerbose returns the %+v rendering of err, if err implements
+// fmt.Formatter and the rendering differs from its message.
+func errorVerbose(err error) (string, bool) {
+	if _, ok := err.(fmt.Formatter); !ok {
+		return "", false
+	}
+	verbose := fmt.Sprintf("%+v", err)
+	if verbose == err.Error() {
+		return "", false
+	}
+	return verbose, true
+}
+
+// errorStack returns the stack of the innermost error of the chain of err
+// carrying one, or nil.
+func errorStack(err error) Frames {
+	var pcs []uintptr
+	for i := 0; err != nil && i < _maxErrorChain; i++ {
+		if s := stackOf(err); s != nil {
+			pcs = s
+		}
+		err = nextError(err)
+	}
+	if pcs == nil {
+		return nil
+	}
+	return framesOf(pcs)
+}
+
+// nextError returns the error wrapped by err, through its Unwrap method
+// or the Cause method of github.com/pkg/errors, or nil.
+func nextError(err error) error {
+	if next := errors.Unwrap(err); next != nil {
+		return next
+	}
+	if c, ok := err.(interface{ Cause() error }); ok {
+		return c.Cause()
+	}
+	return nil
+}
+
+// wrapsErrors reports whether err wraps one or several errors.
+func wrapsErrors(err error) bool {
+	switch err.(type) {
+	case *multiError, multipleErrors:
+		return true
+	}
+	return nextError(err) != nil
+}
+
+// stackOf returns the program counters of the stack carried by err, if any:
+// its Callers() []uintptr method, as implemented by github.com/go-errors/errors,
+// or its StackTrace method returning a slice of program counters, such as
+// the errors.StackTrace of github.com/pkg/errors.
+func stackOf(err error) []uintptr {
+	if s, ok := err.(interface{ Callers() []uintptr }); ok {
+		return s.Callers()
+	}
+
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() {
+		return nil
+	}
+	t := m.Type()
+	if t.NumIn() != 0 || t.NumOut() != 1 ||
+		t.Out(0).Kind() != reflect.Slice || t.Out(0).Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+	st := m.Call(nil)[0]
+	pcs := make([]uintptr, st.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(st.Index(i).Uint())
+	}
+	return pcs
+}
+
+// fieldGroup is the value of a field standing for several fields,
+// which the Logger writes in its place.
+type fieldGroup []Field
+
+// expandGroups returns fs with each group replaced by its fields.
+// fs is returned as it is if it has no group.
+func expandGroups(fs []Field) []Field {
+	n := -1
+	for i, f := range fs {
+		if _, ok := f.Val.(fieldGroup); ok {
+			n = i
+			break
+		}
+	}
+	if n < 0 {
+		return fs
+	}
+
+	expanded := make([]Field, n, len(fs)+2)
+	copy(expanded, fs[:n])
+	for _, f := range fs[n:] {
+		if g, ok := f.Val.(fieldGroup); ok {
+			expanded = append(expanded, g...)
+		} else {
+			expanded = append(expanded, f)
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// the stack of github.com/pkg/errors
+type testFrame uintptr
+type testStackTrace []testFrame
+
+type stackError struct {
+	msg string
+	pcs []uintptr
+}
+
+func newStackError(msg string) error {
+	pcs := make([]uintptr, 32)
+	return &stackError{msg, pcs[:runtime.Callers(2, pcs)]}
+}
+
+func (e *stackError) Error() string { return e.msg }
+func (e *stackError) StackTrace() testStackTrace {
+	st := make(testStackTrace, len(e.pcs))
+	for i, pc := range e.pcs {
+		st[i] = testFrame(pc)
+	}
+	return st
+}
+
+// the stack of github.com/go-errors/errors
+type callersError struct{ stackError }
+
+func (e *callersError) Callers() []uintptr { return e.pcs }
+
+func originOfError() error {
+	return newStackError("origin")
+}
+
+func TestErr(t *testing.T) {
+	if f := Err(errors.New("plain")); f.Key != ErrorKey || f.Val.(error).Error() != "plain" {
+		t.Errorf("Err(plain) = %v", f)
+	}
+
+	for _, origin := range []error{originOfError(), &callersError{*originOfError().(*stackError)}} {
+		err := fmt.Errorf("save: %w", origin)
+		if f := Err(err); f.Val != err {
+			t.Errorf("Err(%T).Val = %v, want the error", origin, f.Val)
+		}
+		d, ok := errorDetail(err)
+		if !ok || d.Key != ErrorStackKey {
+			t.Fatalf("Err(%T) detail = %v, want its stack", origin, d)
+		}
+		if top := d.Val.(Frames)[0].Function; !strings.HasSuffix(top, ".originOfError") {
+			t.Errorf("top frame = %s, want originOfError", top)
+		}
+	}
+}
+
+func TestErr_logged(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, DebugLevel))
+	log.Error("failed", Err(fmt.Errorf("save: %w", originOfError())), F("n", 1))
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("output %s: %v", buf.Bytes(), err)
+	}
+	if chain, ok := m[ErrorKey+ErrorCausesSuffix].([]interface{}); !ok || len(chain) != 2 ||
+		m[ErrorKey] != "save: origin" || m["n"] != 1.0 {
+		t.Errorf("output = %s", buf.Bytes())
+	}
+	if st, ok := m[ErrorStackKey].([]interface{}); !ok || len(st) == 0 {
+		t.Errorf("output = %s, want the errorStack array", buf.Bytes())
+	}
+}
+
+// verboseError renders its detail with %+v.
+type verboseError struct{ msg, detail string }
+
+func (e verboseError) Error() string { return e.msg }
+func (e verboseError) Format(f fmt.State, c rune) {
+	fmt.Fprint(f, e.msg)
+	if c == 'v' && f.Flag('+') && e.detail != "" {
+		fmt.Fprint(f, "\n", e.detail)
+	}
+}
+
+func TestErr_verbose(t *testing.T) {
+	merr := combineErrors(errors.New("a"), errors.New("b"))
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{verboseError{"query", "at line 3"}, `"error":"query","errorVerbose":"query\nat line 3"`},
+		{merr, `"error":"a; b","error_causes":[{"msg":"a","type":"*errors.errorString"},{"msg":"b","type":"*errors.errorString"}],` +
+			`"errorVerbose":` + strconv.Quote(fmt.Sprintf("%+v", merr))},
+		{verboseError{msg: "terse"}, `"error":"terse"`},
+	}
+	for _, tt := range tests {
+		f := Err(tt.err)
+		if f.Val != tt.err {
+			t.Errorf("Err(%v).Val = %v, want the error", tt.err, f.Val)
+		}
+		if got := f.String(); got != tt.want {
+			t.Errorf("Err(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+
+	// the other encoders write it too
+	var b Builder
+	NewGELFEncoder("h").Encode(&b, Entry{Fields: []Field{Err(verboseError{"query", "at line 3"})}})
+	if !strings.Contains(b.String(), `"_errorVerbose":"query\nat line 3"`) {
+		t.Errorf("GELF output = %s", b.String())
+	}
+}
+
+// countingError counts the renderings of its verbose form.
+type countingError struct{ formats *int }
+
+func (e countingError) Error() string { return "counted" }
+func (e countingError) Format(f fmt.State, c rune) {
+	*e.formats++
+	fmt.Fprint(f, "counted\ndetail")
+}
+
+// countingStackError counts the lookups of its stack.
+type countingStackError struct {
+	stackError
+	lookups *int
+}
+
+func (e *countingStackError) StackTrace() testStackTrace {
+	*e.lookups++
+	return e.stackError.StackTrace()
+}
+
+func TestErr_disabled(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, InfoLevel))
+	var formats, lookups int
+	log.Debug("disabled", Err(countingError{&formats}))
+	log.Debug("disabled", Err(&countingStackError{*originOfError().(*stackError), &lookups}))
+	if formats != 0 || lookups != 0 {
+		t.Errorf("%%+v rendered %d times, stack looked up %d times for a disabled level, want 0", formats, lookups)
+	}
+	log.Info("enabled", Err(countingError{&formats}))
+	if formats != 1 || !strings.Contains(buf.String(), `"errorVerbose":"counted\ndetail"`) {
+		t.Errorf("output = %s, %d renderings, want the verbose rendering once", buf.String(), formats)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// fieldType is the type of the unboxed value of a field.
+type fieldType uint8
+
+const (
+	fieldAny fieldType = iota // the value is boxed in Val
+	fieldString
+	fieldInt
+	fieldInt64
+	fieldUint
+	fieldUint64
+	fieldFloat64
+	fieldBool
+	fieldDuration
+	fieldError // created by Err, Val holds the error
+)
+
+// String returns a field with a string value.
+func String(key string, val string) Field {
+	return Field{Key: key, Val: val, typ: fieldString, str: val}
+}
+
+// Strings returns a field with a string slice value.
+func Strings(key string, val []string) Field { return Field{Key: key, Val: val} }
+
+// Int returns a field with an int value.
+func Int(key string, val int) Field {
+	return Field{Key: key, Val: val, typ: fieldInt, num: uint64(val)}
+}
+
+// Int64 returns a field with an int64 value.
+func Int64(key string, val int64) Field {
+	return Field{Key: key, Val: val, typ: fieldInt64, num: uint64(val)}
+}
+
+// Uint returns a field with a uint value.
+func Uint(key string, val uint) Field {
+	return Field{Key: key, Val: val, typ: fieldUint, num: uint64(val)}
+}
+
+// Uint64 returns a field with a uint64 value.
+func Uint64(key string, val uint64) Field {
+	return Field{Key: key, Val: val, typ: fieldUint64, num: val}
+}
+
+// Float64 returns a field with a float64 value.
+func Float64(key string, val float64) Field {
+	return Field{Key: key, Val: val, typ: fieldFloat64, num: math.Float64bits(val)}
+}
+
+// Bool returns a field with a bool value.
+func Bool(key string, val bool) Field {
+	f := Field{Key: key, Val: val, typ: fieldBool}
+	if val {
+		f.num = 1
+	}
+	return f
+}
+
+// Time returns a field with a time value, encoded in RFC 3339 format.
+func Time(key string, val time.Time) Field { return Field{Key: key, Val: val} }
+
+// Duration returns a field with a duration value, encoded as configured
+// by the EncodeDurations option, as a string by default.
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, Val: val, typ: fieldDuration, num: uint64(val)}
+}
+
+// Binary returns a field with an opaque binary value, encoded as configured
+// by the EncodeBytes option, in base64 by default. See FormatBytes to
+// choose the encoding of a single field.
+func Binary(key string, val []byte) Field { return Field{Key: key, Val: val} }
+
+// Any returns a field with a value of any type, the same as F. Prefer
+// the typed constructors, as values of types unknown to the encoders are
+// encoded by reflection.
+func Any(key string, val interface{}) Field { return Field{Key: key, Val: val} }
+
+// Skip returns a no-op field, which the Logger and the encoders drop, so
+// helpers can include a field conditionally without building a slice:
+//
+//	func userField(u *User) xlog.Field {
+//		if u == nil {
+//			return xlog.Skip()
+//		}
+//		return xlog.String("user", u.Name)
+//	}
+func Skip() Field { return Field{Val: fieldGroup(nil)} }
+
+// isSkip reports whether f is a Skip field.
+func (f Field) isSkip() bool {
+	g, ok := f.Val.(fieldGroup)
+	return ok && len(g) == 0
+}
+
+// Value returns the value of f, calling its function if f is Lazy,
+// or the value of the field restricted if f is Restrict'ed.
+func (f Field) Value() interface{} {
+	switch v := f.Val.(type) {
+	case lazyValue:
+		return v.value()
+	case restrictedValue:
+		return v.f.Value()
+	}
+	return f.Val
+}
+
+// stringValue returns the value of f if it's a string, without boxing it.
+func (f Field) stringValue() (string, bool) {
+	if f.typ == fieldString {
+		return f.str, true
+	}
+	s, ok := f.Val.(string)
+	return s, ok
+}
+
+// appendScalar appends the unboxed value of f.
+func (f Field) appendScalar(b *Builder) {
+	switch f.typ {
+	case fieldString:
+		b.appendJSONString(f.str)
+	case fieldInt, fieldInt64:
+		b.AppendInt(int64(f.num))
+	case fieldUint, fieldUint64:
+		b.AppendUint(f.num)
+	case fieldFloat64:
+		b.AppendFloat64(math.Float64frombits(f.num))
+	case fieldBool:
+		b.AppendBool(f.num != 0)
+	case fieldDuration:
+		b.appendJSONDuration(time.Duration(f.num))
+	case fieldError:
+		b.appendError(f.Val.(error))
+	}
+}
+
+// stringerValue is the value of the fields created by Stringer.
+type stringerValue struct {
+	v fmt.Stringer
+}
+
+// Stringer returns a field whose value is the result of v.String(),
+// called only when the entry is encoded, so logging at a disabled level
+// costs nothing. A nil v, or a nil pointer, is encoded as null.
+func Stringer(key string, v fmt.Stringer) Field {
+	return Field{Key: key, Val: stringerValue{v}}
+}
+
+// String calls the String method of the value.
+func (s stringerValue) String() string {
+	if s.v == nil || isNilPointer(s.v) {
+		return "<nil>"
+	}
+	return s.v.String()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface,
+// for the encoders which don't know the type.
+func (s stringerValue) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s stringerValue) appendTo(b *Builder) {
+	if s.v == nil || isNilPointer(s.v) {
+		b.WriteString("null")
+		return
+	}
+	b.appendJSONString(s.v.String())
+}
+
+// lazyValue is the value of the fields created by Lazy.
+type lazyValue func() interface{}
+
+// Lazy returns a field whose value is the result of fn, called only when
+// the entry is encoded, so expensive diagnostics attached to entries of a
+// disabled level cost nothing. fn is called by each encoder writing the
+// entry, it must be safe for concurrent use if the entry is written
+// asynchronously. A nil fn is encoded as null.
+//
+//	log.Debug("dequeued", xlog.Lazy("depths", func() interface{} { return q.Depths() }))
+func Lazy(key string, fn func() interface{}) Field {
+	return Field{Key: key, Val: lazyValue(fn)}
+}
+
+func (lv lazyValue) value() interface{} {
+	if lv == nil {
+		return nil
+	}
+	return lv()
+}
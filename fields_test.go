@@ -0,0 +1,137 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTypedFields(t *testing.T) {
+	tm := time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		f    Field
+		want string
+	}{
+		{String("k", "v"), `"k":"v"`},
+		{Strings("k", []string{"a", "b"}), `"k":["a","b"]`},
+		{Int("k", -1), `"k":-1`},
+		{Int64("k", 1<<40), `"k":1099511627776`},
+		{Uint("k", 1), `"k":1`},
+		{Uint64("k", 1<<63), `"k":9223372036854775808`},
+		{Float64("k", 1.5), `"k":1.5`},
+		{Bool("k", true), `"k":true`},
+		{Time("k", tm), `"k":"2019-04-01T00:00:00Z"`},
+		{Duration("k", time.Second), `"k":"1s"`},
+		{Binary("k", []byte("hi")), `"k":"aGk="`},
+		{Err(errors.New("failed")), `"error":"failed"`},
+		{Any("k", []int{1}), `"k":[1]`},
+	}
+	for _, tt := range tests {
+		if got := tt.f.String(); got != tt.want {
+			t.Errorf("Field = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+type countingStringer struct{ calls int }
+
+func (s *countingStringer) String() string {
+	s.calls++
+	return "str"
+}
+
+func TestTypedFields_Value(t *testing.T) {
+	tests := []struct {
+		f    Field
+		want interface{}
+	}{
+		{String("k", "v"), "v"},
+		{Int("k", -1), -1},
+		{Int64("k", -1), int64(-1)},
+		{Uint("k", 1), uint(1)},
+		{Uint64("k", 1), uint64(1)},
+		{Float64("k", -1.5), -1.5},
+		{Bool("k", true), true},
+		{Bool("k", false), false},
+		{Duration("k", -time.Second), -time.Second},
+		{F("k", 1), 1},
+	}
+	for _, tt := range tests {
+		if got := tt.f.Value(); got != tt.want {
+			t.Errorf("Value() = %#v, want %#v", got, tt.want)
+		}
+		// read by the custom cores and encoders
+		if tt.f.Val != tt.want {
+			t.Errorf("Val = %#v, want %#v", tt.f.Val, tt.want)
+		}
+	}
+
+	// the other encoders see the values
+	var b Builder
+	NewGELFEncoder("h").Encode(&b, Entry{Fields: []Field{String("s", "v"), Int("n", 7)}})
+	if !bytes.Contains(b.Bytes(), []byte(`"_s":"v","_n":7}`)) {
+		t.Errorf("GELF output = %s", b.Bytes())
+	}
+}
+
+func TestStringer(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, InfoLevel))
+	s := &countingStringer{}
+
+	log.Debug("disabled", Stringer("s", s))
+	if s.calls != 0 {
+		t.Errorf("String() called %d times for a disabled level, want 0", s.calls)
+	}
+
+	log.Info("enabled", Stringer("s", s), Stringer("nil", (*countingStringer)(nil)))
+	want := `"s":"str","nil":null}`
+	if got := buf.String(); !bytes.HasSuffix(buf.Bytes(), []byte(want+"\n")) || s.calls != 1 {
+		t.Errorf("output = %s, String() calls = %d, want %s and 1 call", got, s.calls, want)
+	}
+
+	var b Builder
+	NewConsoleEncoder(0).Encode(&b, Entry{Message: "m", Fields: []Field{Stringer("s", s)}})
+	if got := b.String(); !bytes.Contains(b.Bytes(), []byte(`{"s":"str"}`)) {
+		t.Errorf("console output = %s", got)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, DebugLevel), Fields(Skip(), F("a", 1)))
+	log.Info("skip", Skip(), Err(nil), F("o", O{Skip(), F("b", 2), Skip()}), Skip())
+	if want := `"msg":"skip","a":1,"o":{"b":2}}`; !strings.HasSuffix(strings.TrimSpace(buf.String()), want) {
+		t.Errorf("Out = %s, want suffix %s", buf.String(), want)
+	}
+}
+
+func TestLazy(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, InfoLevel))
+	calls := 0
+	depths := Lazy("depths", func() interface{} {
+		calls++
+		return []int{1, 2}
+	})
+
+	log.Debug("disabled", depths)
+	if calls != 0 {
+		t.Errorf("fn called %d times for a disabled level, want 0", calls)
+	}
+
+	log.Info("enabled", depths, Lazy("nil", nil))
+	want := `"depths":[1,2],"nil":null}`
+	if got := buf.String(); !strings.HasSuffix(got, want+"\n") || calls != 1 {
+		t.Errorf("output = %s, fn calls = %d, want %s and 1 call", got, calls, want)
+	}
+	if v, ok := depths.Value().([]int); !ok || len(v) != 2 {
+		t.Errorf("Value() = %v, want the result of fn", depths.Value())
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// _fileCheckInterval is the default delay between two checks
+// that a FileWriter still writes to the file named by its name.
+const _fileCheckInterval = time.Second
+
+// A FileWriter is an append-only log file, safe for concurrent use.
+//
+// If the file is deleted or replaced underneath it, e.g. by a cleanup
+// script, the FileWriter notices it on the first Write following a
+// check interval, or a failed write, and recreates the file, instead of
+// writing into an unlinked file until restart. A truncated file needs no
+// reopening: as it's opened for appending, the writes continue at the new
+// end of the file.
+type FileWriter struct {
+	mu      sync.Mutex
+	name    string
+	flag    int
+	f       *os.File
+	check   time.Duration // the check interval, disabled if <= 0
+	checked time.Time
+	health  sinkHealth
+}
+
+// OpenFile opens the file name for appending, creating it if needed.
+//
+// If dsync is true, the file is opened with O_DSYNC (O_SYNC where O_DSYNC
+// isn't available): each Write returns once its data is on the disk.
+// It's the strictest durability, for trails such as audit logs, at the
+// price of a disk round trip per entry. Alternatively, the SyncEveryWrite
+// option of NewCore calls Sync after each entry.
+func OpenFile(name string, dsync bool) (*FileWriter, error) {
+	flag := os.O_WRONLY | os.O_APPEND | os.O_CREATE
+	if dsync {
+		flag |= _oDSync
+	}
+	f, err := os.OpenFile(name, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	lifecycle(InfoLevel, "sink opened", String("sink", name))
+	return &FileWriter{name: name, flag: flag, f: f, check: _fileCheckInterval, checked: time.Now()}, nil
+}
+
+// SetCheckInterval sets the delay between two checks that the file wasn't
+// deleted or replaced, a second by default; d <= 0 disables the checks.
+func (w *FileWriter) SetCheckInterval(d time.Duration) {
+	w.mu.Lock()
+	w.check = d
+	w.mu.Unlock()
+}
+
+// Name returns the name of the file.
+func (w *FileWriter) Name() string {
+	return w.name
+}
+
+// Write appends p to the file.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	var reopened bool
+	var rerr error // the error reopening the file
+	if w.check > 0 {
+		if now := time.Now(); now.Sub(w.checked) >= w.check {
+			w.checked = now
+			reopened, rerr = w.reopenIfMoved()
+		}
+	}
+	n, err := w.f.Write(p)
+	if err != nil && n == 0 && w.check > 0 {
+		if reopened, rerr = w.reopenIfMoved(); reopened {
+			n, err = w.f.Write(p)
+		}
+	}
+	w.mu.Unlock()
+
+	if reopened {
+		lifecycle(InfoLevel, "sink reopened", String("sink", w.name))
+	}
+	if rerr != nil {
+		w.health.report(w.name, rerr)
+	} else {
+		w.health.report(w.name, err)
+	}
+	return n, err
+}
+
+// reopenIfMoved reopens the file if its name doesn't name it anymore,
+// and reports whether it did. w.mu is held.
+func (w *FileWriter) reopenIfMoved() (bool, error) {
+	fi, err := os.Stat(w.name)
+	if err == nil {
+		if cur, err := w.f.Stat(); err == nil && os.SameFile(fi, cur) {
+			return false, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	f, err := os.OpenFile(w.name, w.flag, 0644)
+	if err != nil {
+		return false, err
+	}
+	w.f.Close()
+	w.f = f
+	return true, nil
+}
+
+// Sync commits the content of the file to the disk.
+func (w *FileWriter) Sync() error {
+	w.mu.Lock()
+	err := w.f.Sync()
+	w.mu.Unlock()
+	return err
+}
+
+// Close closes the file.
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	err := w.f.Close()
+	w.mu.Unlock()
+	return err
+}
@@ -0,0 +1,12 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package xlog
+
+import "syscall"
+
+const _oDSync = syscall.O_DSYNC
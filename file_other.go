@@ -0,0 +1,13 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package xlog
+
+import "os"
+
+// O_DSYNC isn't portable, O_SYNC also commits the metadata.
+const _oDSync = os.O_SYNC
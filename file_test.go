@@ -0,0 +1,101 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type countingSyncer struct {
+	syncs int
+}
+
+func (w *countingSyncer) Write(p []byte) (int, error) { return len(p), nil }
+func (w *countingSyncer) Sync() error                 { w.syncs++; return nil }
+
+func TestFileWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	ioutil.WriteFile(path, []byte("old\n"), 0644)
+	for _, dsync := range []bool{false, true} {
+		w, err := OpenFile(path, dsync)
+		if err != nil {
+			t.Fatalf("OpenFile(%v) error = %v", dsync, err)
+		}
+		log := New(NewCore(NewJSONEncoder(0), w, InfoLevel, SyncEveryWrite()))
+		log.Info("transfer")
+		if err = w.Close(); err != nil {
+			t.Errorf("FileWriter.Close() error = %v", err)
+		}
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if lines := bytes.Count(data, []byte("\n")); lines != 3 {
+		t.Errorf("file = %q, want 3 appended lines", data)
+	}
+}
+
+func TestFileWriter_reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	w, err := OpenFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.SetCheckInterval(time.Nanosecond)
+
+	w.Write([]byte("a\n"))
+	os.Remove(path)
+	w.Write([]byte("b\n"))
+	if data, _ := ioutil.ReadFile(path); string(data) != "b\n" {
+		t.Errorf("file = %q after deletion, want %q", data, "b\n")
+	}
+
+	os.Rename(path, path+".1")
+	w.Write([]byte("c\n"))
+	if data, _ := ioutil.ReadFile(path); string(data) != "c\n" {
+		t.Errorf("file = %q after rename, want %q", data, "c\n")
+	}
+
+	os.Truncate(path, 0)
+	w.Write([]byte("d\n"))
+	if data, _ := ioutil.ReadFile(path); string(data) != "d\n" {
+		t.Errorf("file = %q after truncation, want %q", data, "d\n")
+	}
+}
+
+func TestCore_SyncEveryWrite(t *testing.T) {
+	var w countingSyncer
+	log := New(NewCore(NewJSONEncoder(0), &w, InfoLevel))
+	log.Info("a")
+	log.Error("b")
+	if w.syncs != 1 {
+		t.Errorf("syncs = %d, want 1 without SyncEveryWrite", w.syncs)
+	}
+
+	w.syncs = 0
+	log = New(NewCore(NewJSONEncoder(0), &w, InfoLevel, SyncEveryWrite()))
+	log.Info("a")
+	log.Error("b")
+	if w.syncs != 2 {
+		t.Errorf("syncs = %d, want 2 with SyncEveryWrite", w.syncs)
+	}
+}
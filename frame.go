@@ -0,0 +1,156 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"runtime"
+	"sync"
+)
+
+// the maximum number of frames captured by Stack.
+const _maxStackDepth = 1024
+
+// pcsPool pools the buffers of program counters of Stack.
+var pcsPool = sync.Pool{
+	New: func() interface{} {
+		pcs := make([]uintptr, 64)
+		return &pcs
+	},
+}
+
+// A Frame is a structured stack frame.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// Frames is a stack trace as structured frames, innermost first.
+// Encoded to JSON it is an array of {"func","file","line"} objects, so
+// log backends can render clickable frames and group by the top frame;
+// String returns the familiar text blob.
+type Frames []Frame
+
+// CaptureFrames returns at most n frames of the calling goroutine's stack,
+// skip is the number of frames to skip, 0 identifying the caller of
+// CaptureFrames.
+func CaptureFrames(skip, n int) Frames {
+	if n <= 0 {
+		return nil
+	}
+	var buf [32]uintptr
+	pcs := buf[:]
+	if n > len(pcs) {
+		pcs = make([]uintptr, n)
+	} else {
+		pcs = pcs[:n]
+	}
+	// +1 for runtime.Callers, +1 for CaptureFrames
+	return framesOf(pcs[:runtime.Callers(skip+2, pcs)])
+}
+
+// Stack returns a field holding the stack of the calling goroutine as
+// Frames, from the caller of Stack outward, for error reports of code
+// that doesn't panic. The stack is captured into a pooled buffer, so
+// only the frames are allocated; it's truncated at 1024 frames.
+//
+//	log.Error("unexpected state", xlog.Stack("stack"))
+func Stack(key string) Field {
+	return Field{Key: key, Val: captureStack(1)}
+}
+
+// captureStack returns the stack of the calling goroutine, skip is the
+// number of frames to skip, 0 identifying the caller of captureStack.
+func captureStack(skip int) Frames {
+	p := pcsPool.Get().(*[]uintptr)
+	pcs := *p
+	for {
+		// +1 for runtime.Callers, +1 for captureStack
+		n := runtime.Callers(skip+2, pcs)
+		if n < len(pcs) || len(pcs) >= _maxStackDepth {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, len(pcs)*2)
+	}
+	frames := framesOf(pcs)
+	*p = pcs[:cap(pcs)]
+	pcsPool.Put(p)
+	return frames
+}
+
+// framesOf returns the frames of the program counters pcs,
+// as returned by runtime.Callers.
+func framesOf(pcs []uintptr) Frames {
+	frames := make(Frames, 0, len(pcs))
+	iter := runtime.CallersFrames(pcs)
+	for {
+		f, more := iter.Next()
+		if f.PC != 0 {
+			frames = append(frames, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+// String returns frames as text in the format of runtime/debug.Stack:
+//
+//	main.f()
+//		/src/main.go:12
+func (fs Frames) String() string {
+	b := getBuilder()
+	fs.appendText(b)
+	s := string(b.Bytes())
+	putBuilder(b)
+	return s
+}
+
+// appendText appends the text returned by String.
+func (fs Frames) appendText(b *Builder) {
+	for i, f := range fs {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(f.Function)
+		b.WriteString("()\n\t")
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.AppendInt(int64(f.Line))
+	}
+}
+
+// MarshalJSON implements the Marshaler interface.
+// The returned slice is owned by the caller.
+func (fs Frames) MarshalJSON() ([]byte, error) {
+	b := getBuilder()
+	fs.appendTo(b)
+	p := b.CopyBytes()
+	putBuilder(b)
+	return p, nil
+}
+
+func (fs Frames) appendTo(b *Builder) {
+	if fs == nil {
+		b.WriteString("null")
+		return
+	}
+	b.WriteByte('[')
+	for i, f := range fs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(`{"func":`)
+		b.AppendQuote(f.Function)
+		b.WriteString(`,"file":`)
+		b.AppendQuote(f.File)
+		b.WriteString(`,"line":`)
+		b.AppendInt(int64(f.Line))
+		b.WriteByte('}')
+	}
+	b.WriteByte(']')
+}
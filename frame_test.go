@@ -0,0 +1,73 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCaptureFrames(t *testing.T) {
+	frames := CaptureFrames(0, 2)
+	if len(frames) != 2 {
+		t.Fatalf("len(frames) = %d, want 2", len(frames))
+	}
+	if frames[0].Function != "github.com/cnotch/xlog.TestCaptureFrames" ||
+		!strings.HasSuffix(frames[0].File, "frame_test.go") {
+		t.Errorf("frames[0] = %+v", frames[0])
+	}
+
+	text := frames.String()
+	if !strings.HasPrefix(text, "github.com/cnotch/xlog.TestCaptureFrames()\n\t") {
+		t.Errorf("String() = %s", text)
+	}
+
+	var buf bytes.Buffer
+	New(NewCore(NewJSONEncoder(0), &buf, DebugLevel)).Error("failed", F("stack", frames))
+	var out struct {
+		Stack []struct {
+			Func string
+			File string
+			Line int
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", buf.Bytes(), err)
+	}
+	if len(out.Stack) != 2 || out.Stack[0].Func != frames[0].Function || out.Stack[0].Line != frames[0].Line {
+		t.Errorf("stack = %+v, want %+v", out.Stack, frames)
+	}
+
+	p, _ := Frames(nil).MarshalJSON()
+	if string(p) != "null" {
+		t.Errorf("MarshalJSON() = %s, want null", p)
+	}
+}
+
+func deepStack(n int) Field {
+	if n == 0 {
+		return Stack("stack")
+	}
+	return deepStack(n - 1)
+}
+
+func TestStack(t *testing.T) {
+	f := Stack("stack")
+	frames, ok := f.Val.(Frames)
+	if !ok || f.Key != "stack" || len(frames) < 2 {
+		t.Fatalf("Stack() = %v, want the frames", f)
+	}
+	if frames[0].Function != "github.com/cnotch/xlog.TestStack" {
+		t.Errorf("frames[0] = %+v, want the caller of Stack", frames[0])
+	}
+
+	// deeper than the pooled buffer
+	if frames := deepStack(100).Val.(Frames); len(frames) < 100 ||
+		frames[0].Function != "github.com/cnotch/xlog.deepStack" {
+		t.Errorf("Stack() of a deep stack = %d frames, top %+v", len(frames), frames[0])
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type gelfEncoder struct {
+	host string
+}
+
+// NewGELFEncoder returns an encoder writing entries as GELF 1.1 messages
+// for Graylog: the first line of the message is the short_message, a
+// multi-line message is also written as full_message, the level is mapped
+// to its syslog severity, and the logger name, the caller and the fields
+// become additional fields, prefixed with an underscore. Field values
+// that are neither strings nor numbers are written as their JSON text.
+//
+// Messages are not delimited, write them to a GELFWriter, or to a TCP
+// connection through a writer appending the null byte.
+func NewGELFEncoder(host string) Encoder {
+	return gelfEncoder{host: host}
+}
+
+// gelfLevel returns the syslog severity of lvl.
+func gelfLevel(lvl Level) int {
+	switch lvl {
+	case DebugLevel:
+		return 7
+	case InfoLevel:
+		return 6
+	case WarnLevel:
+		return 4
+	case ErrorLevel:
+		return 3
+	case PanicLevel:
+		return 2 // critical
+	default:
+		return 1 // alert
+	}
+}
+
+func (enc gelfEncoder) Encode(b *Builder, e Entry) error {
+	e = nestEntry(e)
+	b.WriteString(`{"version":"1.1","host":`)
+	b.AppendQuote(enc.host)
+
+	short := e.Message
+	if i := strings.IndexByte(short, '\n'); i >= 0 {
+		short = short[:i]
+	}
+	b.WriteString(`,"short_message":`)
+	b.AppendQuote(short)
+	if len(e.Stack) > 0 {
+		full := getBuilder()
+		full.WriteString(e.Message)
+		full.WriteByte('\n')
+		e.Stack.appendText(full)
+		b.WriteString(`,"full_message":`)
+		b.AppendQuote(full.String())
+		putBuilder(full)
+	} else if len(short) < len(e.Message) {
+		b.WriteString(`,"full_message":`)
+		b.AppendQuote(e.Message)
+	}
+
+	b.WriteString(`,"timestamp":`)
+	appendGELFTime(b, e.Time)
+	b.WriteString(`,"level":`)
+	b.AppendInt(int64(gelfLevel(e.Level)))
+
+	if e.LoggerName != "" {
+		b.WriteString(`,"_logger":`)
+		b.AppendQuote(e.LoggerName)
+	}
+	if !e.IngestTime.IsZero() {
+		b.WriteString(`,"_ingest_time":`)
+		appendGELFTime(b, e.IngestTime)
+	}
+	if e.Caller.Defined {
+		b.WriteString(`,"_file":`)
+		b.AppendQuote(e.Caller.File)
+		b.WriteString(`,"_line":`)
+		b.AppendInt(int64(e.Caller.Line))
+	}
+
+	var err error
+	for _, fs := range [2][]Field{e.Ctx, e.Fields} {
+		for _, f := range fs {
+			b.WriteString(`,"_`)
+			appendGELFKey(b, f.Key)
+			b.WriteString(`":`)
+			if ferr := appendGELFValue(b, f.Value()); ferr != nil && err == nil {
+				err = ferr
+			}
+		}
+	}
+	b.WriteByte('}')
+	return err
+}
+
+// appendGELFKey appends key with the characters not allowed by GELF
+// replaced by underscores. As "_id" is reserved by GELF, the key "id"
+// becomes "__id" once prefixed.
+func appendGELFKey(b *Builder, key string) {
+	if key == "id" {
+		b.WriteString("_id")
+		return
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			c == '_' || c == '.' || c == '-' {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+}
+
+func appendGELFValue(b *Builder, v interface{}) error {
+	switch v.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return b.AppendJSON(v)
+	}
+	jb := getBuilder()
+	defer putBuilder(jb)
+	err := jb.AppendJSON(v)
+	b.AppendQuote(jb.String())
+	return err
+}
+
+// appendGELFTime appends t as the seconds since the epoch, as GELF
+// timestamps are, always with the microseconds as six decimals.
+func appendGELFTime(b *Builder, t time.Time) {
+	b.AppendInt(t.Unix())
+	b.WriteByte('.')
+	us := t.Nanosecond() / int(time.Microsecond)
+	for d := 100000; d > 0; d /= 10 {
+		b.WriteByte(byte('0' + us/d%10))
+	}
+}
+
+// GELF chunking constants.
+const (
+	// GELFChunkSizeWAN is the default chunk size, safe over the internet.
+	GELFChunkSizeWAN = 1420
+	// GELFChunkSizeLAN is the chunk size for local networks.
+	GELFChunkSizeLAN = 8154
+
+	_gelfMaxChunks   = 128
+	_gelfChunkHeader = 12 // magic, message id, sequence number and count
+)
+
+// ErrGELFTooLarge is returned when a message needs more than 128 chunks.
+var ErrGELFTooLarge = errors.New("xlog: gelf message needs more than 128 chunks")
+
+// GELFWriter writes each GELF message, as written by a Core with the GELF
+// encoder, to a datagram connection, splitting the messages larger than
+// a chunk into GELF chunks. It's safe for concurrent use.
+type GELFWriter struct {
+	conn          io.Writer
+	chunkSize     int
+	compressAbove int
+	idPrefix      uint32
+	idSeq         uint32
+	mu            sync.Mutex // serializes the chunks of a message
+	zbuf          bytes.Buffer
+	zw            *gzip.Writer
+	sink          string // the address, for the lifecycle entries
+	health        sinkHealth
+}
+
+// NewGELFWriter creates a GELFWriter writing to conn, usually a UDP
+// connection. Messages are split into chunks of at most chunkSize bytes,
+// GELFChunkSizeWAN if zero. Messages larger than compressAbove bytes are
+// gzip compressed first; compressAbove < 0 disables compression.
+func NewGELFWriter(conn io.Writer, chunkSize, compressAbove int) *GELFWriter {
+	if chunkSize <= _gelfChunkHeader {
+		chunkSize = GELFChunkSizeWAN
+	}
+	var p [4]byte
+	rand.Read(p[:])
+	sink := "gelf"
+	if c, ok := conn.(net.Conn); ok {
+		sink = c.RemoteAddr().Network() + "://" + c.RemoteAddr().String()
+	}
+	return &GELFWriter{
+		conn:          conn,
+		chunkSize:     chunkSize,
+		compressAbove: compressAbove,
+		idPrefix:      binary.BigEndian.Uint32(p[:]),
+		sink:          sink,
+	}
+}
+
+// DialGELF connects to the Graylog GELF UDP input at addr, e.g.
+// "graylog:12201", and returns a GELFWriter compressing the messages
+// that need chunking.
+func DialGELF(addr string) (*GELFWriter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	w := NewGELFWriter(conn, GELFChunkSizeWAN, GELFChunkSizeWAN)
+	w.sink = "udp://" + addr
+	lifecycle(InfoLevel, "sink opened", String("sink", w.sink))
+	return w, nil
+}
+
+// Write writes the GELF message p.
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	n, err := w.write(p)
+	if err != ErrGELFTooLarge {
+		w.health.report(w.sink, err)
+	}
+	return n, err
+}
+
+func (w *GELFWriter) write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	msg := p
+	if w.compressAbove >= 0 && len(p) > w.compressAbove {
+		w.zbuf.Reset()
+		if w.zw == nil {
+			w.zw = gzip.NewWriter(&w.zbuf)
+		} else {
+			w.zw.Reset(&w.zbuf)
+		}
+		w.zw.Write(p)
+		if err := w.zw.Close(); err != nil {
+			return 0, err
+		}
+		msg = w.zbuf.Bytes()
+	}
+
+	if len(msg) <= w.chunkSize {
+		if _, err := w.conn.Write(msg); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	payload := w.chunkSize - _gelfChunkHeader
+	count := (len(msg) + payload - 1) / payload
+	if count > _gelfMaxChunks {
+		return 0, ErrGELFTooLarge
+	}
+
+	b := getBuilder()
+	defer putBuilder(b)
+	seq := atomic.AddUint32(&w.idSeq, 1)
+	for i := 0; i < count; i++ {
+		b.Reset()
+		b.buf = append(b.buf, 0x1e, 0x0f)
+		b.buf = append(b.buf, byte(w.idPrefix>>24), byte(w.idPrefix>>16), byte(w.idPrefix>>8), byte(w.idPrefix),
+			byte(seq>>24), byte(seq>>16), byte(seq>>8), byte(seq))
+		b.buf = append(b.buf, byte(i), byte(count))
+		end := (i + 1) * payload
+		if end > len(msg) {
+			end = len(msg)
+		}
+		b.Write(msg[i*payload : end])
+		if _, err := w.conn.Write(b.Bytes()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close closes the connection, if it's an io.Closer.
+func (w *GELFWriter) Close() error {
+	if c, ok := w.conn.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGELFEncoder(t *testing.T) {
+	e := Entry{
+		Level:      WarnLevel,
+		Time:       time.Unix(1554120000, 250*int64(time.Millisecond)),
+		LoggerName: "svc",
+		Caller:     NewEntryCaller(0, "/src/a.go", 7, true),
+		Message:    "disk full\ndetails",
+		Ctx:        []Field{F("id", 1)},
+		Fields:     []Field{F("user name", "bob"), F("ok", true), F("tags", []string{"a"})},
+	}
+	var b Builder
+	NewGELFEncoder("host1").Encode(&b, e)
+	want := `{"version":"1.1","host":"host1","short_message":"disk full","full_message":"disk full\ndetails",` +
+		`"timestamp":1554120000.250000,"level":4,"_logger":"svc","_file":"/src/a.go","_line":7,` +
+		`"__id":1,"_user_name":"bob","_ok":"true","_tags":"[\"a\"]"}`
+	if b.String() != want {
+		t.Errorf("Encode() = %s\nwant %s", b.String(), want)
+	}
+	if !json.Valid(b.Bytes()) {
+		t.Errorf("Encode() isn't valid json")
+	}
+}
+
+func TestGELFEncoder_stack(t *testing.T) {
+	e := Entry{
+		Level:   ErrorLevel,
+		Time:    time.Unix(1554120000, 0),
+		Message: "failed",
+		Stack:   Frames{{"main.run", "/src/main.go", 12}},
+	}
+	var b Builder
+	NewGELFEncoder("host1").Encode(&b, e)
+	want := `"short_message":"failed","full_message":"failed\nmain.run()\n\t/src/main.go:12",`
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("Encode() = %s, want the stack in full_message", b.String())
+	}
+}
+
+func TestGELFEncoder_ingestTime(t *testing.T) {
+	e := Entry{
+		Time:       time.Unix(1554120000, 0),
+		IngestTime: time.Unix(1554123600, 5*int64(time.Millisecond)),
+		Message:    "backfilled",
+	}
+	var b Builder
+	NewGELFEncoder("host1").Encode(&b, e)
+	want := `"timestamp":1554120000.000000,"level":6,"_ingest_time":1554123600.005000`
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("Encode() = %s, want %s", b.String(), want)
+	}
+}
+
+func TestAppendGELFTime(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Unix(1554120000, 0), "1554120000.000000"},
+		{time.Unix(1554120000, 250*int64(time.Millisecond)), "1554120000.250000"},
+		{time.Unix(1554120000, 123456789), "1554120000.123456"},
+		{time.Unix(1554120000, 999), "1554120000.000000"},
+	}
+	for _, tt := range tests {
+		var b Builder
+		appendGELFTime(&b, tt.t)
+		if b.String() != tt.want {
+			t.Errorf("appendGELFTime(%v) = %s, want %s", tt.t, b.String(), tt.want)
+		}
+	}
+}
+
+func TestGELFLevel(t *testing.T) {
+	want := map[Level]int{DebugLevel: 7, InfoLevel: 6, WarnLevel: 4, ErrorLevel: 3, PanicLevel: 2, FatalLevel: 1}
+	for lvl, severity := range want {
+		if got := gelfLevel(lvl); got != severity {
+			t.Errorf("gelfLevel(%v) = %d, want %d", lvl, got, severity)
+		}
+	}
+}
+
+func TestGELFWriter(t *testing.T) {
+	var rec packetRecorder
+	w := NewGELFWriter(&rec, 100, -1)
+
+	w.Write([]byte("small"))
+	msg := []byte(strings.Repeat("0123456789", 25))
+	w.Write(msg)
+
+	if len(rec.packets) != 4 || rec.packets[0] != "small" {
+		t.Fatalf("packets = %q, want 1 + 3 chunks", rec.packets)
+	}
+	var joined bytes.Buffer
+	for i, p := range rec.packets[1:] {
+		if p[0] != 0x1e || p[1] != 0x0f || int(p[10]) != i || p[11] != 3 || p[2:10] != rec.packets[1][2:10] {
+			t.Errorf("chunk %d has a bad header % x", i, p[:12])
+		}
+		joined.WriteString(p[12:])
+	}
+	if !bytes.Equal(joined.Bytes(), msg) {
+		t.Errorf("chunks = %s, want %s", joined.Bytes(), msg)
+	}
+
+	rec.packets = nil
+	NewGELFWriter(&rec, 0, 10).Write(msg)
+	if len(rec.packets) != 1 {
+		t.Fatalf("packets = %d, want 1 compressed", len(rec.packets))
+	}
+	zr, err := gzip.NewReader(strings.NewReader(rec.packets[0]))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	if p, _ := ioutil.ReadAll(zr); !bytes.Equal(p, msg) {
+		t.Errorf("decompressed = %s, want %s", p, msg)
+	}
+
+	if _, err := NewGELFWriter(&rec, 20, -1).Write(make([]byte, 8*129+1)); err != ErrGELFTooLarge {
+		t.Errorf("Write() error = %v, want ErrGELFTooLarge", err)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"context"
+	"time"
+)
+
+// HeartbeatConfig configures RunHeartbeat.
+type HeartbeatConfig struct {
+	// Level of the heartbeat entries, InfoLevel by default.
+	Level Level
+	// Interval between entries, one minute if zero.
+	Interval time.Duration
+	// Message of the entries, "heartbeat" if empty.
+	Message string
+	// Fields, if not nil, supplies the dynamic fields of each entry,
+	// such as the depth of a queue.
+	Fields func() []Field
+}
+
+// RunHeartbeat logs a heartbeat entry every interval until ctx is done.
+// Each entry carries the fields supplied by cfg.Fields and the time since
+// RunHeartbeat was called, as "uptime". It's usually run in its own
+// goroutine:
+//
+//	go xlog.RunHeartbeat(ctx, log, xlog.HeartbeatConfig{
+//		Fields: func() []xlog.Field { return []xlog.Field{xlog.F("queue", q.Len())} },
+//	})
+func RunHeartbeat(ctx context.Context, l *Logger, cfg HeartbeatConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Message == "" {
+		cfg.Message = "heartbeat"
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !l.LevelEnabled(cfg.Level) {
+				continue
+			}
+			var fields []Field
+			if cfg.Fields != nil {
+				fields = cfg.Fields()
+			}
+			fields = append(fields[:len(fields):len(fields)], F("uptime", now.Sub(start)))
+			l.Log(cfg.Level, cfg.Message, fields...)
+		}
+	}
+}
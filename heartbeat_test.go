@@ -0,0 +1,50 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunHeartbeat(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), Lock(&buf), DebugLevel))
+	var n int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunHeartbeat(ctx, log, HeartbeatConfig{
+			Level:    WarnLevel,
+			Interval: time.Millisecond,
+			Fields: func() []Field {
+				if atomic.AddInt32(&n, 1) == 3 {
+					cancel()
+				}
+				return []Field{F("queue", 5)}
+			},
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunHeartbeat() didn't return after cancel")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("entries = %q, want 3", lines)
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, `"level":"WARN"`) || !strings.Contains(line, `"msg":"heartbeat","queue":5,"uptime":"`) {
+			t.Errorf("entry = %s", line)
+		}
+	}
+}
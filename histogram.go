@@ -0,0 +1,92 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Buckets buckets numeric values into labeled histogram buckets, so a
+// value can be logged as a low-cardinality field that dashboards aggregate
+// cheaply without a metrics system:
+//
+//	latency_bucket="100ms-250ms"
+//
+// The buckets are [bound[i], bound[i+1]), plus one below the first bound
+// and one at or above the last. Labels are computed once, so bucketing
+// doesn't allocate.
+type Buckets struct {
+	bounds []float64
+	labels []string // len(bounds)+1 labels
+}
+
+// NewBuckets creates Buckets with the ascending bounds, format returns the
+// text of a bound in labels, strconv's shortest representation if nil.
+func NewBuckets(bounds []float64, format func(float64) string) *Buckets {
+	if format == nil {
+		format = func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	}
+	bs := &Buckets{bounds: append([]float64(nil), bounds...)}
+	sort.Float64s(bs.bounds)
+	if len(bs.bounds) == 0 {
+		bs.labels = []string{"all"}
+		return bs
+	}
+
+	bs.labels = make([]string, len(bs.bounds)+1)
+	bs.labels[0] = "<" + format(bs.bounds[0])
+	for i := 1; i < len(bs.bounds); i++ {
+		bs.labels[i] = format(bs.bounds[i-1]) + "-" + format(bs.bounds[i])
+	}
+	bs.labels[len(bs.bounds)] = ">=" + format(bs.bounds[len(bs.bounds)-1])
+	return bs
+}
+
+// NewLatencyBuckets creates Buckets of durations, labeled like "100ms-250ms".
+func NewLatencyBuckets(bounds ...time.Duration) *Buckets {
+	fs := make([]float64, len(bounds))
+	for i, d := range bounds {
+		fs[i] = float64(d)
+	}
+	return NewBuckets(fs, func(v float64) string { return time.Duration(v).String() })
+}
+
+// ExponentialLatencyBuckets creates n latency buckets bounded by start,
+// start*factor, start*factor^2 and so on.
+func ExponentialLatencyBuckets(start time.Duration, factor float64, n int) *Buckets {
+	bounds := make([]time.Duration, n)
+	for i := range bounds {
+		bounds[i] = time.Duration(float64(start) * math.Pow(factor, float64(i)))
+	}
+	return NewLatencyBuckets(bounds...)
+}
+
+// DefaultLatencyBuckets span from a millisecond to ten seconds.
+var DefaultLatencyBuckets = NewLatencyBuckets(
+	time.Millisecond, 2500*time.Microsecond, 5*time.Millisecond,
+	10*time.Millisecond, 25*time.Millisecond, 50*time.Millisecond,
+	100*time.Millisecond, 250*time.Millisecond, 500*time.Millisecond,
+	time.Second, 2500*time.Millisecond, 5*time.Second, 10*time.Second)
+
+// Label returns the label of the bucket holding v.
+func (bs *Buckets) Label(v float64) string {
+	// the number of bounds <= v
+	i := sort.Search(len(bs.bounds), func(i int) bool { return bs.bounds[i] > v })
+	return bs.labels[i]
+}
+
+// Field returns a field holding the label of the bucket of v.
+func (bs *Buckets) Field(key string, v float64) Field {
+	return Field{Key: key, Val: bs.Label(v)}
+}
+
+// Duration returns a field holding the label of the bucket of d,
+// for buckets created by NewLatencyBuckets.
+func (bs *Buckets) Duration(key string, d time.Duration) Field {
+	return Field{Key: key, Val: bs.Label(float64(d))}
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuckets(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "<1ms"},
+		{time.Millisecond, "1ms-2.5ms"},
+		{120 * time.Millisecond, "100ms-250ms"},
+		{250 * time.Millisecond, "250ms-500ms"},
+		{time.Minute, ">=10s"},
+	}
+	for _, tt := range tests {
+		f := DefaultLatencyBuckets.Duration("latency_bucket", tt.d)
+		if f.Key != "latency_bucket" || f.Val != tt.want {
+			t.Errorf("Duration(%v) = %v, want %v", tt.d, f, tt.want)
+		}
+	}
+
+	exp := ExponentialLatencyBuckets(10*time.Millisecond, 2, 3)
+	if got := exp.Label(float64(30 * time.Millisecond)); got != "20ms-40ms" {
+		t.Errorf("Label() = %v, want 20ms-40ms", got)
+	}
+
+	sizes := NewBuckets([]float64{1024, 512}, nil)
+	if got := sizes.Field("size", 600).Val; got != "512-1024" {
+		t.Errorf("Field() = %v, want 512-1024", got)
+	}
+	if got := NewBuckets(nil, nil).Label(1); got != "all" {
+		t.Errorf("Label() = %v, want all", got)
+	}
+}
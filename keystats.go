@@ -0,0 +1,114 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyBytes is the number of encoded bytes contributed by a field key.
+type KeyBytes struct {
+	Key   string
+	Bytes uint64 // the size of the encoded "key":value pairs
+	Count uint64 // the number of fields
+}
+
+// KeyStatsCore is a Core accounting the bytes each field key contributes
+// to the log, to find the fields which blow up the log bill. Fields are
+// encoded once more for the accounting, so it's meant to be enabled for
+// audits rather than permanently.
+//
+// The statistics cover the window since the last Reset or Report.
+type KeyStatsCore struct {
+	Core
+	mu    sync.Mutex
+	stats map[string]*KeyBytes
+	since time.Time
+}
+
+// NewKeyStatsCore creates a KeyStatsCore writing to core.
+func NewKeyStatsCore(core Core) *KeyStatsCore {
+	return &KeyStatsCore{
+		Core:  core,
+		stats: make(map[string]*KeyBytes),
+		since: time.Now(),
+	}
+}
+
+// Write accounts the fields of e, then writes it to the underlying core.
+func (c *KeyStatsCore) Write(e Entry) error {
+	b := getBuilder()
+	c.mu.Lock()
+	c.account(b, e.Ctx)
+	c.account(b, e.Fields)
+	c.mu.Unlock()
+	putBuilder(b)
+	return c.Core.Write(e)
+}
+
+func (c *KeyStatsCore) account(b *Builder, fs []Field) {
+	for _, f := range fs {
+		b.Reset()
+		f.appendTo(b)
+		s := c.stats[f.Key]
+		if s == nil {
+			s = &KeyBytes{Key: f.Key}
+			c.stats[f.Key] = s
+		}
+		s.Bytes += uint64(b.Len()) + 1 // the separating comma
+		s.Count++
+	}
+}
+
+// Top returns the k keys contributing the most bytes, in descending order.
+// k <= 0 returns all keys.
+func (c *KeyStatsCore) Top(k int) []KeyBytes {
+	c.mu.Lock()
+	top := make([]KeyBytes, 0, len(c.stats))
+	for _, s := range c.stats {
+		top = append(top, *s)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Bytes != top[j].Bytes {
+			return top[i].Bytes > top[j].Bytes
+		}
+		return top[i].Key < top[j].Key
+	})
+	if k > 0 && k < len(top) {
+		top = top[:k]
+	}
+	return top
+}
+
+// Reset clears the statistics and starts a new window.
+func (c *KeyStatsCore) Reset() {
+	c.mu.Lock()
+	c.stats = make(map[string]*KeyBytes)
+	c.since = time.Now()
+	c.mu.Unlock()
+}
+
+// Report logs the top k keys of the window as a meta-entry to log at
+// InfoLevel, then starts a new window. Call it periodically, e.g. from
+// a time.Ticker loop:
+//
+//	{"msg":"xlog key bytes","window":"1m0s","keys":[{"key":"body","bytes":52311,"count":12}]}
+func (c *KeyStatsCore) Report(log *Logger, k int) {
+	top := c.Top(k)
+	c.mu.Lock()
+	window := time.Since(c.since)
+	c.mu.Unlock()
+	c.Reset()
+
+	keys := make([]O, len(top))
+	for i, s := range top {
+		keys[i] = O{F("key", s.Key), F("bytes", s.Bytes), F("count", s.Count)}
+	}
+	log.Info("xlog key bytes", F("window", window), F("keys", keys))
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestKeyStatsCore(t *testing.T) {
+	var buf bytes.Buffer
+	core := NewKeyStatsCore(NewCore(NewJSONEncoder(0), &buf, DebugLevel))
+	log := New(core, Fields(F("svc", "api")))
+
+	body := strings.Repeat("x", 100)
+	for i := 0; i < 3; i++ {
+		log.Info("request", F("body", body), F("n", 1))
+	}
+
+	top := core.Top(2)
+	want := []KeyBytes{
+		{Key: "body", Bytes: 3 * uint64(len(`"body":""`)+100+1), Count: 3},
+		{Key: "svc", Bytes: 3 * uint64(len(`"svc":"api"`)+1), Count: 3},
+	}
+	if len(top) != 2 || top[0] != want[0] || top[1] != want[1] {
+		t.Errorf("Top(2) = %+v, want %+v", top, want)
+	}
+
+	var meta bytes.Buffer
+	core.Report(New(NewCore(NewJSONEncoder(0), &meta, DebugLevel)), 1)
+	if !bytes.Contains(meta.Bytes(), []byte(`"keys":[{"key":"body","bytes":330,"count":3}]`)) {
+		t.Errorf("Report() = %s", meta.Bytes())
+	}
+	if len(core.Top(0)) != 0 {
+		t.Errorf("Report() doesn't start a new window")
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// the number of latest durations kept per operation for the percentiles
+	_latencySamples = 512
+	// the number of operations above which the new ones are accounted
+	// to LatencyOtherOp, so a message with a variable part can't exhaust
+	// the memory
+	_maxLatencyOps = 1024
+)
+
+// LatencyOtherOp is the operation of the entries accounted once
+// a LatencyStatsCore tracks too many operations.
+const LatencyOtherOp = "(other)"
+
+// OpStats are the RED metrics of an operation: its rate, errors and
+// durations over a window.
+type OpStats struct {
+	Op     string
+	Count  uint64 // the number of entries
+	Errors uint64 // the number of failed operations
+	Min    time.Duration
+	Max    time.Duration
+	Mean   time.Duration
+	// the percentiles of the latest durations
+	P50, P90, P99 time.Duration
+}
+
+// opLatency accounts the durations of an operation.
+type opLatency struct {
+	count, errors uint64
+	sum           time.Duration
+	min, max      time.Duration
+	samples       [_latencySamples]time.Duration // a ring of the latest durations
+}
+
+// LatencyStatsCore is a Core deriving lightweight RED metrics from the
+// log, for teams without a metrics stack: it recognizes the entries
+// carrying a time.Duration field "duration", such as the end entries of a
+// Scope, and keeps per operation, named by the message of the entries,
+// the count, the errors, and a summary of the durations.
+// An entry is failed if its level is ErrorLevel or above, or if it has the
+// field "status" "error".
+//
+// The statistics cover the window since the last Reset or Report.
+type LatencyStatsCore struct {
+	Core
+	mu    sync.Mutex
+	ops   map[string]*opLatency
+	since time.Time
+}
+
+// NewLatencyStatsCore creates a LatencyStatsCore writing to core.
+func NewLatencyStatsCore(core Core) *LatencyStatsCore {
+	return &LatencyStatsCore{
+		Core:  core,
+		ops:   make(map[string]*opLatency),
+		since: time.Now(),
+	}
+}
+
+// Write accounts e, then writes it to the underlying core.
+func (c *LatencyStatsCore) Write(e Entry) error {
+	c.account(e)
+	return c.Core.Write(e)
+}
+
+func (c *LatencyStatsCore) account(e Entry) {
+	d, ok := time.Duration(0), false
+	failed := e.Level >= ErrorLevel
+	for _, fs := range [2][]Field{e.Ctx, e.Fields} {
+		for _, f := range fs {
+			switch f.Key {
+			case "duration":
+				d, ok = f.Value().(time.Duration)
+			case "status":
+				if s, isStr := f.stringValue(); isStr && s == "error" {
+					failed = true
+				}
+			}
+		}
+	}
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	op := c.ops[e.Message]
+	if op == nil {
+		name := e.Message
+		if len(c.ops) >= _maxLatencyOps {
+			name = LatencyOtherOp
+			op = c.ops[name]
+		}
+		if op == nil {
+			op = &opLatency{min: d, max: d}
+			c.ops[name] = op
+		}
+	}
+	op.samples[op.count%_latencySamples] = d
+	op.count++
+	if failed {
+		op.errors++
+	}
+	op.sum += d
+	if d < op.min {
+		op.min = d
+	}
+	if d > op.max {
+		op.max = d
+	}
+}
+
+// Stats returns the statistics of the operations, sorted by operation.
+func (c *LatencyStatsCore) Stats() []OpStats {
+	var sorted []time.Duration
+	c.mu.Lock()
+	stats := make([]OpStats, 0, len(c.ops))
+	for name, op := range c.ops {
+		n := op.count
+		if n > _latencySamples {
+			n = _latencySamples
+		}
+		sorted = append(sorted[:0], op.samples[:n]...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		stats = append(stats, OpStats{
+			Op:     name,
+			Count:  op.count,
+			Errors: op.errors,
+			Min:    op.min,
+			Max:    op.max,
+			Mean:   op.sum / time.Duration(op.count),
+			P50:    percentile(sorted, 50),
+			P90:    percentile(sorted, 90),
+			P99:    percentile(sorted, 99),
+		})
+	}
+	c.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Op < stats[j].Op })
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted, by the nearest rank.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Reset clears the statistics and starts a new window.
+func (c *LatencyStatsCore) Reset() {
+	c.mu.Lock()
+	c.ops = make(map[string]*opLatency)
+	c.since = time.Now()
+	c.mu.Unlock()
+}
+
+// Report logs the statistics of the window as a meta-entry to log at
+// InfoLevel, then starts a new window. Call it periodically, e.g. from
+// a time.Ticker loop:
+//
+//	{"msg":"xlog latency","window":"1m0s","ops":[{"op":"load config","count":12,"errors":1,"min":"1.1ms","max":"9ms","mean":"2.5ms","p50":"2ms","p90":"4ms","p99":"9ms"}]}
+func (c *LatencyStatsCore) Report(log *Logger) {
+	stats := c.Stats()
+	c.mu.Lock()
+	window := time.Since(c.since)
+	c.mu.Unlock()
+	c.Reset()
+
+	ops := make([]O, len(stats))
+	for i, s := range stats {
+		ops[i] = O{F("op", s.Op), F("count", s.Count), F("errors", s.Errors),
+			F("min", s.Min), F("max", s.Max), F("mean", s.Mean),
+			F("p50", s.P50), F("p90", s.P90), F("p99", s.P99)}
+	}
+	log.Info("xlog latency", F("window", window), F("ops", ops))
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLatencyStatsCore(t *testing.T) {
+	core := NewLatencyStatsCore(NewCore(NewJSONEncoder(0), ioutil.Discard, DebugLevel))
+	log := New(core)
+
+	for i := 1; i <= 100; i++ {
+		log.Info("query", F("duration", time.Duration(i)*time.Millisecond), F("status", "ok"))
+	}
+	log.Warn("query", F("duration", 200*time.Millisecond), F("status", "error"))
+	log.Info("query") // no duration
+	s := log.Scope("load")
+	s.End(errors.New("failed"))
+
+	stats := core.Stats()
+	if len(stats) != 2 || stats[0].Op != "load" || stats[1].Op != "query" {
+		t.Fatalf("Stats() = %+v, want load and query", stats)
+	}
+	if load := stats[0]; load.Count != 1 || load.Errors != 1 {
+		t.Errorf("load = %+v, want 1 failed entry", load)
+	}
+	want := OpStats{
+		Op: "query", Count: 101, Errors: 1,
+		Min: time.Millisecond, Max: 200 * time.Millisecond, Mean: (5050 + 200) * time.Millisecond / 101,
+		P50: 51 * time.Millisecond, P90: 91 * time.Millisecond, P99: 100 * time.Millisecond,
+	}
+	if stats[1] != want {
+		t.Errorf("query = %+v, want %+v", stats[1], want)
+	}
+
+	var meta bytes.Buffer
+	core.Report(New(NewCore(NewJSONEncoder(0), &meta, DebugLevel)))
+	if !bytes.Contains(meta.Bytes(), []byte(`{"op":"query","count":101,"errors":1,"min":"1ms","max":"200ms"`)) {
+		t.Errorf("Report() = %s", meta.Bytes())
+	}
+	if len(core.Stats()) != 0 {
+		t.Errorf("Report() doesn't start a new window")
+	}
+}
+
+func TestLatencyStatsCore_maxOps(t *testing.T) {
+	core := NewLatencyStatsCore(NewNopCore())
+	for i := 0; i < _maxLatencyOps+10; i++ {
+		core.Write(Entry{Message: "op " + strconv.Itoa(i), Fields: []Field{F("duration", time.Second)}})
+	}
+	stats := core.Stats()
+	if len(stats) != _maxLatencyOps+1 || stats[0].Op != LatencyOtherOp || stats[0].Count != 10 {
+		t.Errorf("len(Stats()) = %d, first %+v, want %d with %d entries of %s",
+			len(stats), stats[0], _maxLatencyOps+1, 10, LatencyOtherOp)
+	}
+}
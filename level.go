@@ -100,44 +100,89 @@ func (l Level) CapitalString() string {
 	}
 }
 
+// Levels returns all the defined levels, ordered from the lowest
+// to the highest priority.
+func Levels() []Level {
+	return []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, PanicLevel, FatalLevel}
+}
+
+// ShortString returns a single-letter code of the log level,
+// such as "D" for DebugLevel.
+func (l Level) ShortString() string {
+	switch l {
+	case DebugLevel:
+		return "D"
+	case InfoLevel:
+		return "I"
+	case WarnLevel:
+		return "W"
+	case ErrorLevel:
+		return "E"
+	case PanicLevel:
+		return "P"
+	case FatalLevel:
+		return "F"
+	default:
+		return "?"
+	}
+}
+
+// The ANSI escape sequences of the level colors on terminals.
+const (
+	_colorMagenta = "\x1b[35m"
+	_colorBlue    = "\x1b[34m"
+	_colorYellow  = "\x1b[33m"
+	_colorRed     = "\x1b[31m"
+	_colorReset   = "\x1b[0m"
+)
+
+// Color returns the ANSI escape sequence used to colorize the log level
+// on terminals. Reset the color with "\x1b[0m".
+func (l Level) Color() string {
+	switch l {
+	case DebugLevel:
+		return _colorMagenta
+	case InfoLevel:
+		return _colorBlue
+	case WarnLevel:
+		return _colorYellow
+	default:
+		return _colorRed
+	}
+}
+
+// Valid reports whether l is one of the defined levels.
+func (l Level) Valid() bool {
+	return l >= _minLevel && l <= _maxLevel
+}
+
 const isWindows = runtime.GOOS == "windows"
 
+// _consoleStrings are the level strings of the console encoder, padded to
+// five characters and colorized by Color, except on Windows.
+var _consoleStrings = func() (ss [_maxLevel - _minLevel + 1]string) {
+	for _, l := range Levels() {
+		ss[l-_minLevel] = l.colorString()
+		if pad := 5 - len(l.CapitalString()); pad > 0 {
+			ss[l-_minLevel] += "     "[:pad]
+		}
+	}
+	return
+}()
+
 func (l Level) consoleString() string {
+	if l.Valid() {
+		return _consoleStrings[l-_minLevel]
+	}
+	return l.colorString()
+}
+
+// colorString returns the string of l colorized by Color, except on Windows.
+func (l Level) colorString() string {
 	if isWindows {
-		switch l {
-		case DebugLevel:
-			return "DEBUG"
-		case InfoLevel:
-			return "INFO "
-		case WarnLevel:
-			return "WARN "
-		case ErrorLevel:
-			return "ERROR"
-		case PanicLevel:
-			return "PANIC"
-		case FatalLevel:
-			return "FATAL"
-		default:
-			return fmt.Sprintf("LEVEL(%d)", l)
-		}
-	} else {
-		switch l {
-		case DebugLevel:
-			return "\x1b[35mDEBUG\x1b[0m"
-		case InfoLevel:
-			return "\x1b[34mINFO\x1b[0m "
-		case WarnLevel:
-			return "\x1b[33mWARN\x1b[0m "
-		case ErrorLevel:
-			return "\x1b[31mERROR\x1b[0m"
-		case PanicLevel:
-			return "\x1b[31mPANIC\x1b[0m"
-		case FatalLevel:
-			return "\x1b[31mFATAL\x1b[0m"
-		default:
-			return fmt.Sprintf("\x1b[31mLEVEL(%d)\x1b[0m", l)
-		}
+		return l.CapitalString()
 	}
+	return l.Color() + l.CapitalString() + _colorReset
 }
 
 // MarshalText marshals the Level to text. Note that the text representation
@@ -0,0 +1,60 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	levels := Levels()
+	if len(levels) != int(_maxLevel-_minLevel)+1 {
+		t.Fatalf("Levels() = %v, want all levels", levels)
+	}
+	for i, lvl := range levels {
+		if i > 0 && lvl <= levels[i-1] {
+			t.Errorf("Levels() = %v, want ascending order", levels)
+		}
+		if !lvl.Valid() {
+			t.Errorf("%v.Valid() = false, want true", lvl)
+		}
+		if lvl.ShortString() != lvl.CapitalString()[:1] {
+			t.Errorf("%v.ShortString() = %v, want %v", lvl, lvl.ShortString(), lvl.CapitalString()[:1])
+		}
+
+		var parsed Level
+		if err := parsed.UnmarshalText([]byte(lvl.String())); err != nil || parsed != lvl {
+			t.Errorf("UnmarshalText(%v) = %v, %v", lvl, parsed, err)
+		}
+	}
+	if Level(_maxLevel + 1).Valid() {
+		t.Errorf("Level(%d).Valid() = true, want false", _maxLevel+1)
+	}
+}
+
+func TestLevel_consoleString(t *testing.T) {
+	if isWindows {
+		t.Skip("levels aren't colorized on Windows")
+	}
+	tests := []struct {
+		lvl  Level
+		want string
+	}{
+		{DebugLevel, "\x1b[35mDEBUG\x1b[0m"},
+		{InfoLevel, "\x1b[34mINFO\x1b[0m "},
+		{WarnLevel, "\x1b[33mWARN\x1b[0m "},
+		{ErrorLevel, "\x1b[31mERROR\x1b[0m"},
+		{FatalLevel, "\x1b[31mFATAL\x1b[0m"},
+		{Level(9), "\x1b[31mLEVEL(9)\x1b[0m"},
+	}
+	for _, tt := range tests {
+		if got := tt.lvl.consoleString(); got != tt.want {
+			t.Errorf("%v.consoleString() = %q, want %q", tt.lvl, got, tt.want)
+		}
+		if got := tt.lvl.consoleString(); got[:len(tt.lvl.Color())] != tt.lvl.Color() {
+			t.Errorf("%v.consoleString() = %q, want the color %q", tt.lvl, got, tt.lvl.Color())
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// InternalLoggerName is the logger name of the lifecycle entries.
+const InternalLoggerName = "xlog"
+
+var internalL atomic.Value // holds internalCore
+
+// internalCore wraps the Core of the lifecycle entries,
+// as an atomic.Value needs values of a single type.
+type internalCore struct {
+	Core
+}
+
+// ReplaceInternalCore routes the lifecycle entries of the logging pipeline
+// itself to core, and returns a function to restore the previous core.
+// The entries are discarded by default, or if core is nil.
+//
+// The lifecycle entries, named InternalLoggerName, report the events of
+// the sinks, with the field "sink" naming the file or the address:
+//
+//	"sink opened"       INFO  a file, mmap or GELF sink is opened
+//	"sink reopened"     INFO  a FileWriter recreated its deleted or replaced file
+//	"sink failed"       WARN  a file, batch or GELF sink fails to write or to reopen, with the "error"
+//	"sink recovered"    INFO  the sink writes again
+//	"segment rotated"   INFO  a MmapWriter rotated its full segment, to "rotated"
+//	"queue saturated"   WARN  a WebhookCore or an OTLPCore starts dropping entries, its queue is full
+//	"queue recovered"   INFO  the core queues entries again, with the "dropped" count
+//	"slow encode"       WARN  an entry took longer to encode than SlowEncodeThreshold, with the
+//	                          "name" of its logger, the "key" of its slowest field and the "elapsed" time
+//
+// The URL sinks are named by their scheme and host only, as webhook URLs
+// carry their secret token.
+//
+// Subscribing is routing them to a core, e.g. the one of the application
+// with NewTee. The entries are written synchronously by the goroutine
+// causing the event, but never while a sink holds its lock, so a sink can
+// write its own lifecycle entries.
+func ReplaceInternalCore(core Core) func() {
+	prev, _ := internalL.Load().(internalCore)
+	internalL.Store(internalCore{core})
+	return func() { internalL.Store(prev) }
+}
+
+// sinkURL returns the scheme and the host of the URL raw, naming a sink
+// without the credentials of its user info, path or query.
+func sinkURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// sinkHealth reports the failures of a sink as lifecycle entries, once
+// when the sink starts failing and once when it recovers, rather than
+// for each write.
+type sinkHealth struct {
+	failing int32
+}
+
+// report records the result of a write to sink. It must not be called
+// while the sink holds its lock.
+func (h *sinkHealth) report(sink string, err error) {
+	if err != nil {
+		if atomic.CompareAndSwapInt32(&h.failing, 0, 1) {
+			lifecycle(WarnLevel, "sink failed", String("sink", sink), F("error", err))
+		}
+	} else if atomic.LoadInt32(&h.failing) != 0 && atomic.CompareAndSwapInt32(&h.failing, 1, 0) {
+		lifecycle(InfoLevel, "sink recovered", String("sink", sink))
+	}
+}
+
+// lifecycle writes a lifecycle entry to the internal core.
+func lifecycle(lvl Level, msg string, fields ...Field) {
+	c, _ := internalL.Load().(internalCore)
+	if c.Core == nil || !c.Enabled(lvl) {
+		return
+	}
+	c.Write(Entry{
+		Level:      lvl,
+		Time:       time.Now(),
+		LoggerName: InternalLoggerName,
+		Message:    msg,
+		Fields:     fields,
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplaceInternalCore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	var buf bytes.Buffer
+	restore := ReplaceInternalCore(NewCore(NewJSONEncoder(0), &buf, DebugLevel))
+	w, err := OpenFile(name, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// the FileWriter logs its own events without deadlocking
+	ReplaceInternalCore(NewCore(NewJSONEncoder(0), w, DebugLevel))
+	w.SetCheckInterval(time.Nanosecond)
+	os.Remove(name)
+	time.Sleep(time.Millisecond)
+	w.Write([]byte("after\n"))
+
+	restore()
+	lifecycle(InfoLevel, "discarded")
+
+	if !strings.Contains(buf.String(), `"logger":"xlog","msg":"sink opened","sink":"`+name) {
+		t.Errorf("internal entries = %s, want sink opened", buf.String())
+	}
+	data, _ := ioutil.ReadFile(name)
+	if !bytes.Contains(data, []byte(`"msg":"sink reopened"`)) || !bytes.HasPrefix(data, []byte("after\n")) {
+		t.Errorf("file = %s, want the entry then sink reopened", data)
+	}
+	if strings.Contains(buf.String()+string(data), "discarded") {
+		t.Errorf("lifecycle entry written after restore")
+	}
+}
+
+type failingConn struct{ err error }
+
+func (c *failingConn) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	return len(p), nil
+}
+
+func TestLifecycle_sinkFailed(t *testing.T) {
+	var buf bytes.Buffer
+	defer ReplaceInternalCore(NewCore(NewJSONEncoder(0), &buf, DebugLevel))()
+
+	conn := &failingConn{err: errors.New("connection refused")}
+	w := NewGELFWriter(conn, 0, -1)
+	w.Write([]byte("first"))
+	w.Write([]byte("second"))
+	conn.err = nil
+	w.Write([]byte("third"))
+	w.Write([]byte("fourth"))
+
+	got := buf.String()
+	if strings.Count(got, "\n") != 2 ||
+		!strings.Contains(got, `"logger":"xlog","msg":"sink failed","sink":"gelf","error":"connection refused"}`) ||
+		!strings.Contains(got, `"logger":"xlog","msg":"sink recovered","sink":"gelf"}`) {
+		t.Errorf("internal entries = %s, want one sink failed and one sink recovered", got)
+	}
+}
+
+func TestLifecycle_queueSaturated(t *testing.T) {
+	var buf bytes.Buffer
+	defer ReplaceInternalCore(NewCore(NewJSONEncoder(0), &buf, DebugLevel))()
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	// the webhook URL carries its token in the path
+	wh := NewWebhookCore(WebhookConfig{URL: srv.URL + "/services/T0/B0/secret", QueueSize: 1})
+	for i := 0; i < 4; i++ {
+		wh.Write(Entry{Level: ErrorLevel, Message: "m"})
+	}
+	close(release)
+	wh.Close()
+	if got := buf.String(); !strings.Contains(got, `"msg":"queue saturated","sink":"`+srv.URL+`"}`) ||
+		strings.Contains(got, "secret") {
+		t.Errorf("internal entries = %s, want queue saturated with the host of the webhook only", got)
+	}
+
+	buf.Reset()
+	otlp := NewOTLPCore(OTLPConfig{Endpoint: srv.URL + "/v1/logs?token=secret", BatchSize: 2, MaxPending: 1, Interval: time.Hour})
+	defer otlp.Close()
+	otlp.Write(Entry{Message: "pending"})
+	otlp.Write(Entry{Message: "dropped"})
+	otlp.Write(Entry{Message: "dropped"})
+	otlp.Sync()
+	otlp.Write(Entry{Message: "queued"})
+	if got := buf.String(); strings.Count(got, "\n") != 2 ||
+		!strings.Contains(got, `"msg":"queue saturated","sink":"`+srv.URL+`"}`) ||
+		!strings.Contains(got, `"msg":"queue recovered","sink":"`+srv.URL+`","dropped":2}`) ||
+		strings.Contains(got, "secret") {
+		t.Errorf("internal entries = %s, want queue saturated then recovered with 2 dropped", got)
+	}
+}
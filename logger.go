@@ -6,19 +6,30 @@ package xlog
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"runtime"
+	"sync"
 	"time"
 )
 
 // A Logger provides fast, leveled, structured logging.
 // All methods are safe for concurrent use.
 type Logger struct {
-	core       Core
-	addCaller  bool
-	callerSkip int
-	name       string
-	ctx        []Field
+	core        Core
+	addCaller   bool
+	callerSkip  int
+	callerDepth int
+	addStack    LevelEnabler
+	skipPkgs    []string
+	name        string
+	ctx         []Field
+	errorOutput io.Writer
+	clock       Clock
+	sortFields  bool
+	entryIDs    bool
+	fieldsHint  int // the expected number of preset fields
+	children    *childCache
 }
 
 // New constructs a new Logger from the provided Core and Options.
@@ -29,7 +40,8 @@ func New(core Core, options ...Option) *Logger {
 	}
 
 	log := &Logger{
-		core: core,
+		core:     core,
+		children: &childCache{},
 	}
 
 	for _, opt := range options {
@@ -48,6 +60,33 @@ func (l *Logger) With(opts ...Option) *Logger {
 	return c
 }
 
+// NamedCached returns the child Logger named s, as With(Named(s)) does,
+// but caches the children by name, so frameworks deriving the same named
+// loggers per request don't allocate and re-join names each time.
+// The cache holds at most 256 children; when it's full, it's cleared.
+func (l *Logger) NamedCached(s string) *Logger {
+	l.children.mu.Lock()
+	defer l.children.mu.Unlock()
+	if c, ok := l.children.m[s]; ok {
+		return c
+	}
+	if l.children.m == nil || len(l.children.m) >= _maxCachedChildren {
+		l.children.m = make(map[string]*Logger)
+	}
+	c := l.With(Named(s))
+	l.children.m[s] = c
+	return c
+}
+
+// the size bound of the child cache of a Logger.
+const _maxCachedChildren = 256
+
+// childCache holds the children of a Logger created by NamedCached.
+type childCache struct {
+	mu sync.Mutex
+	m  map[string]*Logger
+}
+
 // LevelEnabled 日志对象指定的级别是否启用
 func (l *Logger) LevelEnabled(lvl Level) bool {
 	if lvl < DebugLevel || lvl > FatalLevel {
@@ -56,6 +95,25 @@ func (l *Logger) LevelEnabled(lvl Level) bool {
 	return l.core.Enabled(lvl)
 }
 
+// Log logs a message at the specified level. The message includes any fields
+// passed at the log site, as well as any fields accumulated on the logger.
+//
+// Like the level methods, the logger panics at PanicLevel and calls
+// os.Exit(1) at FatalLevel.
+func (l *Logger) Log(lvl Level, msg string, fields ...Field) {
+	l.log(2, lvl, msg, nil, fields)
+}
+
+// LogAt logs a message at the specified level, stamped with t instead of
+// the current time, for the components ingesting historical events, which
+// must be stamped with the time of the event. The current time is kept
+// as the IngestTime of the entry, which the JSON and CBOR encoders write
+// as "ingest_time", the proto encoder as ingest_time_unix_nano, the GELF
+// encoder as "_ingest_time" and the OTLPCore as the observed time.
+func (l *Logger) LogAt(t time.Time, lvl Level, msg string, fields ...Field) {
+	l.logAt(2, t, lvl, msg, nil, fields)
+}
+
 // Debug logs a message at DebugLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l *Logger) Debug(msg string, fields ...Field) {
@@ -147,6 +205,12 @@ func (l *Logger) Core() Core {
 
 // all logical of log op.
 func (l *Logger) log(calloffset int, lvl Level, template string, fmtArgs []interface{}, fields []Field) {
+	l.logAt(calloffset+1, time.Time{}, lvl, template, fmtArgs, fields)
+}
+
+// logAt logs an entry stamped with at, or with the current time if at
+// is zero.
+func (l *Logger) logAt(calloffset int, at time.Time, lvl Level, template string, fmtArgs []interface{}, fields []Field) {
 	if !l.core.Enabled(lvl) {
 		switch lvl {
 		case PanicLevel:
@@ -157,21 +221,45 @@ func (l *Logger) log(calloffset int, lvl Level, template string, fmtArgs []inter
 		return
 	}
 
+	fields = expandGroups(fields)
+	if l.entryIDs {
+		fields = withEntryID(fields)
+	}
+
+	now := time.Now
+	if l.clock != nil {
+		now = l.clock.Now
+	}
 	e := Entry{
 		Level:      lvl,
-		Time:       time.Now(),
+		Time:       now(),
 		Message:    messagef(template, fmtArgs...),
 		Fields:     fields,
 		LoggerName: l.name,
 		Ctx:        l.ctx,
 	}
+	if !at.IsZero() {
+		e.IngestTime, e.Time = e.Time, at
+	}
+	if l.sortFields {
+		e.Ctx = sortedFields(e.Ctx)
+		e.Fields = sortedFields(e.Fields)
+	}
 
-	if l.addCaller {
+	if l.callerDepth > 1 {
+		e.Callers = newEntryCallers(l.callerSkip+calloffset, l.callerDepth, l.skipPkgs)
+		e.Caller = e.Callers[0]
+	} else if l.addCaller && len(l.skipPkgs) > 0 {
+		e.Caller = newEntryCallers(l.callerSkip+calloffset, 1, l.skipPkgs)[0]
+	} else if l.addCaller {
 		e.Caller = NewEntryCaller(runtime.Caller(l.callerSkip + calloffset))
 	}
+	if l.addStack != nil && l.addStack.Enabled(lvl) {
+		e.Stack = captureStack(l.callerSkip + calloffset)
+	}
 
 	if err := l.core.Write(e); err != nil {
-		// TODO: handle internal log errors
+		l.reportError(err)
 	}
 
 	// PanicLevel and FatalLevel require additional operations
@@ -183,11 +271,27 @@ func (l *Logger) log(calloffset int, lvl Level, template string, fmtArgs []inter
 	}
 }
 
+// reportError writes internal errors, such as failed writes, to the error output.
+func (l *Logger) reportError(err error) {
+	if l.errorOutput == nil {
+		return
+	}
+	fmt.Fprintf(l.errorOutput, "%v write error: %v\n", time.Now(), err)
+	if sync := getSyncFunc(l.errorOutput); sync != nil {
+		sync()
+	}
+}
+
 func (l *Logger) clone() *Logger {
 	c := *l
 	c.ctx = nil
+	if l.fieldsHint > len(l.ctx) {
+		c.ctx = make([]Field, 0, l.fieldsHint)
+	}
 	// avoid the subsequent addition of preset fields to interfere with l
 	c.ctx = append(c.ctx, l.ctx...)
+	c.skipPkgs = c.skipPkgs[:len(c.skipPkgs):len(c.skipPkgs)]
+	c.children = &childCache{}
 	return &c
 }
 
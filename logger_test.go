@@ -0,0 +1,159 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func logHelper(log *Logger, msg string) {
+	log.Info(msg)
+}
+
+func TestLogger_AddCallers(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(Lshortfile), &buf, DebugLevel), AddCallers(2))
+	logHelper(log, "helper")
+
+	var out struct {
+		Caller  string
+		Callers []string
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", buf.Bytes(), err)
+	}
+	if len(out.Callers) != 2 {
+		t.Fatalf("callers = %v, want 2 frames", out.Callers)
+	}
+	if out.Callers[0] != out.Caller {
+		t.Errorf("callers[0] = %v, want %v", out.Callers[0], out.Caller)
+	}
+	for _, c := range out.Callers {
+		if !strings.HasPrefix(c, "logger_test.go:") {
+			t.Errorf("caller = %v, want logger_test.go", c)
+		}
+	}
+}
+
+func TestLogger_AddCallerSkipPackages(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(Lshortfile), &buf, DebugLevel),
+		AddCaller(), AddCallerSkipPackages("github.com/cnotch/xlog"))
+	log.Info("skip")
+
+	// all frames of this package are skipped, the caller is the testing package.
+	if !bytes.Contains(buf.Bytes(), []byte(`"caller":"testing.go:`)) {
+		t.Errorf("Out = %s, want caller in testing.go", buf.Bytes())
+	}
+
+	if !hasPackagePrefix("github.com/cnotch/xlog.(*Logger).Info", []string{"github.com/cnotch"}) {
+		t.Errorf("hasPackagePrefix() = false, want true")
+	}
+	if hasPackagePrefix("github.com/cnotch/xlogx.F", []string{"github.com/cnotch/xlog/"}) {
+		t.Errorf("hasPackagePrefix() = true, want false")
+	}
+}
+
+func TestLogger_WithMinLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, InfoLevel))
+	job := log.With(WithMinLevel(WarnLevel), WithMinLevel(DebugLevel))
+
+	log.Debug("global")
+	job.Debug("job")
+	if !job.LevelEnabled(DebugLevel) || log.LevelEnabled(DebugLevel) {
+		t.Errorf("LevelEnabled(DebugLevel) = %v, %v", job.LevelEnabled(DebugLevel), log.LevelEnabled(DebugLevel))
+	}
+	if bytes.Contains(buf.Bytes(), []byte("global")) || !bytes.Contains(buf.Bytes(), []byte(`"msg":"job"`)) {
+		t.Errorf("Out = %s", buf.Bytes())
+	}
+	if _, ok := job.Core().(*minLevelCore).Core.(*minLevelCore); ok {
+		t.Errorf("WithMinLevel wraps the core twice")
+	}
+}
+
+func TestLogger_NamedCached(t *testing.T) {
+	log := New(NewNopCore(), Named("app"))
+	c1 := log.NamedCached("http")
+	if c1 != log.NamedCached("http") || c1.name != "app.http" {
+		t.Errorf("NamedCached() = %p %q, want a cached app.http", c1, c1.name)
+	}
+	if c1.NamedCached("db") == log.NamedCached("db") {
+		t.Errorf("children share the cache of their parent")
+	}
+
+	for i := 0; i < _maxCachedChildren+1; i++ {
+		log.NamedCached(strings.Repeat("x", i+1))
+	}
+	if n := len(log.children.m); n > _maxCachedChildren {
+		t.Errorf("len(cache) = %d, want <= %d", n, _maxCachedChildren)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() { log.NamedCached("x") })
+	if allocs != 0 {
+		t.Errorf("NamedCached() allocs = %v, want 0", allocs)
+	}
+}
+
+func TestLogger_LogAt(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+	log := New(NewCore(NewJSONEncoder(Lshortfile), &buf, DebugLevel), AddCaller(), WithClock(FixedClock(now)))
+
+	event := now.Add(-48 * time.Hour)
+	log.LogAt(event, WarnLevel, "replayed", F("n", 1))
+	want := `{"level":"WARN","time":"2019-03-30T12:00:00Z","ingest_time":"2019-04-01T12:00:00Z","caller":"logger_test.go:`
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("LogAt() = %s, want prefix %s", buf.String(), want)
+	}
+
+	var e Entry
+	if err := NewDecoder(&buf).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if !e.Time.Equal(event) || !e.IngestTime.Equal(now) {
+		t.Errorf("decoded times = %v, %v, want %v, %v", e.Time, e.IngestTime, event, now)
+	}
+
+	buf.Reset()
+	log.Info("live")
+	if strings.Contains(buf.String(), "ingest_time") {
+		t.Errorf("Info() = %s, want no ingest_time", buf.String())
+	}
+}
+
+func TestLogger_AddStacktrace(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, DebugLevel), AddStacktrace(ErrorLevel))
+
+	log.Warn("no stack")
+	if strings.Contains(buf.String(), "stacktrace") {
+		t.Errorf("Warn() = %s, want no stacktrace", buf.String())
+	}
+
+	buf.Reset()
+	log.Error("failed", F("n", 1))
+	var e Entry
+	if err := NewDecoder(&buf).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Stack) == 0 || e.Stack[0].Function != "github.com/cnotch/xlog.TestLogger_AddStacktrace" {
+		t.Errorf("decoded stack = %v, want the stack from the caller", e.Stack)
+	}
+	if len(e.Fields) != 1 || e.Fields[0].Key != "n" {
+		t.Errorf("decoded fields = %v", e.Fields)
+	}
+
+	buf.Reset()
+	log = New(NewCore(NewConsoleEncoder(0), &buf, DebugLevel), AddStacktrace(ErrorLevel))
+	log.Error("failed")
+	if want := "failed\ngithub.com/cnotch/xlog.TestLogger_AddStacktrace()\n\t"; !strings.Contains(buf.String(), want) {
+		t.Errorf("console = %q, want the stack after the entry", buf.String())
+	}
+}
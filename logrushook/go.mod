@@ -0,0 +1,10 @@
+module github.com/cnotch/xlog/logrushook
+
+go 1.13
+
+require (
+	github.com/cnotch/xlog v0.0.0
+	github.com/sirupsen/logrus v1.9.3
+)
+
+replace github.com/cnotch/xlog => ../
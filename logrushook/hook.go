@@ -0,0 +1,88 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package logrushook provides a logrus.Hook that forwards logrus entries
+// into an xlog Core, so services still logging with logrus can use the
+// xlog sinks and encoders right away.
+//
+// It's a separate module, so xlog itself doesn't depend on logrus.
+package logrushook
+
+import (
+	"sort"
+
+	"github.com/cnotch/xlog"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a logrus.Hook writing entries to an xlog Core.
+type Hook struct {
+	core   xlog.Core
+	name   string
+	levels []logrus.Level
+}
+
+// New returns a Hook writing the entries of the given levels to core,
+// all levels if none is given. name is used as the xlog logger name.
+func New(core xlog.Core, name string, levels ...logrus.Level) *Hook {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+	return &Hook{core: core, name: name, levels: levels}
+}
+
+// Levels implements logrus.Hook.
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire implements logrus.Hook.
+func (h *Hook) Fire(le *logrus.Entry) error {
+	lvl := Level(le.Level)
+	if !h.core.Enabled(lvl) {
+		return nil
+	}
+
+	e := xlog.Entry{
+		Level:      lvl,
+		Time:       le.Time,
+		Message:    le.Message,
+		LoggerName: h.name,
+	}
+
+	if len(le.Data) > 0 {
+		keys := make([]string, 0, len(le.Data))
+		for k := range le.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // logrus fields are unordered
+		e.Fields = make([]xlog.Field, len(keys))
+		for i, k := range keys {
+			e.Fields[i] = xlog.F(k, le.Data[k])
+		}
+	}
+
+	if le.HasCaller() {
+		e.Caller = xlog.NewEntryCaller(le.Caller.PC, le.Caller.File, le.Caller.Line, true)
+	}
+	return h.core.Write(e)
+}
+
+// Level maps a logrus level to the xlog level. Trace is mapped to DebugLevel.
+func Level(lvl logrus.Level) xlog.Level {
+	switch lvl {
+	case logrus.PanicLevel:
+		return xlog.PanicLevel
+	case logrus.FatalLevel:
+		return xlog.FatalLevel
+	case logrus.ErrorLevel:
+		return xlog.ErrorLevel
+	case logrus.WarnLevel:
+		return xlog.WarnLevel
+	case logrus.InfoLevel:
+		return xlog.InfoLevel
+	default:
+		return xlog.DebugLevel
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package logrushook
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cnotch/xlog"
+	"github.com/sirupsen/logrus"
+)
+
+func TestHook_Fire(t *testing.T) {
+	var buf bytes.Buffer
+	core := xlog.NewCore(xlog.NewJSONEncoder(xlog.Lshortfile), &buf, xlog.InfoLevel)
+
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	log.SetLevel(logrus.TraceLevel)
+	log.SetReportCaller(true)
+	log.AddHook(New(core, "legacy"))
+
+	log.Debug("disabled by the core")
+	log.WithFields(logrus.Fields{"b": 2, "a": "x"}).Warn("hello")
+
+	want := `"level":"WARN",`
+	wantTail := `"logger":"legacy","caller":"hook_test.go:28","msg":"hello","a":"x","b":2}` + "\n"
+	got := buf.String()
+	if !strings.HasPrefix(got, `{`+want) || !strings.HasSuffix(got, wantTail) {
+		t.Errorf("Out = %s, want %s...%s", got, want, wantTail)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+)
+
+// The layout of a segment file: a page with the magic number and the
+// committed length of the data, followed by the data itself. The data is
+// a sequence of frames, an entry each: its length and its CRC-32C as
+// little endian uint32, then the entry.
+const (
+	_mmapMagic       = "XLOGMMAP"
+	_mmapHeaderSize  = 4096
+	_mmapFrameHeader = 8
+)
+
+var _mmapCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// ErrEntryTooLarge is returned when an entry can't fit in a segment.
+	ErrEntryTooLarge = errors.New("xlog: entry larger than the mmap segment")
+	errBadSegment    = errors.New("xlog: not a mmap segment")
+	errClosed        = errors.New("xlog: writer closed")
+	errNoMmap        = errors.New("xlog: mmap writer not supported on this platform")
+)
+
+// appendMmapFrame writes the frame of p at the start of m, which must
+// have room for it.
+func appendMmapFrame(m, p []byte) {
+	binary.LittleEndian.PutUint32(m, uint32(len(p)))
+	binary.LittleEndian.PutUint32(m[4:], crc32.Checksum(p, _mmapCRCTable))
+	copy(m[_mmapFrameHeader:], p)
+}
+
+// scanMmapFrames calls fn with the entry of each frame of data up to the
+// first torn one, whose length or checksum doesn't match, and returns
+// the length of the valid frames.
+func scanMmapFrames(data []byte, fn func(p []byte)) int64 {
+	var n int
+	for len(data)-n >= _mmapFrameHeader {
+		l := int(binary.LittleEndian.Uint32(data[n:]))
+		end := n + _mmapFrameHeader + l
+		if l == 0 || end > len(data) {
+			break
+		}
+		p := data[n+_mmapFrameHeader : end]
+		if crc32.Checksum(p, _mmapCRCTable) != binary.LittleEndian.Uint32(data[n+4:]) {
+			break
+		}
+		if fn != nil {
+			fn(p)
+		}
+		n = end
+	}
+	return int64(n)
+}
+
+// readMmapData returns the header and the committed data of the segment f.
+func readMmapData(f *os.File) (header [_mmapHeaderSize]byte, data []byte, err error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	if _, err = f.ReadAt(header[:], 0); err != nil ||
+		!bytes.Equal(header[:len(_mmapMagic)], []byte(_mmapMagic)) {
+		err = errBadSegment
+		return
+	}
+	n := int64(binary.LittleEndian.Uint64(header[len(_mmapMagic):]))
+	if n > fi.Size()-_mmapHeaderSize {
+		n = fi.Size() - _mmapHeaderSize
+	}
+	data = make([]byte, n)
+	_, err = f.ReadAt(data, _mmapHeaderSize)
+	return
+}
+
+// RecoverMmapSegment checks the segment file at path and trims its torn
+// tail: the bytes appended after the last committed entry, and the
+// committed frames that never reached the disk, whose checksum doesn't
+// match. It returns the length of the committed data, frames included.
+func RecoverMmapSegment(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil || fi.Size() == 0 { // created but never written
+		return 0, err
+	}
+	header, data, err := readMmapData(f)
+	if err != nil {
+		return 0, err
+	}
+
+	n := scanMmapFrames(data, nil)
+	binary.LittleEndian.PutUint64(header[len(_mmapMagic):], uint64(n))
+	if _, err = f.WriteAt(header[:16], 0); err != nil {
+		return 0, err
+	}
+	// remove the torn tail
+	if err = f.Truncate(_mmapHeaderSize + n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ReadMmapSegment returns the committed entries of the segment file at
+// path, concatenated.
+func ReadMmapSegment(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	_, data, err := readMmapData(f)
+	if err != nil {
+		return nil, err
+	}
+	entries := data[:0]
+	scanMmapFrames(data, func(p []byte) {
+		entries = append(entries, p...) // never overtakes p
+	})
+	return entries, nil
+}
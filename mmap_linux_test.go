@@ -0,0 +1,172 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMmapWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.mlog")
+
+	w, err := OpenMmapWriter(path, 8192)
+	if err != nil {
+		t.Fatalf("OpenMmapWriter() error = %v", err)
+	}
+	w.Write([]byte("first\n"))
+	w.Write([]byte("second\n"))
+	if err = w.Sync(); err != nil {
+		t.Errorf("MmapWriter.Sync() error = %v", err)
+	}
+
+	// committed data is readable while the writer is open
+	if data, _ := ReadMmapSegment(path); string(data) != "first\nsecond\n" {
+		t.Errorf("ReadMmapSegment() = %q", data)
+	}
+	if _, err = w.Write(make([]byte, 8192)); err != ErrEntryTooLarge {
+		t.Errorf("MmapWriter.Write() error = %v, want %v", err, ErrEntryTooLarge)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("MmapWriter.Close() error = %v", err)
+	}
+
+	// simulate a crash: a torn entry after the committed length
+	committed := int64(2*_mmapFrameHeader + 13)
+	f, _ := os.OpenFile(path, os.O_RDWR, 0)
+	f.WriteAt([]byte("torn"), _mmapHeaderSize+committed)
+	f.Close()
+	if n, err := RecoverMmapSegment(path); err != nil || n != committed {
+		t.Errorf("RecoverMmapSegment() = %d, %v, want %d", n, err, committed)
+	}
+
+	// reopen appends, and a full segment is rotated
+	w, err = OpenMmapWriter(path, 8192)
+	if err != nil {
+		t.Fatalf("OpenMmapWriter() error = %v", err)
+	}
+	w.Write([]byte("third\n"))
+	big := make([]byte, 4080)
+	for i := range big {
+		big[i] = 'x'
+	}
+	w.Write(big)
+	w.Close()
+
+	if data, _ := ReadMmapSegment(path + ".1"); string(data) != "first\nsecond\nthird\n" {
+		t.Errorf("ReadMmapSegment(rotated) = %q", data)
+	}
+	if data, _ := ReadMmapSegment(path); string(data) != string(big) {
+		t.Errorf("ReadMmapSegment() len = %d, want %d", len(data), len(big))
+	}
+}
+
+func TestRecoverMmapSegment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.mlog")
+
+	w, err := OpenMmapWriter(path, 8192)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a CBOR entry ending with the integer 0
+	w.Write([]byte{0xa1, 0x61, 'n', 0x00})
+	w.Write([]byte("lost"))
+	w.Close()
+
+	// simulate a crash: the page of the last committed entry never
+	// reached the disk
+	f, _ := os.OpenFile(path, os.O_RDWR, 0)
+	f.WriteAt(make([]byte, _mmapFrameHeader+4), _mmapHeaderSize+_mmapFrameHeader+4)
+	f.Close()
+
+	if n, err := RecoverMmapSegment(path); err != nil || n != _mmapFrameHeader+4 {
+		t.Errorf("RecoverMmapSegment() = %d, %v, want %d", n, err, _mmapFrameHeader+4)
+	}
+	if data, _ := ReadMmapSegment(path); !bytes.Equal(data, []byte{0xa1, 0x61, 'n', 0x00}) {
+		t.Errorf("ReadMmapSegment() = %x, want the entry ending with a NUL byte", data)
+	}
+}
+
+func TestMmapWriter_reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.mlog")
+
+	w, err := OpenMmapWriter(path, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// a rotation that failed to open the new segment
+	w.mu.Lock()
+	w.close()
+	w.mu.Unlock()
+	os.Rename(path, path+".1")
+	os.Mkdir(path, 0755)
+	if _, err = w.Write([]byte("first\n")); err == nil || err == errClosed {
+		t.Fatalf("Write() error = %v, want the error opening the segment", err)
+	}
+
+	os.Remove(path)
+	if _, err = w.Write([]byte("second\n")); err != nil {
+		t.Fatalf("Write() error = %v, want the segment reopened", err)
+	}
+	w.Sync()
+	if data, _ := ReadMmapSegment(path); string(data) != "second\n" {
+		t.Errorf("ReadMmapSegment() = %q", data)
+	}
+}
+
+func TestMmapWriter_rotateNoName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.mlog")
+
+	defer func(n int) { _maxMmapSegments = n }(_maxMmapSegments)
+	_maxMmapSegments = 2
+	for _, name := range []string{path + ".1", path + ".2"} {
+		ioutil.WriteFile(name, nil, 0644)
+	}
+
+	w, err := OpenMmapWriter(path, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	entry := bytes.Repeat([]byte("x"), 3000)
+	w.Write(entry)
+	if _, err = w.Write(entry); err == nil {
+		t.Fatalf("Write() succeeded with all the rotated names taken")
+	}
+
+	// the full segment was reopened, it rotates once a name is free
+	os.Remove(path + ".2")
+	if _, err = w.Write(entry); err != nil {
+		t.Errorf("Write() error = %v after freeing a name", err)
+	}
+	if fi, err := os.Stat(path + ".2"); err != nil || fi.Size() == 0 {
+		t.Errorf("rotated segment %s.2 = %v, %v", path, fi, err)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package xlog
+
+// MmapWriter is an append-only writer backed by memory-mapped segment files.
+// It's only supported on Linux.
+type MmapWriter struct{}
+
+// OpenMmapWriter is not supported on this platform.
+func OpenMmapWriter(path string, segmentSize int64) (*MmapWriter, error) {
+	return nil, errNoMmap
+}
+
+// Write implements io.Writer.
+func (w *MmapWriter) Write(p []byte) (int, error) { return 0, errNoMmap }
+
+// Sync flushes the mapped pages to the file.
+func (w *MmapWriter) Sync() error { return errNoMmap }
+
+// Close flushes and unmaps the segment.
+func (w *MmapWriter) Close() error { return errNoMmap }
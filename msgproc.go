@@ -0,0 +1,59 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+// A MessageProcessor rewrites the message of e, as localized by the
+// catalog if any, for display. It must be safe for concurrent use, and
+// may retain msg.
+type MessageProcessor func(e Entry, msg string) string
+
+// WithMessageProcessors post-processes the messages of the console output
+// with procs, in order, e.g. for chat sinks or terse terminal output.
+// The JSON encoder keeps the message as logged.
+func WithMessageProcessors(procs ...MessageProcessor) EncoderOption {
+	return encoderOptionFunc(func(opts *encoderOptions) {
+		opts.msgProcs = append(opts.msgProcs, procs...)
+	})
+}
+
+var _levelEmoji = [_maxLevel - _minLevel + 1]string{
+	DebugLevel - _minLevel: "🔍",
+	InfoLevel - _minLevel:  "ℹ️",
+	WarnLevel - _minLevel:  "⚠️",
+	ErrorLevel - _minLevel: "❌",
+	PanicLevel - _minLevel: "💥",
+	FatalLevel - _minLevel: "💀",
+}
+
+// LevelEmoji prefixes messages with an emoji showing their level.
+func LevelEmoji() MessageProcessor {
+	return func(e Entry, msg string) string {
+		if !e.Level.Valid() {
+			return msg
+		}
+		return _levelEmoji[e.Level-_minLevel] + " " + msg
+	}
+}
+
+// PrefixTag prefixes messages with "[tag] ".
+func PrefixTag(tag string) MessageProcessor {
+	prefix := "[" + tag + "] "
+	return func(e Entry, msg string) string {
+		return prefix + msg
+	}
+}
+
+// PrefixField prefixes messages with "[value] ", the value of the string
+// field key, if the entry has one.
+func PrefixField(key string) MessageProcessor {
+	return func(e Entry, msg string) string {
+		if f, ok := lookupField(e, key); ok {
+			if s, ok := f.stringValue(); ok {
+				return "[" + s + "] " + msg
+			}
+		}
+		return msg
+	}
+}
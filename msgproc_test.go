@@ -0,0 +1,47 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMessageProcessors(t *testing.T) {
+	c := NewCatalog("en", "").Add("en", "disk.full", "disk {path} is full")
+	var cbuf, jbuf bytes.Buffer
+	log := New(NewTee(
+		NewCore(NewConsoleEncoder(0, WithCatalog(c),
+			WithMessageProcessors(PrefixField("tenant"), PrefixTag("db"), LevelEmoji())), &cbuf, DebugLevel),
+		NewCore(NewJSONEncoder(0), &jbuf, DebugLevel)))
+
+	log.Warn("disk.full", F("path", "/data"), F("tenant", "acme"))
+	log.Info("plain")
+
+	if !bytes.Contains(cbuf.Bytes(), []byte("⚠️ [db] [acme] disk /data is full\n")) ||
+		!bytes.Contains(cbuf.Bytes(), []byte("ℹ️ [db] plain\n")) {
+		t.Errorf("console = %s", cbuf.Bytes())
+	}
+	if !bytes.Contains(jbuf.Bytes(), []byte(`"msg":"disk.full"`)) {
+		t.Errorf("json = %s", jbuf.Bytes())
+	}
+}
+
+func TestMessageProcessors_retain(t *testing.T) {
+	c := NewCatalog("en", "").Add("en", "disk.full", "disk {path} is full")
+	var kept []string
+	keep := func(e Entry, msg string) string {
+		kept = append(kept, msg[:4])
+		return msg
+	}
+	var buf bytes.Buffer
+	log := New(NewCore(NewConsoleEncoder(0, WithCatalog(c), WithMessageProcessors(keep)), &buf, DebugLevel))
+	log.Warn("disk.full", F("path", "/data"))
+	log.Warn("overwritten")
+
+	if kept[0] != "disk" {
+		t.Errorf("retained message = %q, want %q", kept[0], "disk")
+	}
+}
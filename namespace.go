@@ -0,0 +1,44 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+// namespace is the value of the fields created by Namespace.
+type namespace struct{}
+
+// Namespace returns a field opening the object key: the fields following
+// it in the same list are nested in the object, e.g.
+//
+//	log.Info("query", xlog.F("svc", "api"), xlog.Namespace("db"), xlog.F("rows", 3), xlog.F("ms", 12))
+//	// "svc":"api","db":{"rows":3,"ms":12}
+//
+// A namespace in the preset fields of a Logger nests the preset fields
+// following it, not the log-site fields.
+func Namespace(key string) Field {
+	return Field{Key: key, Val: namespace{}}
+}
+
+// nestNamespaces returns fs with the fields following each namespace
+// nested in an O under its key. fs is returned as it is if it has no
+// namespace.
+func nestNamespaces(fs []Field) []Field {
+	for i, f := range fs {
+		if _, ok := f.Val.(namespace); ok {
+			nested := make([]Field, i, i+1)
+			copy(nested, fs[:i])
+			return append(nested, Field{Key: f.Key, Val: O(nestNamespaces(fs[i+1:]))})
+		}
+	}
+	return fs
+}
+
+// nestEntry returns e with the groups of its fields expanded, Skip
+// dropped, the fields written beside the errors added (see expandErrors),
+// and the namespaces nested, for the encoders which don't write the
+// fields in a single pass.
+func nestEntry(e Entry) Entry {
+	e.Ctx = nestNamespaces(expandErrors(expandGroups(e.Ctx)))
+	e.Fields = nestNamespaces(expandErrors(expandGroups(e.Fields)))
+	return e
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamespace(t *testing.T) {
+	e := Entry{
+		Ctx:    []Field{F("svc", "api"), Namespace("host"), F("name", "h1")},
+		Fields: []Field{F("id", 1), Namespace("db"), F("rows", 3), Namespace("pool"), F("idle", 2), Namespace("empty")},
+	}
+	b := getBuilder()
+	defer putBuilder(b)
+	tests := []struct {
+		enc  Encoder
+		want string
+	}{
+		{NewJSONEncoder(0), `"svc":"api","host":{"name":"h1"},"id":1,"db":{"rows":3,"pool":{"idle":2,"empty":{}}}}`},
+		// namespaces are sorted as a whole
+		{NewJSONEncoder(0, SortKeys()), `"host":{"name":"h1"},"svc":"api","db":{"rows":3,"pool":{"idle":2,"empty":{}}},"id":1}`},
+	}
+	for _, tt := range tests {
+		b.Reset()
+		tt.enc.Encode(b, e)
+		if got := b.String(); !strings.HasSuffix(got, tt.want+"\n") {
+			t.Errorf("Encode() = %s, want suffix %s", got, tt.want)
+		}
+	}
+
+	b.Reset()
+	NewCBOREncoder(0).Encode(b, e)
+	doc, _ := decodeCBOR(t, b.Bytes())
+	db := doc.(map[string]interface{})["db"]
+	wantDB := map[string]interface{}{"rows": int64(3), "pool": map[string]interface{}{"idle": int64(2), "empty": map[string]interface{}{}}}
+	if !reflect.DeepEqual(db, wantDB) {
+		t.Errorf("CBOR db = %v, want %v", db, wantDB)
+	}
+
+	fs := []Field{F("a", 1)}
+	if got := nestNamespaces(fs); &got[0] != &fs[0] {
+		t.Errorf("nestNamespaces() copied fields without namespace")
+	}
+}
@@ -4,7 +4,10 @@
 
 package xlog
 
-import "strings"
+import (
+	"io"
+	"strings"
+)
 
 // An Option configures a Logger.
 type Option interface {
@@ -40,7 +43,7 @@ func Fields(fs ...Field) Option {
 		if len(fs) == 0 {
 			return
 		}
-		log.ctx = append(log.ctx, fs...)
+		log.ctx = append(log.ctx, expandGroups(fs)...)
 	})
 }
 
@@ -52,6 +55,29 @@ func AddCaller() Option {
 	})
 }
 
+// AddCallers configures the Logger to annotate each message with the
+// filename and line number of the n innermost callers, which helps when the
+// direct caller is a helper function rather than the true origin.
+// It implies AddCaller.
+func AddCallers(n int) Option {
+	return optionFunc(func(log *Logger) {
+		log.addCaller = true
+		log.callerDepth = n
+	})
+}
+
+// AddStacktrace configures the Logger to record the stack of the entries
+// whose level is enabled by lvl, e.g. ErrorLevel for the errors and above.
+// The JSON and CBOR encoders write it as the array of frames "stacktrace",
+// the proto encoder as the repeated field stack, the console encoder as text
+// after the entry, the GELF encoder in full_message and the OTLPCore as the
+// attribute "exception.stacktrace".
+func AddStacktrace(lvl LevelEnabler) Option {
+	return optionFunc(func(log *Logger) {
+		log.addStack = lvl
+	})
+}
+
 // AddCallerSkip increases the number of callers skipped by caller annotation
 // (as enabled by the AddCaller option).
 //
@@ -61,3 +87,53 @@ func AddCallerSkip(skip int) Option {
 		log.callerSkip += skip
 	})
 }
+
+// ErrorOutput sets the destination for errors generated by the Logger itself,
+// such as failed writes reported by the Core.
+// Note that it's not the destination of error-level log entries.
+//
+// The supplied writer should be safe for concurrent use (see Lock).
+// Internal errors are discarded by default, or if w is nil.
+func ErrorOutput(w io.Writer) Option {
+	return optionFunc(func(log *Logger) {
+		log.errorOutput = w
+	})
+}
+
+// AddCallerSkipPackages skips the caller frames of functions whose package
+// path starts with one of prefixes, e.g. the packages of logging wrappers.
+// Unlike AddCallerSkip, it keeps the caller annotation correct when the
+// wrappers are refactored.
+func AddCallerSkipPackages(prefixes ...string) Option {
+	return optionFunc(func(log *Logger) {
+		log.skipPkgs = append(log.skipPkgs, prefixes...)
+	})
+}
+
+// minLevelCore enables the levels at or above min, in addition to the
+// levels enabled by its Core.
+type minLevelCore struct {
+	Core
+	min Level
+}
+
+func (c *minLevelCore) Enabled(lvl Level) bool {
+	return lvl >= c.min || c.Core.Enabled(lvl)
+}
+
+// WithMinLevel lowers the minimum level of the Logger to lvl, even if its
+// Core is configured with a higher one. Use it with Logger.With to debug a
+// single unit of work, such as a suspicious job, in production without
+// changing the global verbosity:
+//
+//	jobLog := log.With(xlog.WithMinLevel(xlog.DebugLevel))
+//
+// It never disables the levels the Core enables.
+func WithMinLevel(lvl Level) Option {
+	return optionFunc(func(log *Logger) {
+		if c, ok := log.core.(*minLevelCore); ok {
+			log.core = c.Core
+		}
+		log.core = &minLevelCore{log.core, lvl}
+	})
+}
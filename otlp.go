@@ -0,0 +1,502 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The keys of the fields holding the trace context of an entry, as hex
+// strings. The OTLP core exports them as the trace and span IDs of the
+// log record instead of attributes.
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// OTLPConfig configures an OTLPCore.
+type OTLPConfig struct {
+	// Endpoint is the OTLP/HTTP logs endpoint, e.g.
+	// http://collector:4318/v1/logs.
+	Endpoint string
+	// Headers are added to the export requests, e.g. for authentication.
+	Headers map[string]string
+	// Resource holds the resource attributes, e.g. service.name.
+	Resource map[string]string
+	// Enabler selects the entries to export, all levels if nil.
+	Enabler LevelEnabler
+	// BatchSize is the number of records exported at once, 512 if zero.
+	BatchSize int
+	// Interval is the maximum delay of an export, 5 seconds if zero.
+	Interval time.Duration
+	// MaxPending bounds the records waiting for an export, while the
+	// previous one is in progress, 8 batches if zero. Entries over the
+	// limit are dropped.
+	MaxPending int
+	// HighWater is the number of pending records above which the core is
+	// under backpressure, see Pressurer; 3/4 of MaxPending if zero.
+	HighWater int
+	// OnBackpressure, if set, is called with true when the pending
+	// records grow above HighWater, and with false when they drain to
+	// half of it. It's called by Write or by the export goroutine, and
+	// must not block.
+	OnBackpressure func(pressured bool)
+	// Client sends the requests, a client with a 10 seconds timeout if nil.
+	Client *http.Client
+}
+
+// OTLPCore is a Core exporting entries as OpenTelemetry LogRecords over
+// OTLP/HTTP with the JSON encoding, in batches. The level is mapped to the
+// severity, the message to the body, the logger name and the caller to
+// the semantic attributes, and the fields to attributes; trace_id and
+// span_id fields carry the trace context.
+type OTLPCore struct {
+	cfg      OTLPConfig
+	resource []byte // the encoded resource
+
+	mu      sync.Mutex
+	records Builder // comma separated records
+	n       int
+	err     error
+	dropped uint64
+
+	sink       string // the scheme and host of the endpoint, for the lifecycle entries
+	saturated  bool   // set while MaxPending records are pending
+	satDropped uint64 // the entries dropped since saturated
+	pressure   *backpressure
+	drops      dropCounter
+
+	exportMu sync.Mutex // serializes exports
+	wake     chan struct{}
+	stop     chan struct{}
+	stopped  sync.Once // closes stop
+	done     chan struct{}
+}
+
+// NewOTLPCore creates an OTLPCore and starts its export goroutine,
+// call Close to export the last batch and stop it.
+func NewOTLPCore(cfg OTLPConfig) *OTLPCore {
+	if cfg.Enabler == nil {
+		cfg.Enabler = DebugLevel
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = 8 * cfg.BatchSize
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	var b Builder
+	b.WriteString(`{"attributes":[`)
+	keys := make([]string, 0, len(cfg.Resource))
+	for k := range cfg.Resource {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		appendOTLPAttr(&b, k, cfg.Resource[k])
+	}
+	b.WriteString(`]}`)
+
+	c := &OTLPCore{
+		cfg:      cfg,
+		resource: b.Bytes(),
+		sink:     sinkURL(cfg.Endpoint),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	c.pressure = newBackpressure(cfg.MaxPending, cfg.HighWater, cfg.OnBackpressure)
+	go c.run()
+	return c
+}
+
+// Enabled implements the LevelEnabler interface.
+func (c *OTLPCore) Enabled(lvl Level) bool {
+	return c.cfg.Enabler.Enabled(lvl)
+}
+
+// otlpSeverity returns the severity number of lvl.
+func otlpSeverity(lvl Level) int {
+	switch lvl {
+	case DebugLevel:
+		return 5
+	case InfoLevel:
+		return 9
+	case WarnLevel:
+		return 13
+	case ErrorLevel:
+		return 17
+	case PanicLevel:
+		return 21
+	default:
+		return 24
+	}
+}
+
+// Write converts e to a LogRecord and adds it to the pending batch, or
+// drops it if MaxPending records are pending.
+func (c *OTLPCore) Write(e Entry) error {
+	b := getBuilder()
+	defer putBuilder(b)
+	observed := e.IngestTime
+	if observed.IsZero() {
+		observed = time.Now()
+	}
+	err := appendOTLPRecord(b, nestEntry(e), observed)
+
+	c.mu.Lock()
+	if c.n >= c.cfg.MaxPending {
+		c.dropped++
+		c.satDropped++
+		saturated := !c.saturated
+		c.saturated = true
+		n := c.n
+		c.mu.Unlock()
+		if saturated {
+			lifecycle(WarnLevel, "queue saturated", String("sink", c.sink))
+		}
+		c.pressure.update(n)
+		return err
+	}
+	if c.n > 0 {
+		c.records.WriteByte(',')
+	}
+	c.records.Write(b.Bytes())
+	c.n++
+	n, full := c.n, c.n >= c.cfg.BatchSize
+	recovered, dropped := c.saturated, c.satDropped
+	c.saturated, c.satDropped = false, 0
+	c.mu.Unlock()
+
+	if recovered {
+		lifecycle(InfoLevel, "queue recovered", String("sink", c.sink), Uint64("dropped", dropped))
+	}
+	c.pressure.update(n)
+
+	if full {
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+func appendOTLPRecord(b *Builder, e Entry, observed time.Time) error {
+	b.WriteString(`{"timeUnixNano":"`)
+	b.AppendInt(e.Time.UnixNano())
+	b.WriteString(`","observedTimeUnixNano":"`)
+	b.AppendInt(observed.UnixNano())
+	b.WriteString(`","severityNumber":`)
+	b.AppendInt(int64(otlpSeverity(e.Level)))
+	b.WriteString(`,"severityText":"`)
+	b.WriteString(e.Level.CapitalString())
+	b.WriteString(`","body":{"stringValue":`)
+	b.AppendQuote(e.Message)
+	b.WriteString(`},"attributes":[`)
+
+	n := 0
+	sep := func() {
+		if n > 0 {
+			b.WriteByte(',')
+		}
+		n++
+	}
+	if e.LoggerName != "" {
+		sep()
+		appendOTLPAttr(b, "logger.name", e.LoggerName)
+	}
+	if e.Caller.Defined {
+		sep()
+		appendOTLPAttr(b, "code.filepath", e.Caller.File)
+		sep()
+		appendOTLPAttr(b, "code.lineno", e.Caller.Line)
+	}
+	if len(e.Stack) > 0 {
+		sep()
+		appendOTLPAttr(b, "exception.stacktrace", e.Stack.String())
+	}
+
+	var err error
+	var traceID, spanID string
+	for _, fs := range [2][]Field{e.Ctx, e.Fields} {
+		for _, f := range fs {
+			if s, ok := f.stringValue(); ok {
+				switch f.Key {
+				case TraceIDKey:
+					traceID = s
+					continue
+				case SpanIDKey:
+					spanID = s
+					continue
+				}
+			}
+			sep()
+			if ferr := appendOTLPAttr(b, f.Key, f.Value()); ferr != nil && err == nil {
+				err = ferr
+			}
+		}
+	}
+	b.WriteByte(']')
+
+	if traceID != "" {
+		b.WriteString(`,"traceId":`)
+		b.AppendQuote(traceID)
+	}
+	if spanID != "" {
+		b.WriteString(`,"spanId":`)
+		b.AppendQuote(spanID)
+	}
+	b.WriteByte('}')
+	return err
+}
+
+// appendOTLPAttr appends a KeyValue.
+func appendOTLPAttr(b *Builder, key string, v interface{}) error {
+	b.WriteString(`{"key":`)
+	b.AppendQuote(key)
+	b.WriteString(`,"value":`)
+	err := appendOTLPValue(b, v)
+	b.WriteByte('}')
+	return err
+}
+
+// appendOTLPValue appends v as an AnyValue.
+func appendOTLPValue(b *Builder, v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		b.WriteString(`{"stringValue":`)
+		b.AppendQuote(v)
+	case bool:
+		b.WriteString(`{"boolValue":`)
+		b.AppendBool(v)
+	case int:
+		appendOTLPInt(b, int64(v))
+	case int8:
+		appendOTLPInt(b, int64(v))
+	case int16:
+		appendOTLPInt(b, int64(v))
+	case int32:
+		appendOTLPInt(b, int64(v))
+	case int64:
+		appendOTLPInt(b, v)
+	case uint8:
+		appendOTLPInt(b, int64(v))
+	case uint16:
+		appendOTLPInt(b, int64(v))
+	case uint32:
+		appendOTLPInt(b, int64(v))
+	case float32:
+		b.WriteString(`{"doubleValue":`)
+		b.AppendFloat64(float64(v))
+	case float64:
+		b.WriteString(`{"doubleValue":`)
+		b.AppendFloat64(v)
+	case []byte:
+		b.WriteString(`{"bytesValue":"`)
+		b.WriteString(base64.StdEncoding.EncodeToString(v))
+		b.WriteByte('"')
+	case O:
+		b.WriteString(`{"kvlistValue":{"values":[`)
+		var err error
+		for i, f := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if ferr := appendOTLPAttr(b, f.Key, f.Value()); ferr != nil && err == nil {
+				err = ferr
+			}
+		}
+		b.WriteString("]}}")
+		return err
+	default:
+		return appendOTLPViaJSON(b, v)
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+func appendOTLPInt(b *Builder, i int64) {
+	b.WriteString(`{"intValue":"`)
+	b.AppendInt(i)
+	b.WriteByte('"')
+}
+
+// appendOTLPViaJSON converts the JSON encoding of v to an AnyValue.
+func appendOTLPViaJSON(b *Builder, v interface{}) error {
+	jb := getBuilder()
+	defer putBuilder(jb)
+	err := jb.AppendJSON(v)
+
+	dec := json.NewDecoder(bytes.NewReader(jb.Bytes()))
+	dec.UseNumber()
+	var doc interface{}
+	if derr := dec.Decode(&doc); derr != nil {
+		appendOTLPValue(b, jb.String())
+		return derr
+	}
+	appendOTLPDoc(b, doc)
+	return err
+}
+
+// appendOTLPDoc appends a value decoded by encoding/json with UseNumber.
+func appendOTLPDoc(b *Builder, doc interface{}) {
+	switch v := doc.(type) {
+	case nil:
+		b.WriteString("{}")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString(`{"kvlistValue":{"values":[`)
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(`{"key":`)
+			b.AppendQuote(k)
+			b.WriteString(`,"value":`)
+			appendOTLPDoc(b, v[k])
+			b.WriteByte('}')
+		}
+		b.WriteString("]}}")
+	case []interface{}:
+		b.WriteString(`{"arrayValue":{"values":[`)
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			appendOTLPDoc(b, e)
+		}
+		b.WriteString("]}}")
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			appendOTLPInt(b, i)
+		} else {
+			b.WriteString(`{"doubleValue":`)
+			b.WriteString(v.String())
+			b.WriteByte('}')
+		}
+	default:
+		appendOTLPValue(b, v) // string, bool
+	}
+}
+
+// Sync exports the pending batch now, and returns the error of
+// the last failed export, if any, and a *DroppedError if entries
+// were dropped since the previous Sync.
+func (c *OTLPCore) Sync() error {
+	c.export()
+	c.mu.Lock()
+	err, dropped := c.err, c.dropped
+	c.err = nil
+	c.mu.Unlock()
+	return combineErrors(err, c.drops.since("otlp", dropped))
+}
+
+// Dropped returns the number of entries dropped because MaxPending
+// records were pending.
+func (c *OTLPCore) Dropped() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped
+}
+
+// Pressured reports whether the pending records are above their
+// high-water mark.
+func (c *OTLPCore) Pressured() bool {
+	return c.pressure.pressured()
+}
+
+// Close exports the pending batch and stops the export goroutine.
+func (c *OTLPCore) Close() error {
+	c.stopped.Do(func() { close(c.stop) })
+	<-c.done
+	return c.Sync()
+}
+
+func (c *OTLPCore) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.wake:
+		case <-c.stop:
+			return
+		}
+		c.export()
+	}
+}
+
+// export sends the pending batch, if any.
+func (c *OTLPCore) export() {
+	c.exportMu.Lock()
+	defer c.exportMu.Unlock()
+
+	c.mu.Lock()
+	if c.n == 0 {
+		c.mu.Unlock()
+		return
+	}
+	b := getBuilder()
+	defer putBuilder(b)
+	b.WriteString(`{"resourceLogs":[{"resource":`)
+	b.Write(c.resource)
+	b.WriteString(`,"scopeLogs":[{"scope":{"name":"github.com/cnotch/xlog"},"logRecords":[`)
+	b.Write(c.records.Bytes())
+	b.WriteString(`]}]}]}`)
+	c.records.Reset()
+	c.n = 0
+	c.mu.Unlock()
+	c.pressure.update(0)
+
+	if err := c.post(b.Bytes()); err != nil {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+	}
+}
+
+func (c *OTLPCore) post(payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, c.cfg.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("xlog: otlp export: %s", resp.Status)
+	}
+	return nil
+}
+
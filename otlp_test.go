@@ -0,0 +1,143 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOTLPCore(t *testing.T) {
+	bodies := make(chan []byte, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token" || r.URL.Path != "/v1/logs" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		p, _ := ioutil.ReadAll(r.Body)
+		bodies <- p
+	}))
+	defer srv.Close()
+
+	core := NewOTLPCore(OTLPConfig{
+		Endpoint:  srv.URL + "/v1/logs",
+		Headers:   map[string]string{"Authorization": "token"},
+		Resource:  map[string]string{"service.name": "api"},
+		BatchSize: 2,
+		Interval:  time.Hour,
+	})
+	log := New(core, Named("db"), Fields(F(TraceIDKey, "5b8efff798038103d269b633813fc60c")))
+	log.Info("first", F("n", 42), F("ok", true), F("obj", O{F("k", 1.5)}), F("tags", []string{"a"}))
+	log.Warn("second")
+
+	var body []byte
+	select {
+	case body = <-bodies:
+	case <-time.After(5 * time.Second):
+		t.Fatal("full batch not exported")
+	}
+	var req struct {
+		ResourceLogs []struct {
+			Resource  json.RawMessage
+			ScopeLogs []struct {
+				LogRecords []struct {
+					SeverityNumber int
+					SeverityText   string
+					Body           struct{ StringValue string }
+					Attributes     json.RawMessage
+					TraceID        string
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", body, err)
+	}
+	recs := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(recs) != 2 || recs[0].SeverityNumber != 9 || recs[1].SeverityText != "WARN" ||
+		recs[0].Body.StringValue != "first" || recs[0].TraceID != "5b8efff798038103d269b633813fc60c" {
+		t.Errorf("records = %+v", recs)
+	}
+	wantAttrs := `[{"key":"logger.name","value":{"stringValue":"db"}},` +
+		`{"key":"n","value":{"intValue":"42"}},{"key":"ok","value":{"boolValue":true}},` +
+		`{"key":"obj","value":{"kvlistValue":{"values":[{"key":"k","value":{"doubleValue":1.5}}]}}},` +
+		`{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"}]}}}]`
+	if string(recs[0].Attributes) != wantAttrs {
+		t.Errorf("attributes = %s\nwant %s", recs[0].Attributes, wantAttrs)
+	}
+	if !strings.Contains(string(req.ResourceLogs[0].Resource), `"service.name"`) {
+		t.Errorf("resource = %s", req.ResourceLogs[0].Resource)
+	}
+
+	log.Error("pending")
+	if err := core.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if body := <-bodies; !strings.Contains(string(body), `"pending"`) {
+		t.Errorf("Close() didn't export the pending batch: %s", body)
+	}
+}
+
+func TestAppendOTLPRecord_stack(t *testing.T) {
+	e := Entry{
+		Level:   ErrorLevel,
+		Time:    time.Unix(1554120000, 0),
+		Message: "failed",
+		Stack:   Frames{{"main.run", "/src/main.go", 12}},
+	}
+	var b Builder
+	if err := appendOTLPRecord(&b, e, e.Time); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"key":"exception.stacktrace","value":{"stringValue":"main.run()\n\t/src/main.go:12"}}`
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("appendOTLPRecord() = %s, want the stack attribute", b.String())
+	}
+}
+
+func TestOTLPCore_MaxPending(t *testing.T) {
+	received, release := make(chan struct{}, 4), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	}))
+	defer srv.Close()
+
+	core := NewOTLPCore(OTLPConfig{Endpoint: srv.URL, BatchSize: 1, MaxPending: 2, Interval: time.Hour})
+	core.Write(Entry{Message: "exporting"})
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch not exported")
+	}
+
+	// the collector hangs: the records pile up to MaxPending
+	for i := 0; i < 3; i++ {
+		core.Write(Entry{Message: "pending"})
+	}
+	if n := core.Dropped(); n != 1 {
+		t.Errorf("Dropped() = %d, want 1", n)
+	}
+	close(release)
+	core.Close()
+}
+
+func TestOTLPCore_concurrentClose(t *testing.T) {
+	core := NewOTLPCore(OTLPConfig{Endpoint: "http://127.0.0.1:0", Interval: time.Hour})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			core.Close()
+		}()
+	}
+	wg.Wait()
+}
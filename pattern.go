@@ -0,0 +1,202 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the conversions of a pattern layout.
+const (
+	patLiteral = iota
+	patDate
+	patLevel
+	patLogger
+	patCaller
+	patFile
+	patLine
+	patMessage
+	patFields
+)
+
+// DefaultDateLayout is the time layout of %d without braces.
+const DefaultDateLayout = "2006-01-02 15:04:05.000"
+
+// patternOp is a step of the append plan of a pattern layout.
+type patternOp struct {
+	kind  int
+	text  string // the literal, or the time layout of %d
+	width int    // the minimum width, padded with spaces
+	left  bool   // left-justified
+}
+
+type patternEncoder struct {
+	ops []patternOp
+}
+
+// NewPatternEncoder returns an encoder formatting entries by a log4j-style
+// layout, such as:
+//
+//	%d{2006-01-02 15:04:05} %-5p %c %caller - %m %fields%n
+//
+// The conversions are:
+//
+//	%d, %d{layout}  the time, formatted by a Go time layout, DefaultDateLayout by default
+//	%p              the level: INFO
+//	%c              the logger name
+//	%caller         the short file name and line of the caller: d.go:23
+//	%F, %L          the short file name, the line of the caller
+//	%m              the message
+//	%fields         the fields as a JSON object, nothing if there are none
+//	%n              a newline
+//	%%              a percent sign
+//
+// A width between the percent sign and the conversion pads the value with
+// spaces, on the left or, after a minus sign, on the right: %-5p.
+// The layout is compiled once into an append plan.
+func NewPatternEncoder(layout string) (Encoder, error) {
+	var ops []patternOp
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			ops = append(ops, patternOp{kind: patLiteral, text: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for i := 0; i < len(layout); i++ {
+		c := layout[i]
+		if c != '%' {
+			lit.WriteByte(c)
+			continue
+		}
+		i++
+		if i < len(layout) && layout[i] == '%' {
+			lit.WriteByte('%')
+			continue
+		}
+
+		op := patternOp{}
+		if i < len(layout) && layout[i] == '-' {
+			op.left = true
+			i++
+		}
+		j := i
+		for j < len(layout) && '0' <= layout[j] && layout[j] <= '9' {
+			j++
+		}
+		if j > i {
+			op.width, _ = strconv.Atoi(layout[i:j])
+		}
+		i = j
+
+		name := ""
+		for _, conv := range [...]string{"caller", "fields", "d", "p", "c", "F", "L", "m", "n"} {
+			if strings.HasPrefix(layout[i:], conv) {
+				name = conv
+				break
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("xlog: bad pattern layout %q: unknown conversion at offset %d", layout, i)
+		}
+		i += len(name) - 1
+
+		switch name {
+		case "d":
+			op.kind, op.text = patDate, DefaultDateLayout
+			if i+1 < len(layout) && layout[i+1] == '{' {
+				end := strings.IndexByte(layout[i+1:], '}')
+				if end < 0 {
+					return nil, fmt.Errorf("xlog: bad pattern layout %q: unclosed %%d{", layout)
+				}
+				op.text = layout[i+2 : i+1+end]
+				i += end + 1
+			}
+		case "p":
+			op.kind = patLevel
+		case "c":
+			op.kind = patLogger
+		case "caller":
+			op.kind = patCaller
+		case "F":
+			op.kind = patFile
+		case "L":
+			op.kind = patLine
+		case "m":
+			op.kind = patMessage
+		case "fields":
+			op.kind = patFields
+		case "n":
+			lit.WriteByte('\n')
+			continue
+		}
+		flush()
+		ops = append(ops, op)
+	}
+	flush()
+	return &patternEncoder{ops: ops}, nil
+}
+
+func (enc *patternEncoder) Encode(b *Builder, e Entry) error {
+	for i := range enc.ops {
+		op := &enc.ops[i]
+		start := b.Len()
+		switch op.kind {
+		case patLiteral:
+			b.WriteString(op.text)
+		case patDate:
+			b.buf = e.Time.AppendFormat(b.buf, op.text)
+		case patLevel:
+			b.WriteString(e.Level.CapitalString())
+		case patLogger:
+			b.WriteString(e.LoggerName)
+		case patCaller:
+			if e.Caller.Defined {
+				b.WriteString(callerFile(e.Caller.File, Lshortfile))
+				b.WriteByte(':')
+				b.AppendInt(int64(e.Caller.Line))
+			}
+		case patFile:
+			if e.Caller.Defined {
+				b.WriteString(callerFile(e.Caller.File, Lshortfile))
+			}
+		case patLine:
+			if e.Caller.Defined {
+				b.AppendInt(int64(e.Caller.Line))
+			}
+		case patMessage:
+			b.WriteString(e.Message)
+		case patFields:
+			if hasFields(e) {
+				b.WriteByte('{')
+				appendFields(b, e)
+				b.WriteByte('}')
+			}
+		}
+		if n := b.Len() - start; n < op.width {
+			b.pad(start, op.width-n, op.left)
+		}
+	}
+	return nil
+}
+
+// pad pads the bytes written since offset start with n spaces,
+// after them if left is true, before them otherwise.
+func (b *Builder) pad(start, n int, left bool) {
+	for i := 0; i < n; i++ {
+		b.buf = append(b.buf, ' ')
+	}
+	if left {
+		return
+	}
+	end := len(b.buf) - n
+	copy(b.buf[start+n:], b.buf[start:end])
+	for i := start; i < start+n; i++ {
+		b.buf[i] = ' '
+	}
+}
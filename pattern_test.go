@@ -0,0 +1,50 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPatternEncoder(t *testing.T) {
+	e := Entry{
+		Level:      WarnLevel,
+		Time:       time.Date(2019, 4, 1, 12, 30, 45, 123456789, time.UTC),
+		LoggerName: "svc.db",
+		Caller:     NewEntryCaller(0, "/src/app/db.go", 42, true),
+		Message:    "slow query",
+		Fields:     []Field{F("ms", 250)},
+	}
+	tests := []struct {
+		layout string
+		e      Entry
+		want   string
+	}{
+		{"%d{2006-01-02} %-5p %c %caller - %m %fields%n", e,
+			"2019-04-01 WARN  svc.db db.go:42 - slow query {\"ms\":250}\n"},
+		{"%d [%5p] %F(%L) %m%%%n", e,
+			"2019-04-01 12:30:45.123 [ WARN] db.go(42) slow query%\n"},
+		{"%-8c|%m %fields", Entry{Level: InfoLevel, LoggerName: "a", Message: "m"},
+			"a       |m "},
+	}
+	for _, tt := range tests {
+		enc, err := NewPatternEncoder(tt.layout)
+		if err != nil {
+			t.Fatalf("NewPatternEncoder(%q) error = %v", tt.layout, err)
+		}
+		var b Builder
+		enc.Encode(&b, tt.e)
+		if b.String() != tt.want {
+			t.Errorf("Encode(%q) = %q, want %q", tt.layout, b.String(), tt.want)
+		}
+	}
+
+	for _, layout := range []string{"%x", "%d{2006"} {
+		if _, err := NewPatternEncoder(layout); err == nil {
+			t.Errorf("NewPatternEncoder(%q) error = nil", layout)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"math"
+	"time"
+)
+
+// protobuf wire types.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+)
+
+// field numbers of the Entry message, see entry.proto.
+const (
+	protoEntrySchema = iota + 1
+	protoEntryLevel
+	protoEntryTime
+	protoEntryLogger
+	protoEntryCaller
+	protoEntryMsg
+	protoEntryFields
+	protoEntryStack
+	protoEntryIngestTime
+)
+
+// field numbers of the Frame message, see entry.proto.
+const (
+	protoFrameFunction = iota + 1
+	protoFrameFile
+	protoFrameLine
+)
+
+// field numbers of the Field message, see entry.proto.
+const (
+	protoFieldKey = iota + 1
+	protoFieldString
+	protoFieldBool
+	protoFieldInt
+	protoFieldUint
+	protoFieldDouble
+	protoFieldBytes
+	protoFieldTime
+	protoFieldJSON
+)
+
+type protoEncoder int
+
+// NewProtoEncoder returns an encoder writing each entry as a length-delimited
+// protobuf Entry message, whose schema is entry.proto in the source of this
+// package. It's a stable, compact and versioned format to forward entries
+// between services; the messages are written without reflection.
+//
+// Field values of types without a counterpart in the schema are written
+// as their JSON encoding. Only the Llongfile and Lshortfile flags apply.
+func NewProtoEncoder(flags int) Encoder { return protoEncoder(flags) }
+
+func (enc protoEncoder) Encode(b *Builder, e Entry) error {
+	e = nestEntry(e)
+	flags := int(enc)
+	m := getBuilder()
+	defer putBuilder(m)
+
+	m.appendProtoVarint(protoEntrySchema, SchemaVersion)
+	if e.Level != 0 {
+		m.appendProtoVarint(protoEntryLevel, zigzag(int64(e.Level)))
+	}
+	if !e.Time.IsZero() {
+		m.appendProtoFixed64(protoEntryTime, uint64(e.Time.UnixNano()))
+	}
+	if !e.IngestTime.IsZero() {
+		m.appendProtoFixed64(protoEntryIngestTime, uint64(e.IngestTime.UnixNano()))
+	}
+	if e.LoggerName != "" {
+		m.appendProtoString(protoEntryLogger, e.LoggerName)
+	}
+	if flags&(Llongfile|Lshortfile) != 0 && e.Caller.Defined {
+		m.appendProtoTag(protoEntryCaller, protoBytes)
+		file := callerFile(e.Caller.File, flags)
+		line := intLen(int64(e.Caller.Line))
+		m.appendUvarint(uint64(len(file) + 1 + line))
+		m.WriteString(file)
+		m.WriteByte(':')
+		m.AppendInt(int64(e.Caller.Line))
+	}
+	if e.Message != "" {
+		m.appendProtoString(protoEntryMsg, e.Message)
+	}
+
+	var err error
+	f := getBuilder()
+	defer putBuilder(f)
+	for _, fs := range [2][]Field{e.Ctx, e.Fields} {
+		for _, field := range fs {
+			f.Reset()
+			if ferr := f.appendProtoField(field); ferr != nil && err == nil {
+				err = ferr
+			}
+			m.appendProtoTag(protoEntryFields, protoBytes)
+			m.appendUvarint(uint64(f.Len()))
+			m.Write(f.Bytes())
+		}
+	}
+	for _, frame := range e.Stack {
+		f.Reset()
+		f.appendProtoString(protoFrameFunction, frame.Function)
+		f.appendProtoString(protoFrameFile, frame.File)
+		if frame.Line != 0 {
+			f.appendProtoVarint(protoFrameLine, uint64(frame.Line))
+		}
+		m.appendProtoTag(protoEntryStack, protoBytes)
+		m.appendUvarint(uint64(f.Len()))
+		m.Write(f.Bytes())
+	}
+
+	b.appendUvarint(uint64(m.Len()))
+	b.Write(m.Bytes())
+	return err
+}
+
+// appendProtoField appends the content of the Field message of f.
+func (b *Builder) appendProtoField(f Field) error {
+	b.appendProtoString(protoFieldKey, f.Key)
+	switch v := f.Value().(type) {
+	case nil:
+		// no value
+	case string:
+		b.appendProtoString(protoFieldString, v)
+	case []byte:
+		b.appendProtoTag(protoFieldBytes, protoBytes)
+		b.appendUvarint(uint64(len(v)))
+		b.Write(v)
+	case bool:
+		var u uint64
+		if v {
+			u = 1
+		}
+		b.appendProtoVarint(protoFieldBool, u)
+	case int:
+		b.appendProtoVarint(protoFieldInt, zigzag(int64(v)))
+	case int8:
+		b.appendProtoVarint(protoFieldInt, zigzag(int64(v)))
+	case int16:
+		b.appendProtoVarint(protoFieldInt, zigzag(int64(v)))
+	case int32:
+		b.appendProtoVarint(protoFieldInt, zigzag(int64(v)))
+	case int64:
+		b.appendProtoVarint(protoFieldInt, zigzag(v))
+	case uint:
+		b.appendProtoVarint(protoFieldUint, uint64(v))
+	case uint8:
+		b.appendProtoVarint(protoFieldUint, uint64(v))
+	case uint16:
+		b.appendProtoVarint(protoFieldUint, uint64(v))
+	case uint32:
+		b.appendProtoVarint(protoFieldUint, uint64(v))
+	case uint64:
+		b.appendProtoVarint(protoFieldUint, v)
+	case float32:
+		b.appendProtoFixed64(protoFieldDouble, math.Float64bits(float64(v)))
+	case float64:
+		b.appendProtoFixed64(protoFieldDouble, math.Float64bits(v))
+	case time.Time:
+		b.appendProtoFixed64(protoFieldTime, uint64(v.UnixNano()))
+	default:
+		jb := getBuilder()
+		defer putBuilder(jb)
+		err := jb.AppendJSON(v)
+		b.appendProtoTag(protoFieldJSON, protoBytes)
+		b.appendUvarint(uint64(jb.Len()))
+		b.Write(jb.Bytes())
+		return err
+	}
+	return nil
+}
+
+func (b *Builder) appendProtoTag(num int, wireType int) {
+	b.appendUvarint(uint64(num<<3 | wireType))
+}
+
+func (b *Builder) appendProtoVarint(num int, v uint64) {
+	b.appendProtoTag(num, protoVarint)
+	b.appendUvarint(v)
+}
+
+func (b *Builder) appendProtoFixed64(num int, v uint64) {
+	b.appendProtoTag(num, protoFixed64)
+	b.buf = append(b.buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24),
+		byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56))
+}
+
+func (b *Builder) appendProtoString(num int, s string) {
+	b.appendProtoTag(num, protoBytes)
+	b.appendUvarint(uint64(len(s)))
+	b.WriteString(s)
+}
+
+func (b *Builder) appendUvarint(v uint64) {
+	for v >= 0x80 {
+		b.buf = append(b.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	b.buf = append(b.buf, byte(v))
+}
+
+// zigzag maps signed integers to unsigned ones of the same magnitude,
+// the encoding of sint32 and sint64.
+func zigzag(i int64) uint64 {
+	return uint64(i<<1) ^ uint64(i>>63)
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type protoValue struct {
+	num  int
+	wire int
+	u    uint64 // varint and fixed64
+	b    []byte // length-delimited
+}
+
+// decodeProto is a minimal protobuf decoder of a message content.
+func decodeProto(t *testing.T, p []byte) []protoValue {
+	t.Helper()
+	var vs []protoValue
+	for len(p) > 0 {
+		tag, n := binary.Uvarint(p)
+		p = p[n:]
+		v := protoValue{num: int(tag >> 3), wire: int(tag & 7)}
+		switch v.wire {
+		case protoVarint:
+			v.u, n = binary.Uvarint(p)
+			p = p[n:]
+		case protoFixed64:
+			v.u, p = binary.LittleEndian.Uint64(p), p[8:]
+		case protoBytes:
+			l, n := binary.Uvarint(p)
+			v.b, p = p[n:n+int(l)], p[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", v.wire)
+		}
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+func unzigzag(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func TestProtoEncoder(t *testing.T) {
+	ts := time.Date(2019, 5, 1, 8, 0, 0, 123, time.UTC)
+	e := Entry{
+		Level:      DebugLevel,
+		Time:       ts,
+		IngestTime: ts.Add(time.Hour),
+		LoggerName: "svc",
+		Message:    "hello",
+		Caller:     NewEntryCaller(0, "/src/main.go", 12, true),
+		Stack:      Frames{{"main.run", "/src/main.go", 12}, {"main.main", "/src/main.go", 5}},
+		Ctx:        []Field{F("s", "x")},
+		Fields: []Field{F("i", -3), F("u", uint8(7)), F("f", 1.5), F("b", true),
+			F("raw", []byte{1, 2}), F("t", ts), F("o", O{F("k", 1)}), F("nil", nil)},
+	}
+	b := getBuilder()
+	defer putBuilder(b)
+	if err := NewProtoEncoder(Lshortfile).Encode(b, e); err != nil {
+		t.Fatal(err)
+	}
+	n, l := binary.Uvarint(b.Bytes())
+	if int(n)+l != b.Len() {
+		t.Fatalf("length prefix %d, message of %d bytes", n, b.Len()-l)
+	}
+
+	got := make(map[string]interface{})
+	for _, v := range decodeProto(t, b.Bytes()[l:]) {
+		switch v.num {
+		case protoEntrySchema:
+			got["schema"] = v.u
+		case protoEntryLevel:
+			got["level"] = unzigzag(v.u)
+		case protoEntryTime:
+			got["time"] = int64(v.u)
+		case protoEntryIngestTime:
+			got["ingest_time"] = int64(v.u)
+		case protoEntryLogger, protoEntryCaller, protoEntryMsg:
+			got[[]string{"", "", "", "", "logger", "caller", "msg"}[v.num]] = string(v.b)
+		case protoEntryStack:
+			var f Frame
+			for _, x := range decodeProto(t, v.b) {
+				switch x.num {
+				case protoFrameFunction:
+					f.Function = string(x.b)
+				case protoFrameFile:
+					f.File = string(x.b)
+				case protoFrameLine:
+					f.Line = int(x.u)
+				}
+			}
+			stack, _ := got["stack"].(Frames)
+			got["stack"] = append(stack, f)
+		case protoEntryFields:
+			fv := decodeProto(t, v.b)
+			key := string(fv[0].b)
+			if len(fv) == 1 {
+				got[key] = nil
+				continue
+			}
+			switch x := fv[1]; x.num {
+			case protoFieldString, protoFieldJSON:
+				got[key] = string(x.b)
+			case protoFieldBytes:
+				got[key] = x.b
+			case protoFieldInt:
+				got[key] = unzigzag(x.u)
+			case protoFieldDouble:
+				got[key] = math.Float64frombits(x.u)
+			case protoFieldTime:
+				got[key] = int64(x.u)
+			default: // bool, uint
+				got[key] = x.u
+			}
+		}
+	}
+
+	want := map[string]interface{}{
+		"schema": uint64(SchemaVersion), "level": int64(-1), "time": ts.UnixNano(), "ingest_time": ts.Add(time.Hour).UnixNano(),
+		"logger": "svc", "caller": "main.go:12", "msg": "hello", "stack": e.Stack,
+		"s": "x", "i": int64(-3), "u": uint64(7), "f": 1.5, "b": uint64(1),
+		"raw": []byte{1, 2}, "t": ts.UnixNano(), "o": `{"k":1}`, "nil": nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewProtoEncoder().Encode() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import "strconv"
+
+// QuorumError is returned by the Write method of a quorum tee when fewer
+// cores than its quorum wrote the entry.
+type QuorumError struct {
+	Acks     int   // the number of cores which wrote the entry
+	Quorum   int   // the number of cores required
+	Total    int   // the number of cores
+	FellBack bool  // whether the fallback core wrote the entry
+	Err      error // the errors of the cores, and of the fallback
+}
+
+func (e *QuorumError) Error() string {
+	b := getBuilder()
+	b.WriteString("xlog: quorum not reached (")
+	b.WriteString(strconv.Itoa(e.Acks))
+	b.WriteByte('/')
+	b.WriteString(strconv.Itoa(e.Quorum))
+	b.WriteString(" of ")
+	b.WriteString(strconv.Itoa(e.Total))
+	b.WriteString(" cores)")
+	if e.FellBack {
+		b.WriteString(", written to the fallback")
+	}
+	if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+	s := string(b.Bytes())
+	putBuilder(b)
+	return s
+}
+
+// Unwrap returns the errors of the cores.
+func (e *QuorumError) Unwrap() error {
+	return e.Err
+}
+
+type quorumCore struct {
+	*multiCore
+	quorum   int
+	fallback Core
+}
+
+// NewQuorumTee creates a Core that duplicates log entries into cores, as
+// NewTee does, for critical trails, such as audit logs, written to several
+// destinations: Write succeeds if at least quorum of the cores wrote the
+// entry, ignoring the errors of the others. Otherwise, the entry is
+// written to fallback, if not nil, and Write returns a *QuorumError.
+//
+// A quorum <= 0, or greater than the number of cores, requires all of
+// them. As with NewTee, each entry is written to every core, which should
+// enable the same levels.
+func NewQuorumTee(quorum int, fallback Core, cores ...Core) Core {
+	if quorum <= 0 || quorum > len(cores) {
+		quorum = len(cores)
+	}
+	all := make([]Core, len(cores))
+	copy(all, cores)
+	return &quorumCore{newMultiCore(all), quorum, fallback}
+}
+
+func (qc *quorumCore) Write(e Entry) error {
+	var err error
+	acks := 0
+	for _, c := range qc.cores {
+		if cerr := c.Write(e); cerr != nil {
+			err = combineErrors(err, cerr)
+		} else {
+			acks++
+		}
+	}
+	if acks >= qc.quorum {
+		return nil
+	}
+
+	qerr := &QuorumError{Acks: acks, Quorum: qc.quorum, Total: len(qc.cores), Err: err}
+	if qc.fallback != nil {
+		if ferr := qc.fallback.Write(e); ferr != nil {
+			qerr.Err = combineErrors(qerr.Err, ferr)
+		} else {
+			qerr.FellBack = true
+		}
+	}
+	return qerr
+}
+
+func (qc *quorumCore) Sync() error {
+	err := qc.multiCore.Sync()
+	if qc.fallback != nil {
+		err = combineErrors(err, qc.fallback.Sync())
+	}
+	return err
+}
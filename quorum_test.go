@@ -0,0 +1,39 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewQuorumTee(t *testing.T) {
+	a, b, c, fallback := &fullDiskCore{}, &fullDiskCore{}, &fullDiskCore{}, &fullDiskCore{}
+	core := NewQuorumTee(2, fallback, a, b, c)
+
+	c.full = true
+	if err := core.Write(Entry{Message: "quorum"}); err != nil {
+		t.Errorf("Write() with 2 of 3 acks error = %v, want nil", err)
+	}
+
+	b.full = true
+	err := core.Write(Entry{Message: "minority"})
+	var qerr *QuorumError
+	if !errors.As(err, &qerr) || qerr.Acks != 1 || qerr.Quorum != 2 || qerr.Total != 3 || !qerr.FellBack {
+		t.Fatalf("Write() with 1 of 3 acks error = %#v, want a *QuorumError written to the fallback", err)
+	}
+	if !strings.HasPrefix(err.Error(), "xlog: quorum not reached (1/2 of 3 cores), written to the fallback: ") {
+		t.Errorf("Error() = %s, want the errors of the cores", err)
+	}
+	if len(fallback.msgs) != 1 || fallback.msgs[0] != "minority" {
+		t.Errorf("fallback messages = %q, want the entry missing its quorum", fallback.msgs)
+	}
+
+	// all by default
+	if err := NewQuorumTee(0, nil, a, b).Write(Entry{}); err == nil {
+		t.Errorf("Write() with 1 of 2 acks and the default quorum succeeded")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Quota configures the rates enforced by a QuotaCore for each key.
+type Quota struct {
+	// Key is the field key whose value identifies the tenant of an entry,
+	// e.g. "tenant". Entries without it, or all entries if Key is empty,
+	// are accounted to their logger name.
+	Key string
+	// BytesPerSec limits the estimated encoded size of the entries of a key,
+	// see EstimateSize. Zero means unlimited.
+	BytesPerSec int
+	// EntriesPerSec limits the number of entries of a key. Zero means unlimited.
+	EntriesPerSec int
+	// Burst is the time over which a key may save up its budget,
+	// a second if zero.
+	Burst time.Duration
+	// Clock provides the time to refill budgets, the system clock if nil.
+	Clock Clock
+}
+
+// the number of keys above which the keys having their full budget and
+// no drops are forgotten.
+const _quotaPruneKeys = 4096
+
+// quotaBucket is the budget of a key.
+type quotaBucket struct {
+	bytes, entries float64
+	last           time.Time
+	drops          uint64
+}
+
+// QuotaCore is a Core that enforces per-key byte and entry rates, so one
+// noisy tenant can't starve the others in a shared pipeline.
+// Each key has its own budget, refilled continuously; an entry exceeding
+// the budget of its key is dropped and counted, without affecting the
+// other keys.
+type QuotaCore struct {
+	Core
+	quota   Quota
+	mu      sync.Mutex
+	buckets map[string]*quotaBucket
+	dropped uint64 // the total of the drops, guarded by mu
+	drops   dropCounter
+}
+
+// NewQuotaCore creates a QuotaCore writing the entries within quota to core.
+func NewQuotaCore(core Core, quota Quota) *QuotaCore {
+	if quota.Burst <= 0 {
+		quota.Burst = time.Second
+	}
+	return &QuotaCore{
+		Core:    core,
+		quota:   quota,
+		buckets: make(map[string]*quotaBucket),
+	}
+}
+
+// Write writes e to the underlying core, unless its key is over quota.
+func (c *QuotaCore) Write(e Entry) error {
+	if !c.allow(c.key(e), e) {
+		return nil
+	}
+	return c.Core.Write(e)
+}
+
+// Sync syncs the underlying core, and returns a *DroppedError if entries
+// were dropped since the previous Sync.
+func (c *QuotaCore) Sync() error {
+	err := c.Core.Sync()
+	c.mu.Lock()
+	dropped := c.dropped
+	c.mu.Unlock()
+	return combineErrors(err, c.drops.since("quota", dropped))
+}
+
+// Drops returns the number of entries dropped for each key.
+func (c *QuotaCore) Drops() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	drops := make(map[string]uint64)
+	for k, b := range c.buckets {
+		if b.drops > 0 {
+			drops[k] = b.drops
+		}
+	}
+	return drops
+}
+
+func (c *QuotaCore) key(e Entry) string {
+	if c.quota.Key != "" {
+		if f, ok := lookupField(e, c.quota.Key); ok {
+			if s, ok := f.stringValue(); ok {
+				return s
+			}
+			return fmt.Sprint(f.Value())
+		}
+	}
+	return e.LoggerName
+}
+
+func (c *QuotaCore) allow(key string, e Entry) bool {
+	q := &c.quota
+	if q.BytesPerSec <= 0 && q.EntriesPerSec <= 0 {
+		return true
+	}
+	size := 0
+	if q.BytesPerSec > 0 {
+		size = EstimateSize(e)
+	}
+	now := time.Now()
+	if q.Clock != nil {
+		now = q.Clock.Now()
+	}
+	burst := q.Burst.Seconds()
+	maxBytes := float64(q.BytesPerSec) * burst
+	maxEntries := float64(q.EntriesPerSec) * burst
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.buckets[key]
+	if b == nil {
+		if len(c.buckets) >= _quotaPruneKeys {
+			c.prune(now)
+		}
+		b = &quotaBucket{bytes: maxBytes, entries: maxEntries, last: now}
+		c.buckets[key] = b
+	}
+
+	// refill
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.bytes = minFloat(maxBytes, b.bytes+elapsed*float64(q.BytesPerSec))
+		b.entries = minFloat(maxEntries, b.entries+elapsed*float64(q.EntriesPerSec))
+		b.last = now
+	}
+
+	if q.BytesPerSec > 0 && b.bytes < float64(size) ||
+		q.EntriesPerSec > 0 && b.entries < 1 {
+		b.drops++
+		c.dropped++
+		return false
+	}
+	b.bytes -= float64(size)
+	b.entries--
+	return true
+}
+
+// prune forgets the keys which have refilled their budget and never
+// dropped, they are equivalent to new keys.
+func (c *QuotaCore) prune(now time.Time) {
+	full := c.quota.Burst.Seconds()
+	for k, b := range c.buckets {
+		if b.drops == 0 && now.Sub(b.last).Seconds() >= full {
+			delete(c.buckets, k)
+		}
+	}
+}
+
+func minFloat(x, y float64) float64 {
+	if x < y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type manualClock struct{ t time.Time }
+
+func (c *manualClock) Now() time.Time { return c.t }
+
+func TestQuotaCore(t *testing.T) {
+	var buf bytes.Buffer
+	clock := &manualClock{time.Unix(1000, 0)}
+	core := NewQuotaCore(NewCore(NewJSONEncoder(0), &buf, DebugLevel),
+		Quota{Key: "tenant", EntriesPerSec: 3, Clock: clock})
+	log := New(core)
+
+	for i := 0; i < 10; i++ {
+		log.Info("noisy", F("tenant", "a"))
+	}
+	log.Info("quiet", F("tenant", "b"))
+	log.Info("untagged")
+
+	if n := bytes.Count(buf.Bytes(), []byte(`"msg":"noisy"`)); n != 3 {
+		t.Errorf("noisy entries = %d, want 3", n)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"quiet"`)) || !bytes.Contains(buf.Bytes(), []byte(`"untagged"`)) {
+		t.Errorf("Out = %s, other tenants starved", buf.Bytes())
+	}
+	drops := core.Drops()
+	if len(drops) != 1 || drops["a"] != 7 {
+		t.Errorf("Drops() = %v, want map[a:7]", drops)
+	}
+
+	clock.t = clock.t.Add(time.Second / 2)
+	buf.Reset()
+	log.Info("noisy", F("tenant", "a"))
+	log.Info("noisy", F("tenant", "a"))
+	if n := bytes.Count(buf.Bytes(), []byte(`"msg":"noisy"`)); n != 1 {
+		t.Errorf("noisy entries after refill = %d, want 1", n)
+	}
+}
+
+func TestQuotaCore_Bytes(t *testing.T) {
+	var buf bytes.Buffer
+	core := NewQuotaCore(NewCore(NewJSONEncoder(0), &buf, DebugLevel),
+		Quota{BytesPerSec: 300, Clock: &manualClock{time.Unix(1000, 0)}})
+	log := New(core, Named("svc"))
+	for i := 0; i < 10; i++ {
+		log.Info("entry", F("payload", "0123456789"))
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("\n")); n != 2 {
+		t.Errorf("entries = %d, want 2", n)
+	}
+	if core.Drops()["svc"] != 8 {
+		t.Errorf("Drops() = %v", core.Drops())
+	}
+}
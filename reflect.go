@@ -0,0 +1,270 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ReflectLimits bounds the values that Builder.AppendJSON encodes by
+// reflection. A value exceeding a limit is replaced by a string marker
+// describing the violation, instead of being serialized.
+// A zero limit means unlimited.
+type ReflectLimits struct {
+	MaxDepth    int // maximum nesting of structs, maps, arrays and slices
+	MaxElements int // maximum total number of map, array and slice elements
+	MaxBytes    int // maximum encoded size in bytes
+}
+
+var reflectLimits atomic.Value // holds ReflectLimits
+
+func init() {
+	reflectLimits.Store(ReflectLimits{})
+}
+
+// SetReflectLimits sets the limits of the reflection fallback of
+// Builder.AppendJSON. It's safe for concurrent use.
+func SetReflectLimits(limits ReflectLimits) {
+	reflectLimits.Store(limits)
+}
+
+// GetReflectLimits returns the current limits of the reflection fallback.
+func GetReflectLimits() ReflectLimits {
+	return reflectLimits.Load().(ReflectLimits)
+}
+
+// the depth at which walking stops when MaxDepth is unlimited,
+// the same as encoding/json uses to detect cycles.
+const _maxWalkDepth = 1000
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// appendReflect encodes v with encoding/json after checking it against the
+// ReflectLimits and for reference cycles. Values that can't be encoded
+// are replaced by a string marker, so the output remains valid JSON.
+func (b *Builder) appendReflect(v interface{}) error {
+	limits := GetReflectLimits()
+	w := reflectWalker{limits: limits}
+	w.path = w.pathBuf[:0]
+	w.walk(reflect.ValueOf(v), 0)
+	if w.violation != "" {
+		b.appendReflectMarker(v, w.violation)
+		return nil
+	}
+
+	len := b.Len()
+	b.prepareReflectEnc()
+	err := b.reflectEnc.Encode(v)
+	if err != nil {
+		b.buf = b.buf[:len]
+		b.AppendQuote("!xlog: " + err.Error())
+		return err
+	}
+
+	// ignore json.Encoder last '\n'
+	b.buf = b.buf[:b.Len()-1]
+
+	if limits.MaxBytes > 0 && b.Len()-len > limits.MaxBytes {
+		b.buf = b.buf[:len]
+		b.appendReflectMarker(v, "exceeds max bytes "+strconv.Itoa(limits.MaxBytes))
+	}
+	return nil
+}
+
+// appendMarshaler appends the output of m.MarshalJSON, compacted so it
+// can't break the line of an entry, with the HTML characters escaped
+// unless disabled by SetEscapeHTML, as encoding/json does.
+func (b *Builder) appendMarshaler(m json.Marshaler) error {
+	if isNilPointer(m) {
+		b.WriteString("null")
+		return nil
+	}
+	p, err := m.MarshalJSON()
+	if err != nil {
+		b.AppendQuote("!xlog: " + err.Error())
+		return err
+	}
+
+	start := b.Len()
+	dst := bytes.NewBuffer(b.buf)
+	if err = json.Compact(dst, p); err != nil {
+		b.AppendQuote("!xlog: " + reflect.TypeOf(m).String() + " MarshalJSON: " + err.Error())
+		return err
+	}
+	b.buf = dst.Bytes()
+
+	if !b.rawHTML && bytes.IndexAny(b.buf[start:], "<>&\u2028\u2029") >= 0 {
+		cb := getBuilder()
+		cb.Write(b.buf[start:])
+		dst = bytes.NewBuffer(b.buf[:start])
+		json.HTMLEscape(dst, cb.Bytes())
+		b.buf = dst.Bytes()
+		putBuilder(cb)
+	}
+
+	if max := GetReflectLimits().MaxBytes; max > 0 && b.Len()-start > max {
+		b.buf = b.buf[:start]
+		b.appendReflectMarker(m, "exceeds max bytes "+strconv.Itoa(max))
+	}
+	return nil
+}
+
+// appendTextMarshaler appends the output of m.MarshalText as a string.
+func (b *Builder) appendTextMarshaler(m encoding.TextMarshaler) error {
+	if isNilPointer(m) {
+		b.WriteString("null")
+		return nil
+	}
+	p, err := m.MarshalText()
+	if err != nil {
+		b.AppendQuote("!xlog: " + err.Error())
+		return err
+	}
+	// p is only read while escaping it
+	b.appendJSONString(*(*string)(unsafe.Pointer(&p)))
+	return nil
+}
+
+// isNilPointer reports whether v holds a nil pointer,
+// encoded as null without calling its methods.
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+func (b *Builder) appendReflectMarker(v interface{}, violation string) {
+	b.AppendQuote("!xlog: " + reflect.TypeOf(v).String() + " " + violation)
+}
+
+// reflectWalker checks a value against ReflectLimits and for reference cycles
+// without encoding it.
+type reflectWalker struct {
+	limits    ReflectLimits
+	elements  int
+	violation string
+	path      []walkedRef // references being walked, to detect cycles
+	pathBuf   [16]walkedRef
+}
+
+// walkedRef identifies a reference by its address and type, as a pointer to
+// the first field of a struct has the address of the struct.
+type walkedRef struct {
+	p uintptr
+	t reflect.Type
+}
+
+func (w *reflectWalker) walk(v reflect.Value, depth int) {
+	if w.violation != "" || !v.IsValid() {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			w.walk(v.Elem(), depth)
+		}
+		return
+	case reflect.Ptr:
+		if !v.IsNil() && w.enter(v) {
+			w.walk(v.Elem(), depth)
+			w.leave()
+		}
+		return
+	case reflect.Map, reflect.Slice:
+		if v.IsNil() || !w.enter(v) {
+			return
+		}
+		defer w.leave()
+	case reflect.Struct, reflect.Array:
+	default:
+		return
+	}
+
+	// values encoding themselves are opaque
+	if v.Type().Implements(marshalerType) {
+		return
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		return // base64 string
+	}
+
+	depth++
+	if w.limits.MaxDepth > 0 && depth > w.limits.MaxDepth {
+		w.violation = "exceeds max depth " + strconv.Itoa(w.limits.MaxDepth)
+		return
+	}
+	if depth > _maxWalkDepth {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" && !sf.Anonymous || sf.Tag.Get("json") == "-" {
+				continue // not encoded
+			}
+			w.walk(v.Field(i), depth)
+		}
+	case reflect.Map:
+		if !w.count(v.Len()) {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			w.walk(iter.Value(), depth)
+		}
+	default: // Slice, Array
+		if !w.count(v.Len()) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			w.walk(v.Index(i), depth)
+		}
+	}
+}
+
+func (w *reflectWalker) count(n int) bool {
+	w.elements += n
+	if w.limits.MaxElements > 0 && w.elements > w.limits.MaxElements {
+		w.violation = "exceeds max elements " + strconv.Itoa(w.limits.MaxElements)
+		return false
+	}
+	return true
+}
+
+// enter pushes the reference v on the walking path, it reports false if v
+// is already on the path, that is v refers to itself.
+func (w *reflectWalker) enter(v reflect.Value) bool {
+	ref := walkedRef{v.Pointer(), v.Type()}
+	for _, r := range w.path {
+		if r == ref {
+			w.violation = "contains a cycle"
+			return false
+		}
+	}
+	w.path = append(w.path, ref)
+	return true
+}
+
+func (w *reflectWalker) leave() {
+	w.path = w.path[:len(w.path)-1]
+}
+
+// typeName returns the name of the dynamic type of v, such as
+// "*errors.errorString".
+func typeName(v interface{}) string {
+	if v == nil {
+		return "nil"
+	}
+	return reflect.TypeOf(v).String()
+}
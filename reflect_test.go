@@ -0,0 +1,151 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuilder_AppendJSON_limits(t *testing.T) {
+	type node struct {
+		Name  string
+		Child *node
+	}
+	type bag struct {
+		Items []int
+	}
+
+	deep := &node{"a", &node{"b", &node{"c", nil}}}
+	tests := []struct {
+		label  string
+		limits ReflectLimits
+		input  interface{}
+		want   string
+	}{
+		{"unlimited", ReflectLimits{}, deep, `{"Name":"a","Child":{"Name":"b","Child":{"Name":"c","Child":null}}}`},
+		{"depth ok", ReflectLimits{MaxDepth: 3}, deep, `{"Name":"a","Child":{"Name":"b","Child":{"Name":"c","Child":null}}}`},
+		{"depth", ReflectLimits{MaxDepth: 2}, deep, `"!xlog: *xlog.node exceeds max depth 2"`},
+		{"elements ok", ReflectLimits{MaxElements: 3}, bag{[]int{1, 2, 3}}, `{"Items":[1,2,3]}`},
+		{"elements", ReflectLimits{MaxElements: 2}, bag{[]int{1, 2, 3}}, `"!xlog: xlog.bag exceeds max elements 2"`},
+		{"elements map", ReflectLimits{MaxElements: 1}, map[string]bag{"a": {[]int{1}}}, `"!xlog: map[string]xlog.bag exceeds max elements 1"`},
+		{"bytes", ReflectLimits{MaxBytes: 10}, bag{[]int{1, 2, 3}}, `"!xlog: xlog.bag exceeds max bytes 10"`},
+	}
+	defer SetReflectLimits(ReflectLimits{})
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			SetReflectLimits(tt.limits)
+			var b Builder
+			if err := b.AppendJSON(tt.input); err != nil {
+				t.Errorf("Builder.AppendJSON() error = %v", err)
+			} else if got := b.String(); got != tt.want {
+				t.Errorf("Builder.AppendJSON = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_AppendJSON_cycle(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	type graph struct {
+		Nodes map[string]interface{}
+	}
+
+	ring := &node{Name: "a"}
+	ring.Next = &node{Name: "b", Next: ring}
+
+	g := graph{Nodes: map[string]interface{}{}}
+	g.Nodes["self"] = g.Nodes
+
+	self := map[string]interface{}{}
+	self["self"] = self
+
+	shared := &node{Name: "shared"}
+
+	// a pointer to the first field has the address of the struct
+	type inner struct{ N int }
+	type outer struct {
+		In inner
+		P  *inner
+	}
+	first := &outer{In: inner{1}}
+	first.P = &first.In
+	tests := []struct {
+		label string
+		input interface{}
+		want  string
+	}{
+		{"pointer", ring, `"!xlog: *xlog.node contains a cycle"`},
+		{"map", g, `"!xlog: xlog.graph contains a cycle"`},
+		{"map itself", self, `{"self":"!xlog: map[string]interface {} contains a cycle"}`},
+		{"shared is not a cycle", []*node{shared, shared}, `[{"Name":"shared","Next":null},{"Name":"shared","Next":null}]`},
+		{"pointer to the first field", first, `{"In":{"N":1},"P":{"N":1}}`},
+		{"unsupported", make(chan int), `"!xlog: json: unsupported type: chan int"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			var b Builder
+			b.AppendJSON(tt.input)
+			if got := b.String(); got != tt.want {
+				t.Errorf("Builder.AppendJSON = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	want := `"ring":"!xlog: *xlog.node contains a cycle"`
+	if got := F("ring", ring).String(); got != want {
+		t.Errorf("Field.String() = %v, want %v", got, want)
+	}
+}
+
+type rawJSON string
+
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	if r == "" {
+		return nil, errors.New("empty")
+	}
+	return []byte(r), nil
+}
+
+type textID struct{ n int }
+
+func (id *textID) MarshalText() ([]byte, error) {
+	return []byte("id<" + string(rune('0'+id.n)) + ">"), nil
+}
+
+func TestBuilder_AppendJSON_marshalers(t *testing.T) {
+	tests := []struct {
+		label  string
+		input  interface{}
+		raw    bool
+		want   string
+		hasErr bool
+	}{
+		{"compacted", rawJSON("{\n  \"a\": [1, 2]\n}"), false, `{"a":[1,2]}`, false},
+		{"html", rawJSON(`{"a":"<b>"}`), false, `{"a":"\u003cb\u003e"}`, false},
+		{"raw html", rawJSON(`{"a":"<b>"}`), true, `{"a":"<b>"}`, false},
+		{"invalid", rawJSON(`{"a"`), false, `"!xlog: xlog.rawJSON MarshalJSON: unexpected end of JSON input"`, true},
+		{"error", rawJSON(""), false, `"!xlog: empty"`, true},
+		{"text", &textID{7}, false, `"id\u003c7\u003e"`, false},
+		{"text raw html", &textID{7}, true, `"id<7>"`, false},
+		{"nil text", (*textID)(nil), false, "null", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			var b Builder
+			b.SetEscapeHTML(!tt.raw)
+			err := b.AppendJSON(tt.input)
+			if (err != nil) != tt.hasErr {
+				t.Errorf("Builder.AppendJSON() error = %v, want error %v", err, tt.hasErr)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("Builder.AppendJSON = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
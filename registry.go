@@ -0,0 +1,92 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// EncoderConfig configures an encoder created by NewEncoderByName.
+type EncoderConfig struct {
+	// Flags of the encoder, e.g. Lshortfile.
+	Flags int
+	// Options of the console and JSON encoders.
+	Options []EncoderOption
+	// Params holds the settings specific to an encoder, e.g. "layout"
+	// for the pattern encoder or "host" for the GELF encoder.
+	Params map[string]string
+}
+
+// An EncoderFactory creates an encoder from its configuration.
+type EncoderFactory func(cfg EncoderConfig) (Encoder, error)
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]EncoderFactory{
+		"console": func(cfg EncoderConfig) (Encoder, error) {
+			return NewConsoleEncoder(cfg.Flags, cfg.Options...), nil
+		},
+		"json": func(cfg EncoderConfig) (Encoder, error) {
+			return NewJSONEncoder(cfg.Flags, cfg.Options...), nil
+		},
+		"cbor": func(cfg EncoderConfig) (Encoder, error) {
+			return NewCBOREncoder(cfg.Flags), nil
+		},
+		"proto": func(cfg EncoderConfig) (Encoder, error) {
+			return NewProtoEncoder(cfg.Flags), nil
+		},
+		"pattern": func(cfg EncoderConfig) (Encoder, error) {
+			layout := cfg.Params["layout"]
+			if layout == "" {
+				return nil, errors.New("xlog: the pattern encoder requires the layout param")
+			}
+			return NewPatternEncoder(layout)
+		},
+		"gelf": func(cfg EncoderConfig) (Encoder, error) {
+			host := cfg.Params["host"]
+			if host == "" {
+				host, _ = os.Hostname()
+			}
+			return NewGELFEncoder(host), nil
+		},
+	}
+)
+
+var errNoEncoderName = errors.New("xlog: no encoder name specified")
+
+// RegisterEncoder registers factory under name, so configurations can
+// create the encoder by name with NewEncoderByName. It's usually called
+// from the init function of the package providing the encoder. It fails
+// if name is already registered, which includes the built-in encoders
+// "console", "json", "cbor", "proto", "pattern" and "gelf".
+func RegisterEncoder(name string, factory EncoderFactory) error {
+	if name == "" {
+		return errNoEncoderName
+	}
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	if _, ok := encoders[name]; ok {
+		return fmt.Errorf("xlog: encoder already registered for name %q", name)
+	}
+	encoders[name] = factory
+	return nil
+}
+
+// NewEncoderByName creates the encoder registered under name.
+func NewEncoderByName(name string, cfg EncoderConfig) (Encoder, error) {
+	if name == "" {
+		return nil, errNoEncoderName
+	}
+	encodersMu.RLock()
+	factory, ok := encoders[name]
+	encodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("xlog: no encoder registered for name %q", name)
+	}
+	return factory(cfg)
+}
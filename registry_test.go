@@ -0,0 +1,76 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"strings"
+	"testing"
+)
+
+type upperEncoder struct{ prefix string }
+
+func (enc upperEncoder) Encode(b *Builder, e Entry) error {
+	b.WriteString(enc.prefix + strings.ToUpper(e.Message) + "\n")
+	return nil
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	err := RegisterEncoder("test-upper", func(cfg EncoderConfig) (Encoder, error) {
+		return upperEncoder{cfg.Params["prefix"]}, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterEncoder() error = %v", err)
+	}
+	if err = RegisterEncoder("test-upper", nil); err == nil {
+		t.Errorf("RegisterEncoder() of a registered name succeeded")
+	}
+	if err = RegisterEncoder("json", nil); err == nil {
+		t.Errorf("RegisterEncoder() of a built-in name succeeded")
+	}
+
+	enc, err := NewEncoderByName("test-upper", EncoderConfig{Params: map[string]string{"prefix": "> "}})
+	if err != nil {
+		t.Fatalf("NewEncoderByName() error = %v", err)
+	}
+	b := getBuilder()
+	defer putBuilder(b)
+	enc.Encode(b, Entry{Message: "hi"})
+	if got := b.String(); got != "> HI\n" {
+		t.Errorf("Encode() = %q", got)
+	}
+}
+
+func TestNewEncoderByName(t *testing.T) {
+	for _, name := range []string{"console", "json", "cbor", "proto", "gelf"} {
+		if enc, err := NewEncoderByName(name, EncoderConfig{}); enc == nil || err != nil {
+			t.Errorf("NewEncoderByName(%q) = %v, %v", name, enc, err)
+		}
+	}
+
+	enc, err := NewEncoderByName("json", EncoderConfig{Options: []EncoderOption{RenameKeys(EncoderKeys{Message: "message"})}})
+	b := getBuilder()
+	defer putBuilder(b)
+	enc.Encode(b, Entry{Message: "m"})
+	if err != nil || !strings.Contains(b.String(), `"message":"m"`) {
+		t.Errorf("NewEncoderByName(json) = %s, %v", b.String(), err)
+	}
+
+	b.Reset()
+	enc, err = NewEncoderByName("pattern", EncoderConfig{Params: map[string]string{"layout": "%p %m"}})
+	enc.Encode(b, Entry{Level: WarnLevel, Message: "m"})
+	if err != nil || b.String() != "WARN m" {
+		t.Errorf("NewEncoderByName(pattern) = %q, %v", b.String(), err)
+	}
+
+	if _, err = NewEncoderByName("pattern", EncoderConfig{}); err == nil {
+		t.Errorf("NewEncoderByName(pattern) without layout succeeded")
+	}
+	if _, err = NewEncoderByName("nope", EncoderConfig{}); err == nil {
+		t.Errorf("NewEncoderByName(nope) succeeded")
+	}
+	if _, err = NewEncoderByName("", EncoderConfig{}); err == nil {
+		t.Errorf("NewEncoderByName(\"\") succeeded")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Replay reads the entries written by the JSON encoder from r and writes
+// them into core, which is useful to load-test sinks with captured traffic.
+//
+// With a positive speed the original pace of the entries is reproduced,
+// accelerated by speed (2 replays twice as fast); otherwise entries are
+// written as fast as possible. Entries whose level is disabled by core
+// are skipped.
+//
+// Replay stops at the end of r, on the first decode or write error, or when
+// ctx is done. It returns the number of entries written.
+func Replay(ctx context.Context, r io.Reader, core Core, speed float64) (n int, err error) {
+	dec := NewDecoder(r)
+
+	var first time.Time // time of the first entry
+	var start time.Time // time the first entry was replayed
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		var e Entry
+		if err = dec.Decode(&e); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return
+		}
+
+		if speed > 0 {
+			if first.IsZero() {
+				first, start = e.Time, time.Now()
+			} else {
+				due := start.Add(time.Duration(float64(e.Time.Sub(first)) / speed))
+				if wait := time.Until(due); wait > 0 {
+					if timer == nil {
+						timer = time.NewTimer(wait)
+					} else {
+						timer.Reset(wait)
+					}
+					select {
+					case <-ctx.Done():
+						return n, ctx.Err()
+					case <-timer.C:
+					}
+				}
+			}
+		}
+
+		if !core.Enabled(e.Level) {
+			continue
+		}
+		if err = core.Write(e); err != nil {
+			return
+		}
+		n++
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	var captured bytes.Buffer
+	core := NewCore(NewJSONEncoder(0), &captured, DebugLevel)
+	base := time.Date(2019, 1, 18, 12, 0, 0, 0, time.UTC)
+	for i, lvl := range []Level{DebugLevel, InfoLevel, ErrorLevel} {
+		core.Write(Entry{Level: lvl, Time: base.Add(time.Duration(i) * 20 * time.Millisecond), Message: "m"})
+	}
+	data := captured.Bytes()
+
+	var out bytes.Buffer
+	start := time.Now()
+	n, err := Replay(context.Background(), bytes.NewReader(data), NewCore(NewJSONEncoder(0), &out, InfoLevel), 1)
+	if err != nil || n != 2 {
+		t.Fatalf("Replay() = %d, %v, want 2, nil", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Replay() took %v, want original pace of 40ms", elapsed)
+	}
+	if !bytes.Equal(out.Bytes(), bytes.SplitAfterN(data, []byte("\n"), 2)[1]) {
+		t.Errorf("Replay() Out = \n%s", out.Bytes())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Replay(ctx, bytes.NewReader(data), NewNopCore(), 0); err != context.Canceled {
+		t.Errorf("Replay() error = %v, want %v", err, context.Canceled)
+	}
+}
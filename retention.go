@@ -0,0 +1,96 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetainKey is the key of the retention hint of entries.
+//
+// The hint tells the log storage how long to keep an entry, e.g.
+// retain="30d" for an audit trail and retain="3d" for debugging output,
+// so that it can tier entries by class. Written as a plain string field,
+// it's carried through every encoder.
+const RetainKey = "retain"
+
+// Retain adds the retention hint d to the Logger's preset fields,
+// replacing any previous one.
+//
+//	audit := log.With(xlog.Named("audit"), xlog.Retain(30*24*time.Hour))
+func Retain(d time.Duration) Option {
+	return optionFunc(func(log *Logger) {
+		val := FormatRetention(d)
+		for i := range log.ctx {
+			if log.ctx[i].Key == RetainKey {
+				log.ctx[i] = Field{Key: RetainKey, Val: val}
+				return
+			}
+		}
+		log.ctx = append(log.ctx, Field{Key: RetainKey, Val: val})
+	})
+}
+
+// RetainFor returns the retention hint d as a log-site field,
+// which overrides the hint of the Logger.
+func RetainFor(d time.Duration) Field {
+	return Field{Key: RetainKey, Val: FormatRetention(d)}
+}
+
+// Retention returns the retention hint of e, if any. It accepts the
+// hints of decoded entries too.
+func Retention(e Entry) (time.Duration, bool) {
+	f, ok := lookupField(e, RetainKey)
+	if !ok {
+		return 0, false
+	}
+	var s string
+	switch v := f.Value().(type) {
+	case string:
+		s = v
+	case json.RawMessage:
+		if json.Unmarshal(v, &s) != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	d, err := ParseRetention(s)
+	return d, err == nil
+}
+
+// FormatRetention formats d in the largest whole unit of days, hours or
+// minutes, e.g. "30d", "12h" or "90m"; as time.Duration does otherwise.
+func FormatRetention(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d == 0:
+		return "0d"
+	case d%day == 0:
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	}
+	return d.String()
+}
+
+// ParseRetention parses a retention hint: a number of days such as "30d",
+// or a duration accepted by time.ParseDuration.
+func ParseRetention(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+		if err != nil {
+			return 0, errors.New("xlog: invalid retention " + strconv.Quote(s))
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFormatRetention(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0d"},
+		{30 * 24 * time.Hour, "30d"},
+		{36 * time.Hour, "36h"},
+		{90 * time.Minute, "90m"},
+		{1500 * time.Millisecond, "1.5s"},
+	}
+	for _, tt := range tests {
+		got := FormatRetention(tt.d)
+		if got != tt.want {
+			t.Errorf("FormatRetention(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+		if d, err := ParseRetention(got); err != nil || d != tt.d {
+			t.Errorf("ParseRetention(%q) = %v, %v, want %v", got, d, err, tt.d)
+		}
+	}
+	if _, err := ParseRetention("xd"); err == nil {
+		t.Errorf("ParseRetention(xd) succeeded")
+	}
+}
+
+func TestRetain(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, DebugLevel), Retain(3*24*time.Hour))
+	audit := log.With(Retain(30 * 24 * time.Hour))
+	audit.Info("transfer")
+	log.Debug("cache miss", RetainFor(time.Hour))
+
+	dec := NewDecoder(&buf)
+	for _, want := range []time.Duration{30 * 24 * time.Hour, time.Hour} {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if d, ok := Retention(e); !ok || d != want {
+			t.Errorf("Retention(%q) = %v, %v, want %v", e.Message, d, ok, want)
+		}
+	}
+	if len(audit.ctx) != 1 {
+		t.Errorf("Retain() appended instead of replacing: %v", audit.ctx)
+	}
+	if _, ok := Retention(Entry{}); ok {
+		t.Errorf("Retention() of an entry without hint")
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"runtime"
+	"time"
+)
+
+// RuntimeStats returns the field "runtime" holding a snapshot of the
+// runtime statistics, for slow-path warnings and periodic health logs:
+//
+//	"runtime":{"goroutines":12,"heap_inuse":4194304,"heap_objects":15230,
+//		"num_gc":7,"gc_pause_last":"85µs","gc_pause_total":"410µs"}
+//
+// It calls runtime.ReadMemStats, which stops the world briefly,
+// so it doesn't belong on hot paths.
+func RuntimeStats() Field {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	var last time.Duration
+	if ms.NumGC > 0 {
+		last = time.Duration(ms.PauseNs[(ms.NumGC+255)%256])
+	}
+	return Field{Key: "runtime", Val: O{
+		F("goroutines", runtime.NumGoroutine()),
+		F("heap_inuse", ms.HeapInuse),
+		F("heap_objects", ms.HeapObjects),
+		F("num_gc", ms.NumGC),
+		F("gc_pause_last", last),
+		F("gc_pause_total", time.Duration(ms.PauseTotalNs)),
+	}}
+}
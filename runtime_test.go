@@ -0,0 +1,27 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+)
+
+func TestRuntimeStats(t *testing.T) {
+	runtime.GC()
+	p, _ := O{RuntimeStats()}.MarshalJSON()
+	var m map[string]map[string]interface{}
+	if err := json.Unmarshal(p, &m); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", p, err)
+	}
+	stats := m["runtime"]
+	if stats["goroutines"].(float64) < 1 || stats["heap_inuse"].(float64) <= 0 || stats["num_gc"].(float64) < 1 {
+		t.Errorf("RuntimeStats() = %s", p)
+	}
+	if s, _ := stats["gc_pause_last"].(string); s == "" {
+		t.Errorf("RuntimeStats() gc_pause_last = %v", stats["gc_pause_last"])
+	}
+}
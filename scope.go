@@ -0,0 +1,92 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// A Scope is a lightweight span: a begin entry and an end entry carrying
+// the elapsed time and the outcome of an operation, for code not yet
+// instrumented with tracing.
+type Scope struct {
+	child  *Logger // logs with the parent_id of the scope, if enabled
+	id     string  // the entry ID of the begin entry, if enabled
+	name   string
+	fields []Field
+	start  time.Time
+	ended  int32
+}
+
+// Scope logs the begin of the operation name at DebugLevel, and returns
+// the Scope whose End method logs its end. fields are added to both entries.
+//
+//	func load() (err error) {
+//		scope := log.Scope("load config", xlog.F("path", path))
+//		defer func() { scope.End(err) }()
+//		...
+//	}
+func (l *Logger) Scope(name string, fields ...Field) *Scope {
+	s := &Scope{child: l, name: name, fields: fields, start: time.Now()}
+	begin := []Field{F("scope", "begin")}
+	if l.entryIDs {
+		s.id = NewEntryID()
+		s.child = l.With(Fields(F(ParentIDKey, s.id)))
+		begin = append(begin, F(EntryIDKey, s.id))
+	}
+	if l.core.Enabled(DebugLevel) {
+		l.log(2, DebugLevel, name, nil, s.with(begin...))
+	}
+	return s
+}
+
+// ID returns the entry ID of the begin entry of the scope, empty unless
+// the Logger is configured with AddEntryIDs.
+func (s *Scope) ID() string {
+	return s.id
+}
+
+// Logger returns the Logger of the operation: with AddEntryIDs, its
+// entries carry the ID of the scope as their parent_id; otherwise it's
+// the Logger the scope was created from.
+func (s *Scope) Logger() *Logger {
+	return s.child
+}
+
+// End logs the end of the scope with its duration: at InfoLevel with
+// status "ok" if err is nil, at ErrorLevel with status "error" and the
+// error otherwise. Only the first call has an effect.
+func (s *Scope) End(err error) {
+	if !atomic.CompareAndSwapInt32(&s.ended, 0, 1) {
+		return
+	}
+	d := time.Since(s.start)
+	if err != nil {
+		s.child.log(2, ErrorLevel, s.name, nil,
+			s.with(F("scope", "end"), F("duration", d), F("status", "error"), F("error", err)))
+		return
+	}
+	s.child.log(2, InfoLevel, s.name, nil,
+		s.with(F("scope", "end"), F("duration", d), F("status", "ok")))
+}
+
+// Close ends the scope successfully, so a Scope can be used as an io.Closer.
+func (s *Scope) Close() error {
+	s.End(nil)
+	return nil
+}
+
+// Elapsed returns the time elapsed since the begin of the scope.
+func (s *Scope) Elapsed() time.Duration {
+	return time.Since(s.start)
+}
+
+// with returns the scope fields followed by fs.
+func (s *Scope) with(fs ...Field) []Field {
+	all := make([]Field, 0, len(s.fields)+len(fs))
+	all = append(all, s.fields...)
+	return append(all, fs...)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(Lshortfile), &buf, DebugLevel), AddCaller())
+
+	s := log.Scope("load config", F("path", "/etc/app"))
+	s.End(nil)
+	s.End(errors.New("ignored"))
+	log.Scope("connect").End(errors.New("refused"))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 4 {
+		t.Fatalf("Out = %s, want 4 entries", buf.Bytes())
+	}
+	want := [][]string{
+		{`"level":"DEBUG"`, `"caller":"scope_test.go:`, `"msg":"load config","path":"/etc/app","scope":"begin"}`},
+		{`"level":"INFO"`, `"caller":"scope_test.go:`, `"path":"/etc/app","scope":"end","duration":`, `"status":"ok"}`},
+		{`"level":"DEBUG"`, `"msg":"connect","scope":"begin"}`},
+		{`"level":"ERROR"`, `"status":"error","error":"refused"}`},
+	}
+	for i, subs := range want {
+		for _, sub := range subs {
+			if !bytes.Contains(lines[i], []byte(sub)) {
+				t.Errorf("entry %d = %s, want %s", i, lines[i], sub)
+			}
+		}
+	}
+}
+
+func TestScope_EntryIDs(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, DebugLevel), AddEntryIDs())
+
+	req := log.Scope("request")
+	req.Logger().Info("step")
+	sub := req.Logger().Scope("query")
+	sub.End(nil)
+	req.End(nil)
+	log.Info("explicit", F(EntryIDKey, "x1"))
+
+	type entry struct {
+		Msg      string
+		Scope    string
+		EntryID  string `json:"entry_id"`
+		ParentID string `json:"parent_id"`
+	}
+	var entries []entry
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			t.Fatalf("json.Unmarshal(%s) error = %v", line, err)
+		}
+		if e.EntryID == "" {
+			t.Errorf("entry %s has no entry_id", line)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("Out = %s, want 6 entries", buf.Bytes())
+	}
+
+	reqID, subID := req.ID(), sub.ID()
+	want := []struct{ id, parent string }{
+		{reqID, ""},    // request begin
+		{"", reqID},    // step
+		{subID, reqID}, // query begin
+		{"", subID},    // query end
+		{"", reqID},    // request end
+		{"x1", ""},     // explicit
+	}
+	for i, w := range want {
+		e := entries[i]
+		if w.id != "" && e.EntryID != w.id || e.ParentID != w.parent {
+			t.Errorf("entry %d = %+v, want id %q parent %q", i, e, w.id, w.parent)
+		}
+	}
+	if NewEntryID() == NewEntryID() || len(NewEntryID()) != 16 {
+		t.Errorf("NewEntryID() isn't unique")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// the package prefixes skipped when locating the call site of an entry.
+var _xlogPackages = []string{"github.com/cnotch/xlog"}
+
+type secretCore struct {
+	Core
+	w io.Writer
+}
+
+// NewSecretDetectorCore returns a Core that writes entries to core, and
+// warns on w when a string field value looks like a credential: a JWT, or a
+// long token of high Shannon entropy such as an API key. The warning names
+// the field and the call site, and never includes the value itself.
+//
+// The check costs an entropy computation per string field, so it's meant
+// for debug builds and tests, to catch accidental credential logging before
+// it reaches production.
+func NewSecretDetectorCore(core Core, w io.Writer) Core {
+	return &secretCore{core, w}
+}
+
+func (c *secretCore) Write(e Entry) error {
+	c.check(e, e.Ctx)
+	c.check(e, e.Fields)
+	return c.Core.Write(e)
+}
+
+func (c *secretCore) check(e Entry, fs []Field) {
+	for _, f := range fs {
+		s, ok := f.stringValue()
+		if !ok || !LooksLikeSecret(s) {
+			continue
+		}
+
+		caller := e.Caller
+		if !caller.Defined {
+			caller = newEntryCallers(3, 1, _xlogPackages)[0]
+		}
+		site := "unknown"
+		if caller.Defined {
+			site = fmt.Sprintf("%s:%d", caller.File, caller.Line)
+		}
+		fmt.Fprintf(c.w, "xlog: field %q of %q looks like a secret, logged at %s\n", f.Key, e.Message, site)
+	}
+}
+
+const (
+	_secretMinLen     = 20  // shorter tokens are rarely credentials
+	_secretMinRun     = 16  // the minimum run of alphanumerics
+	_secretMinEntropy = 3.5 // bits per byte, random hex scores about 4, base64 about 5
+)
+
+// LooksLikeSecret reports whether s resembles a credential: a JWT, or a
+// single token of at least 20 bytes with a long run of letters and digits
+// whose Shannon entropy is high. It's the heuristic of NewSecretDetectorCore.
+func LooksLikeSecret(s string) bool {
+	if len(s) < _secretMinLen || strings.IndexAny(s, " \t\r\n") >= 0 {
+		return false
+	}
+	if isJWT(s) {
+		return true
+	}
+
+	var letters, digits bool
+	run, maxRun := 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+			letters = true
+			run++
+		case '0' <= c && c <= '9':
+			digits = true
+			run++
+		case c == '-' || c == '_' || c == '+' || c == '/' || c == '=' || c == '.':
+			run = 0
+		default:
+			return false // urls and prose contain other characters
+		}
+		if run > maxRun {
+			maxRun = run
+		}
+	}
+	// paths and identifiers are made of short words
+	return letters && digits && maxRun >= _secretMinRun &&
+		shannonEntropy(s) >= _secretMinEntropy
+}
+
+// isJWT reports whether s has the form header.payload.signature
+// with a base64url encoded JSON header.
+func isJWT(s string) bool {
+	if !strings.HasPrefix(s, "eyJ") || strings.Count(s, ".") != 2 {
+		return false
+	}
+	for _, part := range strings.Split(s, ".") {
+		if len(part) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// shannonEntropy returns the entropy of s in bits per byte.
+func shannonEntropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	var h float64
+	n := float64(len(s))
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
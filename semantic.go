@@ -0,0 +1,81 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import "sync/atomic"
+
+// SemanticKeys maps the semantic options Component, Operation and Tenant
+// to the field keys they add, so that all teams of an organization log
+// them under the same names.
+type SemanticKeys struct {
+	Component string
+	Operation string
+	Tenant    string
+}
+
+// DefaultSemanticKeys are the keys used unless SetSemanticKeys is called.
+var DefaultSemanticKeys = SemanticKeys{
+	Component: "component",
+	Operation: "operation",
+	Tenant:    "tenant",
+}
+
+var semanticKeys atomic.Value // holds SemanticKeys
+
+func init() {
+	semanticKeys.Store(DefaultSemanticKeys)
+}
+
+// SetSemanticKeys sets the field keys of the semantic options. An empty key
+// keeps its default. It affects the options applied afterwards, so it's
+// usually called once at program start. It's safe for concurrent use.
+func SetSemanticKeys(keys SemanticKeys) {
+	if keys.Component == "" {
+		keys.Component = DefaultSemanticKeys.Component
+	}
+	if keys.Operation == "" {
+		keys.Operation = DefaultSemanticKeys.Operation
+	}
+	if keys.Tenant == "" {
+		keys.Tenant = DefaultSemanticKeys.Tenant
+	}
+	semanticKeys.Store(keys)
+}
+
+// GetSemanticKeys returns the current field keys of the semantic options.
+func GetSemanticKeys() SemanticKeys {
+	return semanticKeys.Load().(SemanticKeys)
+}
+
+// Component adds the name of the component, module or subsystem
+// to the Logger's preset fields, replacing any previous one.
+func Component(name string) Option {
+	return semanticOption(func(k SemanticKeys) string { return k.Component }, name)
+}
+
+// Operation adds the name of the operation being performed
+// to the Logger's preset fields, replacing any previous one.
+func Operation(name string) Option {
+	return semanticOption(func(k SemanticKeys) string { return k.Operation }, name)
+}
+
+// Tenant adds the tenant id to the Logger's preset fields,
+// replacing any previous one.
+func Tenant(id string) Option {
+	return semanticOption(func(k SemanticKeys) string { return k.Tenant }, id)
+}
+
+func semanticOption(key func(SemanticKeys) string, val string) Option {
+	return optionFunc(func(log *Logger) {
+		k := key(GetSemanticKeys())
+		for i := range log.ctx {
+			if log.ctx[i].Key == k {
+				log.ctx[i] = Field{Key: k, Val: val}
+				return
+			}
+		}
+		log.ctx = append(log.ctx, Field{Key: k, Val: val})
+	})
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSemanticOptions(t *testing.T) {
+	var buf bytes.Buffer
+	parent := New(NewCore(NewJSONEncoder(0), &buf, DebugLevel), Component("db"), Tenant("t1"))
+	log := parent.With(Operation("query"), Component("cache"))
+	log.Info("hit")
+	parent.Info("parent")
+
+	want := []string{
+		`"msg":"hit","component":"cache","tenant":"t1","operation":"query"}`,
+		`"msg":"parent","component":"db","tenant":"t1"}`,
+	}
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != len(want) {
+		t.Fatalf("Out = %s, want %d lines", buf.Bytes(), len(want))
+	}
+	for i := range want {
+		if !bytes.HasSuffix(lines[i], []byte(want[i])) {
+			t.Errorf("Out = %s, want suffix %s", lines[i], want[i])
+		}
+	}
+
+	SetSemanticKeys(SemanticKeys{Component: "comp"})
+	defer SetSemanticKeys(DefaultSemanticKeys)
+	if keys := GetSemanticKeys(); keys.Component != "comp" || keys.Tenant != "tenant" {
+		t.Errorf("GetSemanticKeys() = %+v", keys)
+	}
+	buf.Reset()
+	New(NewCore(NewJSONEncoder(0), &buf, DebugLevel), Component("db")).Info("renamed")
+	if !bytes.Contains(buf.Bytes(), []byte(`"comp":"db"`)) {
+		t.Errorf("Out = %s, want comp key", buf.Bytes())
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package zapshim provides a zap-like façade over xlog, so code written
+// against zap's Logger and SugaredLogger can be migrated to xlog
+// incrementally, without touching every call site at once.
+package zapshim
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/cnotch/xlog"
+)
+
+// Field is an xlog field, built by the zap-like constructors of this package.
+type Field = xlog.Field
+
+// String constructs a field with the given key and value.
+func String(key string, val string) Field { return xlog.String(key, val) }
+
+// Strings constructs a field that carries a slice of strings.
+func Strings(key string, val []string) Field { return xlog.F(key, val) }
+
+// Bool constructs a field that carries a bool.
+func Bool(key string, val bool) Field { return xlog.Bool(key, val) }
+
+// Int constructs a field with the given key and value.
+func Int(key string, val int) Field { return xlog.Int(key, val) }
+
+// Int64 constructs a field with the given key and value.
+func Int64(key string, val int64) Field { return xlog.Int64(key, val) }
+
+// Uint64 constructs a field with the given key and value.
+func Uint64(key string, val uint64) Field { return xlog.Uint64(key, val) }
+
+// Float64 constructs a field that carries a float64.
+func Float64(key string, val float64) Field { return xlog.Float64(key, val) }
+
+// Duration constructs a field with the given key and value.
+func Duration(key string, val time.Duration) Field { return xlog.Duration(key, val) }
+
+// Time constructs a field with the given key and value.
+func Time(key string, val time.Time) Field { return xlog.F(key, val) }
+
+// Binary constructs a field that carries an opaque binary blob.
+func Binary(key string, val []byte) Field { return xlog.F(key, val) }
+
+// Error is shorthand for NamedError("error", err).
+func Error(err error) Field { return NamedError("error", err) }
+
+// NamedError constructs a field that carries an error.
+func NamedError(key string, err error) Field { return xlog.F(key, err) }
+
+// Stringer constructs a field with the given key and the output of the value's String method.
+// The method is called when the entry is encoded; a nil val, or a nil pointer, is written
+// as "<nil>", as zap does.
+func Stringer(key string, val fmt.Stringer) Field {
+	return xlog.Lazy(key, func() interface{} { return stringOf(val) })
+}
+
+// stringOf returns the output of the String method of val, or "<nil>" if val is nil.
+func stringOf(val fmt.Stringer) string {
+	if val == nil {
+		return "<nil>"
+	}
+	if v := reflect.ValueOf(val); v.Kind() == reflect.Ptr && v.IsNil() {
+		return "<nil>"
+	}
+	return val.String()
+}
+
+// Any takes a key and an arbitrary value and chooses the best way to represent them as a field.
+func Any(key string, val interface{}) Field { return xlog.F(key, val) }
+
+// Namespace creates a named, isolated scope within the logger's context.
+// The fields following it at the log site are nested in the object key.
+func Namespace(key string) Field { return xlog.Namespace(key) }
+
+// A Logger provides zap's Logger API on top of an xlog.Logger.
+type Logger struct {
+	l *xlog.Logger
+}
+
+// New wraps an xlog.Logger. The caller annotation, if enabled,
+// skips the frames of this package.
+func New(l *xlog.Logger) *Logger {
+	return &Logger{l.With(xlog.AddCallerSkip(1))}
+}
+
+// XLogger returns the underlying xlog.Logger.
+func (log *Logger) XLogger() *xlog.Logger {
+	return log.l.With(xlog.AddCallerSkip(-1))
+}
+
+// Named adds a new path segment to the logger's name.
+func (log *Logger) Named(s string) *Logger { return &Logger{log.l.With(xlog.Named(s))} }
+
+// With creates a child logger and adds structured context to it.
+func (log *Logger) With(fields ...Field) *Logger { return &Logger{log.l.With(xlog.Fields(fields...))} }
+
+// Debug logs a message at DebugLevel.
+func (log *Logger) Debug(msg string, fields ...Field) { log.l.Log(xlog.DebugLevel, msg, fields...) }
+
+// Info logs a message at InfoLevel.
+func (log *Logger) Info(msg string, fields ...Field) { log.l.Log(xlog.InfoLevel, msg, fields...) }
+
+// Warn logs a message at WarnLevel.
+func (log *Logger) Warn(msg string, fields ...Field) { log.l.Log(xlog.WarnLevel, msg, fields...) }
+
+// Error logs a message at ErrorLevel.
+func (log *Logger) Error(msg string, fields ...Field) { log.l.Log(xlog.ErrorLevel, msg, fields...) }
+
+// DPanic logs a message at ErrorLevel, xlog has no development mode to panic in.
+func (log *Logger) DPanic(msg string, fields ...Field) { log.l.Log(xlog.ErrorLevel, msg, fields...) }
+
+// Panic logs a message at PanicLevel, then panics.
+func (log *Logger) Panic(msg string, fields ...Field) { log.l.Log(xlog.PanicLevel, msg, fields...) }
+
+// Fatal logs a message at FatalLevel, then calls os.Exit(1).
+func (log *Logger) Fatal(msg string, fields ...Field) { log.l.Log(xlog.FatalLevel, msg, fields...) }
+
+// Sync flushes any buffered log entries.
+func (log *Logger) Sync() error { return log.l.Sync() }
+
+// Sugar wraps the Logger to provide a more ergonomic, but slightly slower, API.
+func (log *Logger) Sugar() *SugaredLogger { return &SugaredLogger{log.l} }
+
+// A SugaredLogger provides zap's SugaredLogger API on top of an xlog.Logger.
+type SugaredLogger struct {
+	l *xlog.Logger
+}
+
+// Desugar unwraps a SugaredLogger, exposing the original Logger.
+func (s *SugaredLogger) Desugar() *Logger { return &Logger{s.l} }
+
+// Named adds a sub-scope to the logger's name.
+func (s *SugaredLogger) Named(name string) *SugaredLogger {
+	return &SugaredLogger{s.l.With(xlog.Named(name))}
+}
+
+// With adds a variadic number of loosely-typed key-value pairs to the logging context.
+func (s *SugaredLogger) With(keysAndValues ...interface{}) *SugaredLogger {
+	return &SugaredLogger{s.l.With(xlog.Fields(sweeten(keysAndValues)...))}
+}
+
+// Debugf uses fmt.Sprintf to log a templated message at DebugLevel.
+func (s *SugaredLogger) Debugf(template string, args ...interface{}) {
+	s.l.Log(xlog.DebugLevel, fmt.Sprintf(template, args...))
+}
+
+// Infof uses fmt.Sprintf to log a templated message at InfoLevel.
+func (s *SugaredLogger) Infof(template string, args ...interface{}) {
+	s.l.Log(xlog.InfoLevel, fmt.Sprintf(template, args...))
+}
+
+// Warnf uses fmt.Sprintf to log a templated message at WarnLevel.
+func (s *SugaredLogger) Warnf(template string, args ...interface{}) {
+	s.l.Log(xlog.WarnLevel, fmt.Sprintf(template, args...))
+}
+
+// Errorf uses fmt.Sprintf to log a templated message at ErrorLevel.
+func (s *SugaredLogger) Errorf(template string, args ...interface{}) {
+	s.l.Log(xlog.ErrorLevel, fmt.Sprintf(template, args...))
+}
+
+// Debugw logs a message with some additional context at DebugLevel.
+func (s *SugaredLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	s.l.Log(xlog.DebugLevel, msg, sweeten(keysAndValues)...)
+}
+
+// Infow logs a message with some additional context at InfoLevel.
+func (s *SugaredLogger) Infow(msg string, keysAndValues ...interface{}) {
+	s.l.Log(xlog.InfoLevel, msg, sweeten(keysAndValues)...)
+}
+
+// Warnw logs a message with some additional context at WarnLevel.
+func (s *SugaredLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	s.l.Log(xlog.WarnLevel, msg, sweeten(keysAndValues)...)
+}
+
+// Errorw logs a message with some additional context at ErrorLevel.
+func (s *SugaredLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	s.l.Log(xlog.ErrorLevel, msg, sweeten(keysAndValues)...)
+}
+
+// Sync flushes any buffered log entries.
+func (s *SugaredLogger) Sync() error { return s.l.Sync() }
+
+// sweeten turns loosely-typed key-value pairs into fields. Fields are
+// accepted as-is, and a dangling key is kept with a nil value.
+func sweeten(args []interface{}) []Field {
+	if len(args) == 0 {
+		return nil
+	}
+
+	fields := make([]Field, 0, len(args)/2+1)
+	for i := 0; i < len(args); i++ {
+		if f, ok := args[i].(Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+
+		key, ok := args[i].(string)
+		if !ok {
+			key = fmt.Sprint(args[i])
+		}
+		var val interface{}
+		if i+1 < len(args) {
+			i++
+			val = args[i]
+		}
+		fields = append(fields, xlog.F(key, val))
+	}
+	return fields
+}
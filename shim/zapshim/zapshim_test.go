@@ -0,0 +1,61 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package zapshim
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cnotch/xlog"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	xl := xlog.New(xlog.NewCore(xlog.NewJSONEncoder(xlog.Lshortfile), &buf, xlog.DebugLevel), xlog.AddCaller())
+	log := New(xl).Named("shim").With(String("app", "demo"))
+
+	log.Info("hello", Int("n", 1), Error(errors.New("failed")))
+	log.Sugar().Infow("sugar", "k", "v", "dangling")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`"logger":"shim","caller":"zapshim_test.go:21","msg":"hello","app":"demo","n":1,"error":"failed"}`,
+		`"logger":"shim","caller":"zapshim_test.go:22","msg":"sugar","app":"demo","k":"v","dangling":null}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Out = %s", buf.String())
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("Out = %v, want suffix %v", lines[i], want[i])
+		}
+	}
+}
+
+type countingStringer struct{ calls *int }
+
+func (s *countingStringer) String() string {
+	*s.calls++
+	return "stringer"
+}
+
+func TestStringer(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(xlog.New(xlog.NewCore(xlog.NewJSONEncoder(0), &buf, xlog.InfoLevel)))
+
+	calls := 0
+	log.Debug("disabled", Stringer("s", &countingStringer{&calls}))
+	if calls != 0 {
+		t.Errorf("String() called %d times for a disabled level", calls)
+	}
+
+	var nilPtr *countingStringer
+	log.Info("m", Stringer("s", &countingStringer{&calls}), Stringer("nil", nil), Stringer("nilptr", nilPtr))
+	if want := `"msg":"m","s":"stringer","nil":"\u003cnil\u003e","nilptr":"\u003cnil\u003e"}`; !strings.HasSuffix(strings.TrimSpace(buf.String()), want) {
+		t.Errorf("Out = %s, want suffix %s", buf.String(), want)
+	}
+}
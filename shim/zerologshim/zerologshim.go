@@ -0,0 +1,183 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package zerologshim provides a zerolog-like façade over xlog, so code
+// written against zerolog's chained event API can be migrated to xlog
+// incrementally, without touching every call site at once.
+package zerologshim
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cnotch/xlog"
+)
+
+// A Logger provides zerolog's Logger API on top of an xlog.Logger.
+type Logger struct {
+	l *xlog.Logger
+}
+
+// New wraps an xlog.Logger. The caller annotation, if enabled,
+// skips the frames of this package.
+func New(l *xlog.Logger) Logger {
+	return Logger{l.With(xlog.AddCallerSkip(1))}
+}
+
+// With creates a child logger with the field added to its context.
+func (l Logger) With() Context {
+	return Context{l: l.l}
+}
+
+// Debug starts a new message with debug level.
+func (l Logger) Debug() *Event { return newEvent(l.l, xlog.DebugLevel) }
+
+// Info starts a new message with info level.
+func (l Logger) Info() *Event { return newEvent(l.l, xlog.InfoLevel) }
+
+// Warn starts a new message with warn level.
+func (l Logger) Warn() *Event { return newEvent(l.l, xlog.WarnLevel) }
+
+// Error starts a new message with error level.
+func (l Logger) Error() *Event { return newEvent(l.l, xlog.ErrorLevel) }
+
+// Panic starts a new message with panic level. The panic is called
+// by the Msg method, which stops the ordinary flow of a goroutine.
+func (l Logger) Panic() *Event { return newEvent(l.l, xlog.PanicLevel) }
+
+// Fatal starts a new message with fatal level. The os.Exit(1) function
+// is called by the Msg method, which terminates the program immediately.
+func (l Logger) Fatal() *Event { return newEvent(l.l, xlog.FatalLevel) }
+
+// Err starts a new message with error level with err as a field if not nil
+// or with info level if err is nil.
+func (l Logger) Err(err error) *Event {
+	if err != nil {
+		return l.Error().Err(err)
+	}
+	return l.Info()
+}
+
+// An Event represents a log event. It is instanced by one of the level
+// methods of Logger and finalized by the Msg, Msgf or Send method.
+// A nil Event, returned when the level is disabled, discards everything.
+type Event struct {
+	l      *xlog.Logger
+	lvl    xlog.Level
+	fields []xlog.Field
+}
+
+func newEvent(l *xlog.Logger, lvl xlog.Level) *Event {
+	// Panic and Fatal always go through to panic or exit
+	if lvl < xlog.PanicLevel && !l.LevelEnabled(lvl) {
+		return nil
+	}
+	return &Event{l: l, lvl: lvl}
+}
+
+// Enabled returns false if the event is going to be filtered out by the level.
+func (e *Event) Enabled() bool { return e != nil }
+
+func (e *Event) add(key string, val interface{}) *Event {
+	return e.addField(xlog.F(key, val))
+}
+
+func (e *Event) addField(f xlog.Field) *Event {
+	if e != nil {
+		e.fields = append(e.fields, f)
+	}
+	return e
+}
+
+// Str adds the field key with val as a string to the event.
+func (e *Event) Str(key, val string) *Event { return e.addField(xlog.String(key, val)) }
+
+// Strs adds the field key with vals as a []string to the event.
+func (e *Event) Strs(key string, vals []string) *Event { return e.add(key, vals) }
+
+// Bytes adds the field key with val as a string to the event.
+func (e *Event) Bytes(key string, val []byte) *Event { return e.add(key, string(val)) }
+
+// Bool adds the field key with val as a bool to the event.
+func (e *Event) Bool(key string, b bool) *Event { return e.addField(xlog.Bool(key, b)) }
+
+// Int adds the field key with i as a int to the event.
+func (e *Event) Int(key string, i int) *Event { return e.addField(xlog.Int(key, i)) }
+
+// Int64 adds the field key with i as a int64 to the event.
+func (e *Event) Int64(key string, i int64) *Event { return e.addField(xlog.Int64(key, i)) }
+
+// Uint64 adds the field key with i as a uint64 to the event.
+func (e *Event) Uint64(key string, i uint64) *Event { return e.addField(xlog.Uint64(key, i)) }
+
+// Float64 adds the field key with f as a float64 to the event.
+func (e *Event) Float64(key string, f float64) *Event { return e.addField(xlog.Float64(key, f)) }
+
+// Dur adds the field key with duration d to the event.
+func (e *Event) Dur(key string, d time.Duration) *Event { return e.addField(xlog.Duration(key, d)) }
+
+// Time adds the field key with t to the event.
+func (e *Event) Time(key string, t time.Time) *Event { return e.add(key, t) }
+
+// Err adds the field "error" with serialized err to the event.
+// If err is nil, no field is added.
+func (e *Event) Err(err error) *Event {
+	if err == nil {
+		return e
+	}
+	return e.add("error", err)
+}
+
+// Interface adds the field key with i marshaled using reflection.
+func (e *Event) Interface(key string, i interface{}) *Event { return e.add(key, i) }
+
+// Msg sends the event with msg added as the message field.
+func (e *Event) Msg(msg string) {
+	if e != nil {
+		e.l.Log(e.lvl, msg, e.fields...)
+	}
+}
+
+// Msgf sends the event with formatted msg added as the message field.
+func (e *Event) Msgf(format string, v ...interface{}) {
+	if e != nil {
+		e.l.Log(e.lvl, fmt.Sprintf(format, v...), e.fields...)
+	}
+}
+
+// Send is equivalent to calling Msg("").
+func (e *Event) Send() {
+	if e != nil {
+		e.l.Log(e.lvl, "", e.fields...)
+	}
+}
+
+// Context configures a new sub-logger with contextual fields.
+type Context struct {
+	l      *xlog.Logger
+	fields []xlog.Field
+}
+
+// Logger returns the logger with the context previously set.
+func (c Context) Logger() Logger {
+	return Logger{c.l.With(xlog.Fields(c.fields...))}
+}
+
+// Str adds the field key with val as a string to the logger context.
+func (c Context) Str(key, val string) Context {
+	c.fields = append(c.fields[:len(c.fields):len(c.fields)], xlog.F(key, val))
+	return c
+}
+
+// Int adds the field key with i as a int to the logger context.
+func (c Context) Int(key string, i int) Context {
+	c.fields = append(c.fields[:len(c.fields):len(c.fields)], xlog.F(key, i))
+	return c
+}
+
+// Interface adds the field key with i marshaled using reflection to the logger context.
+func (c Context) Interface(key string, i interface{}) Context {
+	c.fields = append(c.fields[:len(c.fields):len(c.fields)], xlog.F(key, i))
+	return c
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package zerologshim
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cnotch/xlog"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	xl := xlog.New(xlog.NewCore(xlog.NewJSONEncoder(xlog.Lshortfile), &buf, xlog.InfoLevel), xlog.AddCaller())
+	log := New(xl).With().Str("app", "demo").Logger()
+
+	log.Debug().Str("k", "v").Msg("disabled")
+	log.Info().Str("k", "v").Int("n", 1).Msg("hello")
+	log.Err(errors.New("failed")).Msgf("job %d", 7)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`"caller":"zerologshim_test.go:22","msg":"hello","app":"demo","k":"v","n":1}`,
+		`"caller":"zerologshim_test.go:23","msg":"job 7","app":"demo","error":"failed"}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Out = %s", buf.String())
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("Out = %v, want suffix %v", lines[i], want[i])
+		}
+	}
+	if log.Debug().Enabled() {
+		t.Errorf("Debug().Enabled() = true, want false")
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/base64"
+	"time"
+)
+
+// the size of the JSON skeleton of an entry, with the longest level
+// and an RFC 3339 time:
+// {"level":"ERROR","time":"2006-01-02T15:04:05.999999999+07:00","msg":""}\n
+const _entryOverhead = 74
+
+// EstimateSize estimates the size in bytes of e encoded by the JSON
+// encoder, without encoding it. Strings are counted without escaping,
+// and values of types without a cheap estimate are encoded, so it's
+// exact for most entries and never far off. It lets cores enforcing
+// byte budgets drop or truncate entries before paying for the encoding.
+func EstimateSize(e Entry) int {
+	n := _entryOverhead + len(e.Message)
+	if e.LoggerName != "" {
+		n += len(`,"logger":""`) + len(e.LoggerName)
+	}
+	if e.Caller.Defined {
+		n += len(`,"caller":":"`) + len(e.Caller.File) + uintLen(uint64(e.Caller.Line))
+	}
+	return n + estimateFields(e.Ctx) + estimateFields(e.Fields)
+}
+
+// estimateFields estimates the size of fs as a comma separated list.
+func estimateFields(fs []Field) int {
+	n := 0
+	for _, f := range fs {
+		// ,"key":
+		n += len(f.Key) + 4 + estimateFieldValue(f)
+	}
+	return n
+}
+
+func estimateFieldValue(f Field) int {
+	if s, ok := f.stringValue(); ok {
+		return len(s) + 2
+	}
+	if f.typ != fieldAny {
+		return estimateValue(f.Value())
+	}
+	return estimateValue(f.Val)
+}
+
+func estimateValue(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 4
+	case string:
+		return len(v) + 2
+	case bool:
+		if v {
+			return 4
+		}
+		return 5
+	case int:
+		return intLen(int64(v))
+	case int32:
+		return intLen(int64(v))
+	case int64:
+		return intLen(v)
+	case uint:
+		return uintLen(uint64(v))
+	case uint32:
+		return uintLen(uint64(v))
+	case uint64:
+		return uintLen(v)
+	case float32, float64:
+		return 24 // upper bound of the shortest representation
+	case []byte:
+		return base64.StdEncoding.EncodedLen(len(v)) + 2
+	case time.Time:
+		return 37
+	case error:
+		return len(v.Error()) + 2
+	case Field:
+		return len(v.Key) + 5 + estimateFieldValue(v)
+	case O:
+		return estimateFields(v) + 1
+	default:
+		b := getBuilder()
+		b.AppendJSON(v)
+		n := b.Len()
+		putBuilder(b)
+		return n
+	}
+}
+
+// intLen returns the number of bytes of the decimal form of i.
+func intLen(i int64) int {
+	if i < 0 {
+		return uintLen(-uint64(i)) + 1
+	}
+	return uintLen(uint64(i))
+}
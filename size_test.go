@@ -0,0 +1,34 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEstimateSize(t *testing.T) {
+	entries := []Entry{
+		{Level: InfoLevel, Time: time.Now(), Message: "empty"},
+		{Level: WarnLevel, Time: time.Now(), Message: "fields", LoggerName: "svc.db",
+			Caller: NewEntryCaller(0, "/a/b.go", 123, true),
+			Ctx:    []Field{F("tenant", "t1"), F("id", -42)},
+			Fields: []Field{F("ok", false), F("n", uint64(1<<40)), F("err", errors.New("boom")),
+				F("obj", O{F("a", 1), F("b", "x")}), F("list", []int{1, 2, 3}), F("data", []byte("abc"))}},
+	}
+	enc := NewJSONEncoder(Llongfile)
+	for _, e := range entries {
+		b := getBuilder()
+		enc.Encode(b, e)
+		actual := b.Len()
+		putBuilder(b)
+
+		est := EstimateSize(e)
+		if est < actual || est > actual+actual/4 {
+			t.Errorf("EstimateSize(%q) = %d, encoded size %d", e.Message, est, actual)
+		}
+	}
+}
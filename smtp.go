@@ -0,0 +1,179 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTPConfig configures an SMTPCore.
+type SMTPConfig struct {
+	Addr    string    // the address of the SMTP server, host:port
+	Auth    smtp.Auth // nil for no authentication
+	From    string
+	To      []string
+	Subject string // "xlog digest" if empty, followed by the number of entries
+	// Enabler selects the entries to mail, ErrorLevel and above if nil.
+	Enabler LevelEnabler
+	// Interval is the period of the digests, 10 minutes if zero.
+	Interval time.Duration
+	// Threshold sends a digest as soon as it holds this many entries,
+	// without waiting for the interval. Zero waits for the interval.
+	Threshold int
+	// MaxEntries bounds the entries of a digest, 1000 if zero;
+	// the others are only counted.
+	MaxEntries int
+
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// _digestEncoder writes the plain text lines of digest emails.
+var _digestEncoder, _ = NewPatternEncoder("%d %-5p %c %caller %m %fields%n")
+
+// SMTPCore is an alert Core for small deployments without a monitoring
+// stack: it batches entries into periodic digest emails, one plain text
+// line per entry.
+type SMTPCore struct {
+	cfg     SMTPConfig
+	enc     Encoder
+	mu      sync.Mutex
+	digest  Builder
+	n       int // entries in the digest
+	omitted int // entries over MaxEntries
+	err     error
+	wake    chan struct{}
+	stop    chan struct{}
+	stopped sync.Once // closes stop
+	done    chan struct{}
+}
+
+// NewSMTPCore creates an SMTPCore and starts its digest goroutine,
+// call Close to send the last digest and stop it.
+func NewSMTPCore(cfg SMTPConfig) *SMTPCore {
+	if cfg.Subject == "" {
+		cfg.Subject = "xlog digest"
+	}
+	if cfg.Enabler == nil {
+		cfg.Enabler = ErrorLevel
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Minute
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	if cfg.sendMail == nil {
+		cfg.sendMail = smtp.SendMail
+	}
+	c := &SMTPCore{
+		cfg:  cfg,
+		enc:  _digestEncoder,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// Enabled implements the LevelEnabler interface.
+func (c *SMTPCore) Enabled(lvl Level) bool {
+	return c.cfg.Enabler.Enabled(lvl)
+}
+
+// Write adds e to the pending digest.
+func (c *SMTPCore) Write(e Entry) (err error) {
+	c.mu.Lock()
+	if c.n >= c.cfg.MaxEntries {
+		c.omitted++
+	} else {
+		err = c.enc.Encode(&c.digest, e)
+		c.n++
+	}
+	full := c.cfg.Threshold > 0 && c.n+c.omitted >= c.cfg.Threshold
+	c.mu.Unlock()
+
+	if full {
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+// Sync sends the pending digest now, and returns the error of
+// the last failed sending, if any.
+func (c *SMTPCore) Sync() error {
+	c.send()
+	c.mu.Lock()
+	err := c.err
+	c.err = nil
+	c.mu.Unlock()
+	return err
+}
+
+// Close sends the pending digest and stops the digest goroutine.
+func (c *SMTPCore) Close() error {
+	c.stopped.Do(func() { close(c.stop) })
+	<-c.done
+	return c.Sync()
+}
+
+func (c *SMTPCore) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.wake:
+		case <-c.stop:
+			return
+		}
+		c.send()
+	}
+}
+
+// send mails the pending digest, if any.
+func (c *SMTPCore) send() {
+	c.mu.Lock()
+	if c.n == 0 && c.omitted == 0 {
+		c.mu.Unlock()
+		return
+	}
+	n, omitted := c.n, c.omitted
+	body := c.digest.CopyBytes()
+	c.digest.Reset()
+	c.n, c.omitted = 0, 0
+	c.mu.Unlock()
+
+	var msg Builder
+	msg.WriteString("From: ")
+	msg.WriteString(c.cfg.From)
+	msg.WriteString("\r\nTo: ")
+	msg.WriteString(strings.Join(c.cfg.To, ", "))
+	msg.WriteString("\r\nSubject: ")
+	msg.WriteString(c.cfg.Subject)
+	msg.WriteString(" (")
+	msg.WriteString(strconv.Itoa(n + omitted))
+	msg.WriteString(" entries)\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.Write(body)
+	if omitted > 0 {
+		msg.WriteString("\n... ")
+		msg.WriteString(strconv.Itoa(omitted))
+		msg.WriteString(" more entries omitted\n")
+	}
+
+	if err := c.cfg.sendMail(c.cfg.Addr, c.cfg.Auth, c.cfg.From, c.cfg.To, msg.Bytes()); err != nil {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+	}
+}
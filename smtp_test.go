@@ -0,0 +1,87 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"errors"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSMTPCore(t *testing.T) {
+	var mu sync.Mutex
+	var mails []string
+	sent := make(chan struct{}, 10)
+	cfg := SMTPConfig{
+		Addr:       "mail:25",
+		From:       "xlog@example.com",
+		To:         []string{"ops@example.com", "dev@example.com"},
+		Interval:   time.Hour,
+		Threshold:  3,
+		MaxEntries: 2,
+		sendMail: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			mu.Lock()
+			mails = append(mails, string(msg))
+			mu.Unlock()
+			sent <- struct{}{}
+			if strings.Contains(string(msg), "fail") {
+				return errors.New("smtp: refused")
+			}
+			return nil
+		},
+	}
+	core := NewSMTPCore(cfg)
+	log := New(core)
+
+	log.Warn("ignored")
+	log.Error("first")
+	log.Error("second")
+	log.Error("third")
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("threshold didn't send a digest")
+	}
+
+	log.Error("fail")
+	if err := core.Close(); err == nil {
+		t.Errorf("Close() error = nil, want the sending error")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(mails) != 2 {
+		t.Fatalf("mails = %d, want 2", len(mails))
+	}
+	m := mails[0]
+	for _, want := range []string{
+		"To: ops@example.com, dev@example.com\r\n",
+		"Subject: xlog digest (3 entries)\r\n",
+		"ERROR   first", "ERROR   second", "1 more entries omitted",
+	} {
+		if !strings.Contains(m, want) {
+			t.Errorf("mail = %q, want %q", m, want)
+		}
+	}
+	if strings.Contains(m, "ignored") || strings.Contains(m, "third") {
+		t.Errorf("mail = %q", m)
+	}
+}
+
+func TestSMTPCore_concurrentClose(t *testing.T) {
+	core := NewSMTPCore(SMTPConfig{Interval: time.Hour})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			core.Close()
+		}()
+	}
+	wg.Wait()
+}
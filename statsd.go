@@ -0,0 +1,146 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+type statsdCore struct {
+	Core
+	w      io.Writer
+	prefix string
+}
+
+// NewStatsdCore returns a Core that writes entries to core, and mirrors
+// their conventional metric fields to a statsd endpoint w (usually a UDP
+// connection), one packet per entry:
+//
+//	metric      the metric name, defaults to the logger name
+//	count       a counter increment: name:count|c
+//	duration_ms a timing in milliseconds: name:duration_ms|ms
+//	duration    a time.Duration timing: name:ms|ms
+//	gauge       a gauge value: name:gauge|g
+//
+// An entry with a metric field but no value increments the counter by 1.
+// Metrics are best effort: errors writing to w are ignored.
+func NewStatsdCore(core Core, w io.Writer, prefix string) Core {
+	return &statsdCore{core, w, prefix}
+}
+
+func (c *statsdCore) Write(e Entry) error {
+	err := c.Core.Write(e)
+	c.emit(e)
+	return err
+}
+
+func (c *statsdCore) emit(e Entry) {
+	name := e.LoggerName
+	var named bool
+	var count, duration, gauge interface{}
+	for _, fs := range [2][]Field{e.Ctx, e.Fields} {
+		for _, f := range fs {
+			switch f.Key {
+			case "metric":
+				if s, ok := f.stringValue(); ok && s != "" {
+					name, named = s, true
+				}
+			case "count":
+				count = f.Value()
+			case "duration_ms":
+				duration = f.Value()
+			case "duration":
+				if d, ok := f.Value().(time.Duration); ok {
+					duration = float64(d) / float64(time.Millisecond)
+				}
+			case "gauge":
+				gauge = f.Value()
+			}
+		}
+	}
+	if name == "" || count == nil && duration == nil && gauge == nil && !named {
+		return
+	}
+
+	b := getBuilder()
+	defer putBuilder(b)
+	if count == nil && duration == nil && gauge == nil {
+		count = 1
+	}
+	c.appendMetric(b, name, count, "c")
+	c.appendMetric(b, name, duration, "ms")
+	c.appendMetric(b, name, gauge, "g")
+	if b.Len() > 0 {
+		c.w.Write(b.Bytes())
+	}
+}
+
+func (c *statsdCore) appendMetric(b *Builder, name string, val interface{}, typ string) {
+	v, ok := toFloat64(val)
+	if !ok {
+		return
+	}
+	if b.Len() > 0 {
+		b.WriteByte('\n')
+	}
+	b.WriteString(c.prefix)
+	b.WriteString(sanitizeMetricName(name))
+	b.WriteByte(':')
+	b.AppendFloat64(v)
+	b.WriteByte('|')
+	b.WriteString(typ)
+}
+
+// sanitizeMetricName replaces the characters reserved by the statsd protocol.
+func sanitizeMetricName(name string) string {
+	if strings.IndexAny(name, ":|@\n ") < 0 {
+		return name
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '\n', ' ':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// toFloat64 converts the numeric values of fields to float64.
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
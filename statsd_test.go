@@ -0,0 +1,48 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type packetRecorder struct {
+	packets []string
+}
+
+func (r *packetRecorder) Write(p []byte) (int, error) {
+	r.packets = append(r.packets, string(p))
+	return len(p), nil
+}
+
+func TestStatsdCore(t *testing.T) {
+	var buf bytes.Buffer
+	var rec packetRecorder
+	log := New(NewStatsdCore(NewCore(NewJSONEncoder(0), &buf, DebugLevel), &rec, "app."), Named("db"))
+
+	log.Info("query", F("duration_ms", 12.5))
+	log.Info("cache hit", F("metric", "cache.hit"))
+	log.Info("batch", F("metric", "batch size"), F("count", 3), F("duration", 1500*time.Microsecond), F("gauge", uint(7)))
+	New(NewStatsdCore(NewNopCore(), &rec, "")).Info("no metric", F("x", 1))
+
+	want := []string{
+		"app.db:12.5|ms",
+		"app.cache.hit:1|c",
+		"app.batch_size:3|c\napp.batch_size:1.5|ms\napp.batch_size:7|g",
+	}
+	if len(rec.packets) != len(want) {
+		t.Fatalf("packets = %q, want %q", rec.packets, want)
+	}
+	for i := range want {
+		if rec.packets[i] != want[i] {
+			t.Errorf("packet = %q, want %q", rec.packets[i], want[i])
+		}
+	}
+	if bytes.Count(buf.Bytes(), []byte("\n")) != 3 {
+		t.Errorf("entries not written to the wrapped core: %s", buf.Bytes())
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// TargetCore is a Core for customer-specific investigations: it keeps all
+// the entries whose field key holds one of a runtime-managed set of
+// target values (e.g. the user_id of the users being investigated), at
+// every level, and samples the other entries.
+type TargetCore struct {
+	Core
+	key     string
+	every   uint64
+	n       uint64 // the number of untargeted entries seen
+	skipped uint64 // the number of untargeted entries sampled out
+	drops   dropCounter
+	mu      sync.RWMutex
+	targets map[string]struct{}
+}
+
+// NewTargetCore creates a TargetCore writing to core. Entries whose field
+// key matches a target are all written, even at levels core doesn't
+// enable; of the other entries, one in every sampleEvery is written.
+func NewTargetCore(core Core, key string, sampleEvery int) *TargetCore {
+	if sampleEvery < 1 {
+		sampleEvery = 1
+	}
+	return &TargetCore{
+		Core:    core,
+		key:     key,
+		every:   uint64(sampleEvery),
+		targets: make(map[string]struct{}),
+	}
+}
+
+// Enabled enables all levels while there are targets, so that their
+// entries reach Write.
+func (c *TargetCore) Enabled(lvl Level) bool {
+	if c.Core.Enabled(lvl) {
+		return true
+	}
+	c.mu.RLock()
+	n := len(c.targets)
+	c.mu.RUnlock()
+	return n > 0 && lvl.Valid()
+}
+
+// Write writes e if it matches a target, or if it's sampled.
+func (c *TargetCore) Write(e Entry) error {
+	if c.targeted(e) {
+		return c.Core.Write(e)
+	}
+	if !c.Core.Enabled(e.Level) {
+		return nil
+	}
+	if (atomic.AddUint64(&c.n, 1)-1)%c.every != 0 {
+		atomic.AddUint64(&c.skipped, 1)
+		return nil
+	}
+	return c.Core.Write(e)
+}
+
+// Sync syncs the underlying core, and returns a *DroppedError if
+// untargeted entries were sampled out since the previous Sync.
+func (c *TargetCore) Sync() error {
+	err := c.Core.Sync()
+	return combineErrors(err, c.drops.since("target", atomic.LoadUint64(&c.skipped)))
+}
+
+func (c *TargetCore) targeted(e Entry) bool {
+	f, ok := lookupField(e, c.key)
+	if !ok {
+		return false
+	}
+	s, ok := f.stringValue()
+	if !ok {
+		s = fmt.Sprint(f.Value())
+	}
+	c.mu.RLock()
+	_, ok = c.targets[s]
+	c.mu.RUnlock()
+	return ok
+}
+
+// Add adds values to the targets.
+func (c *TargetCore) Add(values ...string) {
+	c.mu.Lock()
+	for _, v := range values {
+		c.targets[v] = struct{}{}
+	}
+	c.mu.Unlock()
+}
+
+// Remove removes values from the targets.
+func (c *TargetCore) Remove(values ...string) {
+	c.mu.Lock()
+	for _, v := range values {
+		delete(c.targets, v)
+	}
+	c.mu.Unlock()
+}
+
+// Targets returns the sorted target values.
+func (c *TargetCore) Targets() []string {
+	c.mu.RLock()
+	values := make([]string, 0, len(c.targets))
+	for v := range c.targets {
+		values = append(values, v)
+	}
+	c.mu.RUnlock()
+	sort.Strings(values)
+	return values
+}
+
+// ServeHTTP is a simple JSON endpoint managing the targets.
+//
+// GET returns the targets:
+//
+//	{"key":"user_id","targets":["42"]}
+//
+// PUT adds, and DELETE removes, the values of the "value" query parameter:
+//
+//	curl -X PUT localhost:8080/log/targets?value=42
+func (c *TargetCore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		c.Add(r.URL.Query()["value"]...)
+	case http.MethodDelete:
+		c.Remove(r.URL.Query()["value"]...)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Key     string   `json:"key"`
+		Targets []string `json:"targets"`
+	}{c.key, c.Targets()})
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTargetCore(t *testing.T) {
+	var buf bytes.Buffer
+	core := NewTargetCore(NewCore(NewJSONEncoder(0), &buf, InfoLevel), "user_id", 4)
+	log := New(core)
+
+	if core.Enabled(DebugLevel) {
+		t.Errorf("Enabled(DebugLevel) = true without targets")
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/targets?value=42&value=7", nil)
+	rec := httptest.NewRecorder()
+	core.ServeHTTP(rec, req)
+	if rec.Body.String() != `{"key":"user_id","targets":["42","7"]}`+"\n" {
+		t.Errorf("PUT = %s", rec.Body.String())
+	}
+
+	for i := 0; i < 8; i++ {
+		log.Debug("target", F("user_id", 42))
+		log.Debug("other debug", F("user_id", 1))
+		log.Info("other", F("user_id", 1))
+	}
+	if n := bytes.Count(buf.Bytes(), []byte(`"msg":"target"`)); n != 8 {
+		t.Errorf("targeted entries = %d, want 8", n)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte(`"msg":"other"`)); n != 2 {
+		t.Errorf("sampled entries = %d, want 2", n)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("other debug")) {
+		t.Errorf("untargeted debug entry written")
+	}
+
+	rec = httptest.NewRecorder()
+	core.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/targets?value=42", nil))
+	if got := core.Targets(); len(got) != 1 || got[0] != "7" {
+		t.Errorf("Targets() = %v, want [7]", got)
+	}
+}
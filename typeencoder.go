@@ -0,0 +1,64 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// A TypeEncoder appends the JSON encoding of v, a value of the type it's
+// registered for, to b. It must append exactly one valid JSON value.
+type TypeEncoder func(b *Builder, v interface{})
+
+var (
+	typeEncodersMu sync.Mutex   // serializes the registrations
+	typeEncoders   atomic.Value // holds map[reflect.Type]TypeEncoder, copied on write
+)
+
+func init() {
+	typeEncoders.Store(map[reflect.Type]TypeEncoder(nil))
+}
+
+// RegisterTypeEncoder registers fn as the encoder of the values of the
+// dynamic type of sample, such as UUIDs, decimals or protobuf enums, used
+// by Builder.AppendJSON instead of their marshaling methods or reflection.
+// A nil fn unregisters the type. The types AppendJSON encodes natively,
+// e.g. strings, numbers, time.Time and time.Duration, can't be overridden,
+// and the values nested in a value encoded by reflection, such as the
+// fields of a struct, don't use the registered encoders.
+//
+// Register the encoders at init, before logging: registering is safe for
+// concurrent use, but copies the registry.
+func RegisterTypeEncoder(sample interface{}, fn TypeEncoder) {
+	t := reflect.TypeOf(sample)
+	if t == nil {
+		panic("xlog: RegisterTypeEncoder of nil")
+	}
+
+	typeEncodersMu.Lock()
+	defer typeEncodersMu.Unlock()
+	old := typeEncoders.Load().(map[reflect.Type]TypeEncoder)
+	m := make(map[reflect.Type]TypeEncoder, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	if fn == nil {
+		delete(m, t)
+	} else {
+		m[t] = fn
+	}
+	typeEncoders.Store(m)
+}
+
+// lookupTypeEncoder returns the encoder registered for the type of v, if any.
+func lookupTypeEncoder(v interface{}) TypeEncoder {
+	m := typeEncoders.Load().(map[reflect.Type]TypeEncoder)
+	if len(m) == 0 {
+		return nil
+	}
+	return m[reflect.TypeOf(v)]
+}
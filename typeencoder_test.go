@@ -0,0 +1,51 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+type testUUID [16]byte
+
+func (u testUUID) MarshalText() ([]byte, error) {
+	return []byte("text"), nil
+}
+
+func TestRegisterTypeEncoder(t *testing.T) {
+	id := testUUID{0xde, 0xad, 0xbe, 0xef}
+	var b Builder
+	b.AppendJSON(id)
+	if got := b.String(); got != `"text"` {
+		t.Errorf("AppendJSON() = %s, want the MarshalText output", got)
+	}
+
+	RegisterTypeEncoder(testUUID{}, func(b *Builder, v interface{}) {
+		u := v.(testUUID)
+		var buf [32]byte
+		hex.Encode(buf[:], u[:])
+		b.WriteByte('"')
+		b.Write(buf[:])
+		b.WriteByte('"')
+	})
+	b.Reset()
+	b.AppendJSON(map[string]interface{}{"id": id})
+	if got, want := b.String(), `{"id":"deadbeef000000000000000000000000"}`; got != want {
+		t.Errorf("AppendJSON() = %s, want %s", got, want)
+	}
+	b.Reset()
+	b.AppendJSON(&id) // the pointer type isn't registered
+	if got := b.String(); got != `"text"` {
+		t.Errorf("AppendJSON(&id) = %s, want the MarshalText output", got)
+	}
+
+	RegisterTypeEncoder(testUUID{}, nil)
+	b.Reset()
+	b.AppendJSON(id)
+	if got := b.String(); got != `"text"` {
+		t.Errorf("AppendJSON() = %s after unregistering, want the MarshalText output", got)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import "sync/atomic"
+
+// _maxPreallocSize caps the builder capacity set by Preallocate,
+// so a misconfiguration can't pin huge buffers.
+const _maxPreallocSize = 1 << 20
+
+// Preallocate configures the Core for the expected throughput: the pooled
+// builders are created with a capacity of entrySize bytes, so an entry of
+// that size is encoded without growing its buffer, and builders of them
+// are put in the pool at construction, so the first burst of entries
+// after a deploy doesn't allocate them.
+//
+// The capacity is shared by all the Cores and only ever raised. The pool
+// may still release idle builders on garbage collections, so builders is
+// a warm-up, not a floor.
+func Preallocate(entrySize, builders int) CoreOption {
+	return coreOptionFunc(func(*ioCore) {
+		preallocBuilders(entrySize, builders)
+	})
+}
+
+func preallocBuilders(size, n int) {
+	if size > _maxPreallocSize {
+		size = _maxPreallocSize
+	}
+	for {
+		old := atomic.LoadInt32(&builderSize)
+		if int32(size) <= old || atomic.CompareAndSwapInt32(&builderSize, old, int32(size)) {
+			break
+		}
+	}
+
+	size = int(atomic.LoadInt32(&builderSize))
+	for i := 0; i < n; i++ {
+		builderPool.Put(&Builder{buf: make([]byte, 0, size)})
+	}
+}
+
+// ExpectedFields configures the Logger, and the Loggers derived from it,
+// to reserve room for n preset fields, so adding fields with With doesn't
+// grow the field slice again and again.
+func ExpectedFields(n int) Option {
+	return optionFunc(func(log *Logger) {
+		if n < 0 {
+			n = 0
+		}
+		log.fieldsHint = n
+		if cap(log.ctx) < n {
+			ctx := make([]Field, len(log.ctx), n)
+			copy(ctx, log.ctx)
+			log.ctx = ctx
+		}
+	})
+}
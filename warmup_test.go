@@ -0,0 +1,59 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPreallocate(t *testing.T) {
+	old := atomic.LoadInt32(&builderSize)
+	defer atomic.StoreInt32(&builderSize, old)
+
+	var buf bytes.Buffer
+	NewCore(NewJSONEncoder(0), &buf, DebugLevel, Preallocate(4096, 4))
+	if got := atomic.LoadInt32(&builderSize); got != 4096 {
+		t.Errorf("builderSize = %d, want 4096", got)
+	}
+	if b := builderPool.New().(*Builder); cap(b.buf) != 4096 {
+		t.Errorf("cap(new builder) = %d, want 4096", cap(b.buf))
+	}
+
+	NewCore(NewJSONEncoder(0), &buf, DebugLevel, Preallocate(1024, 0))
+	if got := atomic.LoadInt32(&builderSize); got != 4096 {
+		t.Errorf("builderSize = %d after a smaller size, want 4096", got)
+	}
+	NewCore(NewJSONEncoder(0), &buf, DebugLevel, Preallocate(1<<30, 0))
+	if got := atomic.LoadInt32(&builderSize); got != _maxPreallocSize {
+		t.Errorf("builderSize = %d, want the cap %d", got, _maxPreallocSize)
+	}
+}
+
+func TestExpectedFields(t *testing.T) {
+	log := New(NewNopCore(), Fields(F("a", 1)), ExpectedFields(4))
+	if cap(log.ctx) != 4 {
+		t.Fatalf("cap(ctx) = %d, want 4", cap(log.ctx))
+	}
+
+	child := log.With(Fields(F("b", 2)))
+	if len(child.ctx) != 2 || cap(child.ctx) != 4 {
+		t.Errorf("child ctx len, cap = %d, %d, want 2, 4", len(child.ctx), cap(child.ctx))
+	}
+	if len(log.ctx) != 1 {
+		t.Errorf("len(parent ctx) = %d, want 1", len(log.ctx))
+	}
+
+	with := func(l *Logger) float64 {
+		return testing.AllocsPerRun(100, func() {
+			l.With(Fields(F("c", 3), F("d", 4)))
+		})
+	}
+	plain := New(NewNopCore(), Fields(F("a", 1), F("b", 2)))
+	if got, base := with(child), with(plain); got >= base {
+		t.Errorf("With() allocs = %v, want less than %v without ExpectedFields", got, base)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WebhookFormat is the payload format of a WebhookCore.
+type WebhookFormat int
+
+const (
+	// WebhookJSON posts the entry as written by the JSON encoder.
+	WebhookJSON WebhookFormat = iota
+	// WebhookSlack posts a Slack incoming webhook message: {"text":"..."}.
+	WebhookSlack
+	// WebhookTeams posts a Microsoft Teams incoming webhook message: {"text":"..."}.
+	WebhookTeams
+)
+
+// DefaultWebhookTemplate is the text template of chat messages.
+const DefaultWebhookTemplate = "[{level}] {logger}: {msg}"
+
+// WebhookConfig configures a WebhookCore.
+type WebhookConfig struct {
+	URL    string
+	Format WebhookFormat
+	// Template is the text of chat messages, with the placeholders {level},
+	// {logger}, {msg}, and {key} for the value of the field key.
+	// DefaultWebhookTemplate if empty.
+	Template string
+	// Enabler selects the entries to post, WarnLevel and above if nil.
+	Enabler LevelEnabler
+	// RateLimit is the maximum number of posts per minute, with bursts of
+	// as many posts. Entries over the limit are dropped. Zero means unlimited.
+	RateLimit int
+	// QueueSize bounds the entries waiting to be posted, 64 if zero.
+	// Entries are dropped when the queue is full.
+	QueueSize int
+	// HighWater is the number of queued entries above which the core is
+	// under backpressure, see Pressurer; 3/4 of QueueSize if zero.
+	HighWater int
+	// OnBackpressure, if set, is called with true when the queue grows
+	// above HighWater, and with false when it drains to half of it.
+	// It's called by Write or by the delivery goroutine, and must not
+	// block.
+	OnBackpressure func(pressured bool)
+	// Client posts the payloads, a client with a 10 seconds timeout if nil.
+	// A client without timeout makes Sync wait on a hung endpoint.
+	Client *http.Client
+}
+
+// WebhookStats are the counters of a WebhookCore.
+type WebhookStats struct {
+	Sent    uint64 // successfully posted
+	Dropped uint64 // over the rate limit or the queue size
+	Failed  uint64 // failed requests or non-2xx responses
+}
+
+var errWebhookClosed = errors.New("xlog: webhook core closed")
+
+// WebhookCore is a Core posting entries to a webhook, such as a Slack or
+// Teams channel or a generic JSON endpoint, to replace per-project alert
+// glue code. Payloads are rendered by Write and delivered asynchronously,
+// so logging never waits on the network.
+type WebhookCore struct {
+	cfg   WebhookConfig
+	sink  string // the scheme and host of the URL, for the lifecycle entries
+	json  Encoder
+	queue chan webhookItem
+	done  chan struct{}
+	stats WebhookStats
+
+	mu     sync.Mutex // guards the rate limit
+	tokens float64
+	last   time.Time
+
+	closeMu sync.RWMutex // held for reading while sending to the queue
+	closed  bool
+
+	saturated  int32  // set while the queue is full
+	satDropped uint64 // the entries dropped since the queue is full
+
+	pressure *backpressure
+	drops    dropCounter
+}
+
+// webhookItem is a payload to post, or a Sync request if flushed is set.
+type webhookItem struct {
+	payload []byte
+	flushed chan struct{}
+}
+
+// NewWebhookCore creates a WebhookCore and starts its delivery goroutine,
+// call Close to stop it.
+func NewWebhookCore(cfg WebhookConfig) *WebhookCore {
+	if cfg.Template == "" {
+		cfg.Template = DefaultWebhookTemplate
+	}
+	if cfg.Enabler == nil {
+		cfg.Enabler = WarnLevel
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 64
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	c := &WebhookCore{
+		cfg:    cfg,
+		sink:   sinkURL(cfg.URL),
+		json:   NewJSONEncoder(Lshortfile),
+		queue:  make(chan webhookItem, cfg.QueueSize),
+		done:   make(chan struct{}),
+		tokens: float64(cfg.RateLimit),
+		last:   time.Now(),
+	}
+	c.pressure = newBackpressure(cfg.QueueSize, cfg.HighWater, cfg.OnBackpressure)
+	go c.run()
+	return c
+}
+
+// Enabled implements the LevelEnabler interface.
+func (c *WebhookCore) Enabled(lvl Level) bool {
+	return c.cfg.Enabler.Enabled(lvl)
+}
+
+// Write renders the payload of e and queues it for delivery.
+func (c *WebhookCore) Write(e Entry) error {
+	queued, full, err := c.write(e)
+	switch {
+	case full:
+		atomic.AddUint64(&c.satDropped, 1)
+		if atomic.CompareAndSwapInt32(&c.saturated, 0, 1) {
+			lifecycle(WarnLevel, "queue saturated", String("sink", c.sink))
+		}
+	case queued && atomic.LoadInt32(&c.saturated) != 0:
+		if atomic.CompareAndSwapInt32(&c.saturated, 1, 0) {
+			lifecycle(InfoLevel, "queue recovered", String("sink", c.sink),
+				Uint64("dropped", atomic.SwapUint64(&c.satDropped, 0)))
+		}
+	}
+	if queued || full {
+		c.pressure.update(len(c.queue))
+	}
+	return err
+}
+
+// write queues the payload of e, and reports whether it did, or dropped
+// it because the queue is full.
+func (c *WebhookCore) write(e Entry) (queued, full bool, err error) {
+	c.closeMu.RLock()
+	defer c.closeMu.RUnlock()
+	if c.closed {
+		return false, false, errWebhookClosed
+	}
+	c.mu.Lock()
+	allowed := c.allow()
+	c.mu.Unlock()
+	if !allowed {
+		atomic.AddUint64(&c.stats.Dropped, 1)
+		return false, false, nil
+	}
+
+	b := getBuilder()
+	defer putBuilder(b)
+	switch c.cfg.Format {
+	case WebhookSlack, WebhookTeams:
+		text := getBuilder()
+		appendTemplate(text, c.cfg.Template, e, true)
+		b.WriteString(`{"text":`)
+		b.AppendQuote(text.String())
+		b.WriteByte('}')
+		putBuilder(text)
+	default:
+		err = c.json.Encode(b, e)
+	}
+
+	select {
+	case c.queue <- webhookItem{payload: b.CopyBytes()}:
+		queued = true
+	default:
+		atomic.AddUint64(&c.stats.Dropped, 1)
+		full = true
+	}
+	return
+}
+
+// allow reports whether the rate limit allows a post, c.mu must be held.
+func (c *WebhookCore) allow() bool {
+	if c.cfg.RateLimit <= 0 {
+		return true
+	}
+	now := time.Now()
+	limit := float64(c.cfg.RateLimit)
+	c.tokens += now.Sub(c.last).Minutes() * limit
+	if c.tokens > limit {
+		c.tokens = limit
+	}
+	c.last = now
+	if c.tokens < 1 {
+		return false
+	}
+	c.tokens--
+	return true
+}
+
+// Sync waits until the entries written before are delivered, and returns
+// a *DroppedError if entries were dropped since the previous Sync.
+func (c *WebhookCore) Sync() error {
+	c.closeMu.RLock()
+	if !c.closed {
+		flushed := make(chan struct{})
+		c.queue <- webhookItem{flushed: flushed}
+		c.closeMu.RUnlock()
+		<-flushed
+	} else {
+		c.closeMu.RUnlock()
+	}
+	return c.drops.since("webhook", atomic.LoadUint64(&c.stats.Dropped))
+}
+
+// Close delivers the queued entries and stops the delivery goroutine.
+func (c *WebhookCore) Close() error {
+	c.closeMu.Lock()
+	if c.closed {
+		c.closeMu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.queue)
+	c.closeMu.Unlock()
+	<-c.done
+	return nil
+}
+
+// Pressured reports whether the queue is above its high-water mark.
+func (c *WebhookCore) Pressured() bool {
+	return c.pressure.pressured()
+}
+
+// Stats returns the delivery counters.
+func (c *WebhookCore) Stats() WebhookStats {
+	return WebhookStats{
+		Sent:    atomic.LoadUint64(&c.stats.Sent),
+		Dropped: atomic.LoadUint64(&c.stats.Dropped),
+		Failed:  atomic.LoadUint64(&c.stats.Failed),
+	}
+}
+
+func (c *WebhookCore) run() {
+	defer close(c.done)
+	for item := range c.queue {
+		c.pressure.update(len(c.queue))
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+		if c.post(item.payload) {
+			atomic.AddUint64(&c.stats.Sent, 1)
+		} else {
+			atomic.AddUint64(&c.stats.Failed, 1)
+		}
+	}
+}
+
+func (c *WebhookCore) post(payload []byte) bool {
+	resp, err := c.cfg.Client.Post(c.cfg.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
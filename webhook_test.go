@@ -0,0 +1,96 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookCore(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(p))
+		mu.Unlock()
+		if strings.Contains(string(p), "reject") {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	core := NewWebhookCore(WebhookConfig{
+		URL:       srv.URL,
+		Format:    WebhookSlack,
+		Template:  "{level} {logger}: {msg} on {host}",
+		RateLimit: 3,
+	})
+	log := New(core, Named("db"))
+	log.Info("ignored")
+	log.Warn("disk full", F("host", "h1"))
+	log.Error("reject \"quoted\"", F("host", "h2"))
+	log.Error("third")
+	log.Error("over the rate limit")
+	core.Sync()
+
+	mu.Lock()
+	got := bodies
+	mu.Unlock()
+	want := []string{
+		`{"text":"WARN db: disk full on h1"}`,
+		`{"text":"ERROR db: reject \"quoted\" on h2"}`,
+		`{"text":"ERROR db: third on {host}"}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("bodies = %q, want %q", got, want)
+	}
+	if s := core.Stats(); s != (WebhookStats{Sent: 2, Dropped: 1, Failed: 1}) {
+		t.Errorf("Stats() = %+v", s)
+	}
+
+	core.Close()
+	if err := core.Write(Entry{Level: ErrorLevel}); err != errWebhookClosed {
+		t.Errorf("Write() after Close error = %v", err)
+	}
+}
+
+func TestWebhookCore_hungEndpoint(t *testing.T) {
+	if c := NewWebhookCore(WebhookConfig{}); c.cfg.Client.Timeout <= 0 {
+		t.Errorf("default client timeout = %v, want a timeout", c.cfg.Client.Timeout)
+	} else {
+		c.Close()
+	}
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	core := NewWebhookCore(WebhookConfig{URL: srv.URL, Client: &http.Client{Timeout: 50 * time.Millisecond}})
+	defer core.Close()
+	core.Write(Entry{Level: ErrorLevel, Message: "m"})
+	done := make(chan struct{})
+	go func() {
+		core.Sync()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sync() blocked on a hung endpoint")
+	}
+	if s := core.Stats(); s.Failed != 1 {
+		t.Errorf("Stats() = %+v, want the post failed", s)
+	}
+}
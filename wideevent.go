@@ -0,0 +1,127 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A WideEvent accumulates the fields of a unit of work, typically a
+// request, over its lifetime, and logs them as a single wide entry when
+// it's finished, following the canonical log line pattern: one entry per
+// request, with the same keys whatever the code path, is easier to query
+// than a trail of small entries.
+//
+// The methods of a WideEvent are safe for concurrent use.
+type WideEvent struct {
+	log   *Logger
+	name  string
+	start time.Time
+
+	mu     sync.Mutex
+	fields []Field
+	err    error
+	done   bool
+}
+
+// WideEvent returns a WideEvent logging the entry with the message name,
+// starting with fields.
+//
+//	func handle(w http.ResponseWriter, r *http.Request) {
+//		ev := log.WideEvent("request", xlog.String("path", r.URL.Path))
+//		defer ev.Done()
+//		...
+//		ev.AddField(xlog.Int("rows", n))
+//	}
+func (l *Logger) WideEvent(name string, fields ...Field) *WideEvent {
+	w := &WideEvent{log: l, name: name, start: time.Now()}
+	w.fields = make([]Field, 0, len(fields)+8)
+	for _, f := range fields {
+		w.set(f)
+	}
+	return w
+}
+
+// AddField adds fs to the entry. A field replaces the field with the same
+// key added before, keeping its position, so each key appears once. The
+// fields added after the entry is logged are ignored.
+func (w *WideEvent) AddField(fs ...Field) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return
+	}
+	for _, f := range fs {
+		w.set(f)
+	}
+}
+
+// SetError records the error the unit of work ended with, logged by Finish
+// or Done; nil clears it.
+func (w *WideEvent) SetError(err error) {
+	w.mu.Lock()
+	w.err = err
+	w.mu.Unlock()
+}
+
+// Finish logs the entry with the accumulated fields followed by its
+// duration and status: at InfoLevel with status "ok", or at ErrorLevel
+// with status "error" and the error set by SetError. Only the first call
+// of Finish or Done has an effect.
+func (w *WideEvent) Finish() {
+	w.finish(nil)
+}
+
+// Done is Finish to be deferred: if the function deferring it panics, the
+// entry is logged at ErrorLevel with status "panic" and the value of the
+// panic, and the panic goes on.
+func (w *WideEvent) Done() {
+	if r := recover(); r != nil {
+		w.finish(r)
+		panic(r)
+	}
+	w.finish(nil)
+}
+
+// finish logs the entry, p is the value of a panic, if any.
+func (w *WideEvent) finish(p interface{}) {
+	w.mu.Lock()
+	if w.done {
+		w.mu.Unlock()
+		return
+	}
+	w.done = true
+	fields := append(w.fields, F("duration", time.Since(w.start)))
+	err := w.err
+	w.mu.Unlock()
+
+	switch {
+	case p != nil:
+		fields = append(fields, F("status", "panic"), F("panic", fmt.Sprint(p)))
+		if err != nil {
+			fields = append(fields, Err(err))
+		}
+		w.log.log(3, ErrorLevel, w.name, nil, fields)
+	case err != nil:
+		fields = append(fields, F("status", "error"), Err(err))
+		w.log.log(3, ErrorLevel, w.name, nil, fields)
+	default:
+		fields = append(fields, F("status", "ok"))
+		w.log.log(3, InfoLevel, w.name, nil, fields)
+	}
+}
+
+// set adds f, or replaces the field with the same key, w.mu is held.
+func (w *WideEvent) set(f Field) {
+	for i := range w.fields {
+		if w.fields[i].Key == f.Key {
+			w.fields[i] = f
+			return
+		}
+	}
+	w.fields = append(w.fields, f)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWideEvent(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(Lshortfile), &buf, DebugLevel), AddCaller())
+
+	ev := log.WideEvent("request", String("path", "/a"), Int("rows", 0))
+	ev.AddField(String("user", "bob"), Int("rows", 3))
+	ev.Finish()
+	ev.AddField(String("late", "x"))
+	ev.Finish()
+
+	ev = log.WideEvent("request", String("path", "/b"))
+	ev.SetError(errors.New("refused"))
+	ev.Finish()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Out = %s, want 2 entries", buf.String())
+	}
+	want := [][]string{
+		{`"level":"INFO"`, `"caller":"wideevent_test.go:`, `"msg":"request","path":"/a","rows":3,"user":"bob","duration":`, `"status":"ok"}`},
+		{`"level":"ERROR"`, `"path":"/b","duration":`, `"status":"error","error":"refused"}`},
+	}
+	for i, subs := range want {
+		for _, sub := range subs {
+			if !strings.Contains(lines[i], sub) {
+				t.Errorf("entry %d = %s, want %s", i, lines[i], sub)
+			}
+		}
+	}
+}
+
+func TestWideEvent_panic(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewCore(NewJSONEncoder(0), &buf, DebugLevel))
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recover() = %v, want the panic to go on", r)
+			}
+		}()
+		ev := log.WideEvent("request")
+		defer ev.Done()
+		ev.AddField(Int("step", 1))
+		panic("boom")
+	}()
+
+	for _, sub := range []string{`"level":"ERROR"`, `"step":1`, `"status":"panic","panic":"boom"}`} {
+		if !strings.Contains(buf.String(), sub) {
+			t.Errorf("Out = %s, want %s", buf.String(), sub)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package xlogtest provides helpers for testing code built on xlog,
+// such as custom cores, writers and encoders.
+package xlogtest
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInjected is the default error returned by a failing FaultWriter.
+	ErrInjected = errors.New("xlogtest: injected write error")
+	// ErrDisconnected is returned by a FaultWriter once it is disconnected.
+	ErrDisconnected = errors.New("xlogtest: writer disconnected")
+)
+
+// Faults configures the failures injected by a FaultWriter.
+// Rates are probabilities between 0 and 1.
+type Faults struct {
+	ErrorRate       float64       // a write fails with Err and writes nothing
+	Err             error         // the injected error, ErrInjected if nil
+	ShortRate       float64       // a write only writes a part of its input
+	DelayRate       float64       // a write is delayed by Delay
+	Delay           time.Duration // the injected latency
+	DisconnectAfter int           // all writes fail with ErrDisconnected after the n-th one, 0 never
+	Seed            int64         // seed of the random source, for reproducible runs
+}
+
+// FaultWriter is an io.Writer that injects failures into the writes to the
+// underlying writer, to verify that core compositions (fallback, retry,
+// circuit breaker...) behave as intended. It's safe for concurrent use.
+type FaultWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	faults Faults
+	rnd    *rand.Rand
+	writes int
+	failed int
+	since  int // writes since the last (re)connection
+}
+
+// NewFaultWriter returns a FaultWriter writing to w.
+func NewFaultWriter(w io.Writer, faults Faults) *FaultWriter {
+	if faults.Err == nil {
+		faults.Err = ErrInjected
+	}
+	return &FaultWriter{
+		w:      w,
+		faults: faults,
+		rnd:    rand.New(rand.NewSource(faults.Seed)),
+	}
+}
+
+// Write implements io.Writer.
+func (fw *FaultWriter) Write(p []byte) (n int, err error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	fw.writes++
+	fw.since++
+	if fw.faults.DisconnectAfter > 0 && fw.since > fw.faults.DisconnectAfter {
+		fw.failed++
+		return 0, ErrDisconnected
+	}
+
+	if fw.hit(fw.faults.DelayRate) {
+		time.Sleep(fw.faults.Delay)
+	}
+	if fw.hit(fw.faults.ErrorRate) {
+		fw.failed++
+		return 0, fw.faults.Err
+	}
+	if len(p) > 1 && fw.hit(fw.faults.ShortRate) {
+		fw.failed++
+		n, err = fw.w.Write(p[:fw.rnd.Intn(len(p)-1)+1])
+		if err == nil {
+			err = io.ErrShortWrite
+		}
+		return
+	}
+	return fw.w.Write(p)
+}
+
+// Sync flushes the underlying writer if it supports it.
+func (fw *FaultWriter) Sync() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if fw.faults.DisconnectAfter > 0 && fw.since > fw.faults.DisconnectAfter {
+		return ErrDisconnected
+	}
+	if s, ok := fw.w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Reconnect ends a disconnection, the count of writes before the next
+// disconnection restarts from 0.
+func (fw *FaultWriter) Reconnect() {
+	fw.mu.Lock()
+	fw.since = 0
+	fw.mu.Unlock()
+}
+
+// Stats returns the number of writes and the number of failed writes.
+func (fw *FaultWriter) Stats() (writes, failed int) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.writes, fw.failed
+}
+
+func (fw *FaultWriter) hit(rate float64) bool {
+	return rate > 0 && fw.rnd.Float64() < rate
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlogtest
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestFaultWriter(t *testing.T) {
+	t.Run("errors", func(t *testing.T) {
+		var buf bytes.Buffer
+		fw := NewFaultWriter(&buf, Faults{ErrorRate: 1})
+		if _, err := fw.Write([]byte("x")); err != ErrInjected {
+			t.Errorf("Write() error = %v, want %v", err, ErrInjected)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("failed Write() wrote %q", buf.Bytes())
+		}
+	})
+
+	t.Run("short", func(t *testing.T) {
+		var buf bytes.Buffer
+		fw := NewFaultWriter(&buf, Faults{ShortRate: 1})
+		n, err := fw.Write([]byte("hello"))
+		if err != io.ErrShortWrite || n >= 5 || buf.Len() != n {
+			t.Errorf("Write() = %d, %v, want short write", n, err)
+		}
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		var buf bytes.Buffer
+		fw := NewFaultWriter(&buf, Faults{DisconnectAfter: 2})
+		for i := 0; i < 2; i++ {
+			if _, err := fw.Write([]byte("x")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		if _, err := fw.Write([]byte("x")); err != ErrDisconnected {
+			t.Errorf("Write() error = %v, want %v", err, ErrDisconnected)
+		}
+		fw.Reconnect()
+		if _, err := fw.Write([]byte("x")); err != nil {
+			t.Errorf("Write() after Reconnect error = %v", err)
+		}
+		if writes, failed := fw.Stats(); writes != 4 || failed != 1 {
+			t.Errorf("Stats() = %d, %d, want 4, 1", writes, failed)
+		}
+	})
+
+	t.Run("reproducible", func(t *testing.T) {
+		run := func() (out []bool) {
+			fw := NewFaultWriter(&bytes.Buffer{}, Faults{ErrorRate: 0.5, Seed: 7})
+			for i := 0; i < 32; i++ {
+				_, err := fw.Write([]byte("x"))
+				out = append(out, err == nil)
+			}
+			return
+		}
+		a, b := run(), run()
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("runs with the same seed differ at write %d", i)
+			}
+		}
+	})
+}
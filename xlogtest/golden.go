@@ -0,0 +1,97 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlogtest
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/cnotch/xlog"
+)
+
+// Update reports whether golden files are rewritten instead of compared,
+// it's set by the -update flag of the test binary:
+//
+//	go test ./... -update
+var Update = updateFlag()
+
+func updateFlag() *bool {
+	if f := flag.Lookup("update"); f != nil {
+		if g, ok := f.Value.(flag.Getter); ok {
+			if v, ok := g.Get().(bool); ok {
+				return &v
+			}
+		}
+	}
+	return flag.Bool("update", false, "update the golden files of xlogtest")
+}
+
+// TimeMask replaces the timestamps in normalized output.
+const TimeMask = "<time>"
+
+var timePattern = regexp.MustCompile(
+	`\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})?)?|\b\d{2}:\d{2}:\d{2}(?:\.\d+)?`)
+
+// Normalize returns b with the dates and times written by the xlog
+// encoders replaced by TimeMask, so the output of different runs compares
+// equal.
+func Normalize(b []byte) []byte {
+	return timePattern.ReplaceAll(b, []byte(TimeMask))
+}
+
+// Encode encodes entries with enc, and returns the normalized output.
+func Encode(enc xlog.Encoder, entries ...xlog.Entry) ([]byte, error) {
+	var out bytes.Buffer
+	var b xlog.Builder
+	for _, e := range entries {
+		b.Reset()
+		if err := enc.Encode(&b, e); err != nil {
+			return nil, err
+		}
+		out.Write(b.Bytes())
+	}
+	return Normalize(out.Bytes()), nil
+}
+
+// Golden encodes entries with enc and compares the normalized output with
+// the golden file testdata/name.golden, failing t on any difference.
+// It locks down a log format, so that accidental schema drift is caught
+// in tests. With the -update flag, the golden file is rewritten instead.
+func Golden(t testing.TB, name string, enc xlog.Encoder, entries ...xlog.Entry) {
+	t.Helper()
+	got, err := Encode(enc, entries...)
+	if err != nil {
+		t.Fatalf("encode %s: %v", name, err)
+	}
+	AssertGolden(t, filepath.Join("testdata", name+".golden"), got)
+}
+
+// AssertGolden compares got with the content of the golden file at path,
+// or rewrites the file with got when the -update flag is set.
+func AssertGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if *Update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("update %s: %v", path, err)
+		}
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("update %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file: %v (run the test with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run the test with -update to accept it):\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
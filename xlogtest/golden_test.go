@@ -0,0 +1,33 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlogtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cnotch/xlog"
+)
+
+func TestNormalize(t *testing.T) {
+	in := `{"time":"2019-04-01T12:30:45.123Z","t2":"2019-04-01T12:30:45+08:00"}
+INFO  2019-04-01 12:30:45.123456 svc: started, took 12:30:45`
+	want := `{"time":"<time>","t2":"<time>"}
+INFO  <time> svc: started, took <time>`
+	if got := string(Normalize([]byte(in))); got != want {
+		t.Errorf("Normalize() = %s, want %s", got, want)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	entries := []xlog.Entry{
+		{Level: xlog.InfoLevel, Time: time.Now(), LoggerName: "svc", Message: "started",
+			Fields: []xlog.Field{xlog.F("port", 8080)}},
+		{Level: xlog.ErrorLevel, Time: time.Now(), Message: "failed",
+			Ctx: []xlog.Field{xlog.F("id", "a1")}, Fields: []xlog.Field{xlog.F("retry", true)}},
+	}
+	Golden(t, "json", xlog.NewJSONEncoder(0), entries...)
+	Golden(t, "console", xlog.NewConsoleEncoder(xlog.LstdFlags), entries...)
+}
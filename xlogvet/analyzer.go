@@ -0,0 +1,187 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package xlogvet provides an analyzer reporting expensive expressions
+// passed to the arguments of xlog calls at levels which are usually
+// disabled in production, such as
+//
+//	log.Debug("request", xlog.F("body", fmt.Sprintf("%+v", req)))
+//
+// The arguments of a call are evaluated even when its level is disabled,
+// so the cost is paid for nothing; guard the call with LevelEnabled.
+// Calls inside an if statement testing LevelEnabled are not reported.
+//
+// Run it with go vet:
+//
+//	go install github.com/cnotch/xlog/xlogvet/cmd/xlogvet
+//	go vet -vettool=$(which xlogvet) ./...
+package xlogvet
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const xlogPath = "github.com/cnotch/xlog"
+
+// Analyzer reports expensive arguments of disabled-level xlog calls.
+var Analyzer = &analysis.Analyzer{
+	Name:     "xlogvet",
+	Doc:      "report expensive arguments of xlog calls at usually disabled levels",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+var levels string
+
+func init() {
+	Analyzer.Flags.StringVar(&levels, "levels", "debug", "comma separated levels whose calls are checked")
+}
+
+// expensive lists the functions whose results are costly to build,
+// by package path and name.
+var expensive = map[string]map[string]bool{
+	"fmt":           {"Sprint": true, "Sprintf": true, "Sprintln": true, "Errorf": true},
+	"encoding/json": {"Marshal": true, "MarshalIndent": true},
+	"strings":       {"Join": true, "Repeat": true, "Replace": true, "ReplaceAll": true},
+	"strconv":       {"Quote": true},
+}
+
+// expensiveMethods lists the methods which render their receiver.
+var expensiveMethods = map[string]bool{"String": true, "Error": true, "MarshalJSON": true, "MarshalText": true}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	checked := make(map[string]bool)
+	for _, l := range strings.Split(levels, ",") {
+		if l = strings.TrimSpace(strings.ToLower(l)); l != "" {
+			checked[l] = true
+		}
+	}
+
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		call := n.(*ast.CallExpr)
+		level, args := logCall(pass, call)
+		if level == "" || !checked[level] || guarded(pass, stack) {
+			return true
+		}
+		for _, arg := range args {
+			ast.Inspect(arg, func(n ast.Node) bool {
+				if c, ok := n.(*ast.CallExpr); ok {
+					if name := expensiveCall(pass, c); name != "" {
+						pass.Reportf(c.Pos(), "%s is evaluated even when %s level is disabled, guard the call with LevelEnabled", name, level)
+						return false
+					}
+				}
+				return true
+			})
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// logCall returns the lower-case level and the arguments of call
+// if it's a log call on an *xlog.Logger.
+func logCall(pass *analysis.Pass, call *ast.CallExpr) (string, []ast.Expr) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", nil
+	}
+	fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != xlogPath {
+		return "", nil
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() == nil || !isLogger(sig.Recv().Type()) {
+		return "", nil
+	}
+
+	name := fn.Name()
+	switch name {
+	case "Debug", "Debugf", "Info", "Infof", "Warn", "Warnf", "Error", "Errorf":
+		return strings.ToLower(strings.TrimSuffix(name, "f")), call.Args
+	case "Log":
+		if len(call.Args) > 0 {
+			if c, ok := pass.TypesInfo.Uses[ident(call.Args[0])].(*types.Const); ok && c.Pkg().Path() == xlogPath {
+				return strings.ToLower(strings.TrimSuffix(c.Name(), "Level")), call.Args[1:]
+			}
+		}
+	}
+	return "", nil
+}
+
+func isLogger(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == xlogPath && named.Obj().Name() == "Logger"
+}
+
+func ident(e ast.Expr) *ast.Ident {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.SelectorExpr:
+		return e.Sel
+	}
+	return nil
+}
+
+// expensiveCall returns the name of the function called by c if it's expensive.
+func expensiveCall(pass *analysis.Pass, c *ast.CallExpr) string {
+	id := ident(c.Fun)
+	if id == nil {
+		return ""
+	}
+	fn, ok := pass.TypesInfo.Uses[id].(*types.Func)
+	if !ok {
+		return ""
+	}
+	if fn.Type().(*types.Signature).Recv() != nil {
+		if expensiveMethods[fn.Name()] && len(c.Args) == 0 {
+			return fn.Name() + "()"
+		}
+		return ""
+	}
+	if fn.Pkg() != nil && expensive[fn.Pkg().Path()][fn.Name()] {
+		return fn.Pkg().Name() + "." + fn.Name()
+	}
+	return ""
+}
+
+// guarded reports whether the innermost node of stack is inside the body
+// of an if statement whose condition calls LevelEnabled.
+func guarded(pass *analysis.Pass, stack []ast.Node) bool {
+	for i := len(stack) - 2; i >= 0; i-- {
+		ifStmt, ok := stack[i].(*ast.IfStmt)
+		if !ok || stack[i+1] != ifStmt.Body {
+			continue
+		}
+		found := false
+		ast.Inspect(ifStmt.Cond, func(n ast.Node) bool {
+			if c, ok := n.(*ast.CallExpr); ok {
+				if id := ident(c.Fun); id != nil && id.Name == "LevelEnabled" {
+					if fn, ok := pass.TypesInfo.Uses[id].(*types.Func); ok && fn.Pkg() != nil && fn.Pkg().Path() == xlogPath {
+						found = true
+					}
+				}
+			}
+			return !found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
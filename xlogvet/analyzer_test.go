@@ -0,0 +1,16 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package xlogvet_test
+
+import (
+	"testing"
+
+	"github.com/cnotch/xlog/xlogvet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), xlogvet.Analyzer, "a")
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Command xlogvet reports expensive arguments of xlog calls at usually
+// disabled levels. It runs standalone or as a go vet tool:
+//
+//	xlogvet ./...
+//	go vet -vettool=$(which xlogvet) ./...
+package main
+
+import (
+	"github.com/cnotch/xlog/xlogvet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() { singlechecker.Main(xlogvet.Analyzer) }
@@ -0,0 +1,23 @@
+package a
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cnotch/xlog"
+)
+
+func f(log *xlog.Logger, req interface{}, d time.Duration, parts []string) {
+	log.Debug("request", xlog.F("body", fmt.Sprintf("%+v", req)))        // want `fmt.Sprintf is evaluated even when debug level is disabled`
+	log.Debugf("parts %s", strings.Join(parts, ","))                     // want `strings.Join is evaluated`
+	log.Debug(d.String())                                                // want `String\(\) is evaluated`
+	log.Log(xlog.DebugLevel, "request", xlog.F("body", fmt.Sprint(req))) // want `fmt.Sprint is evaluated`
+
+	log.Debug("elapsed", xlog.F("d", d), xlog.F("n", len(parts)))
+	log.Info("request", xlog.F("body", fmt.Sprintf("%+v", req)))
+	log.Log(xlog.InfoLevel, fmt.Sprint(req))
+	if log.LevelEnabled(xlog.DebugLevel) {
+		log.Debug("request", xlog.F("body", fmt.Sprintf("%+v", req)))
+	}
+}
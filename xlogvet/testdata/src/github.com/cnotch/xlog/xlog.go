@@ -0,0 +1,24 @@
+// Package xlog is a stub of the API checked by the analyzer.
+package xlog
+
+type Level int8
+
+const (
+	DebugLevel Level = iota - 1
+	InfoLevel
+)
+
+type Field struct {
+	Key string
+	Val interface{}
+}
+
+func F(key string, val interface{}) Field { return Field{key, val} }
+
+type Logger struct{}
+
+func (l *Logger) LevelEnabled(lvl Level) bool                 { return false }
+func (l *Logger) Log(lvl Level, msg string, fields ...Field)  {}
+func (l *Logger) Debug(msg string, fields ...Field)           {}
+func (l *Logger) Debugf(template string, args ...interface{}) {}
+func (l *Logger) Info(msg string, fields ...Field)            {}